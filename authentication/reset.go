@@ -0,0 +1,65 @@
+package authentication
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/structure"
+)
+
+// Password reset links are valid for one hour
+const passwordResetValidity = time.Hour
+
+var ErrInvalidResetToken = errors.New("The password reset link is invalid or has expired.")
+
+// Function to create a password reset token for a user. The token is signed and encrypted with the session keys and
+// contains a fingerprint of the current password hash, so it becomes invalid as soon as the password has been changed (single use).
+func GeneratePasswordResetToken(user *structure.User) (string, error) {
+	hashedPassword, err := database.RetrieveHashedPasswordForUser(user.Name)
+	if err != nil {
+		return "", err
+	}
+	value := map[string]string{
+		"user":        strconv.FormatInt(user.Id, 10),
+		"expires":     strconv.FormatInt(date.GetCurrentTime().Add(passwordResetValidity).Unix(), 10),
+		"fingerprint": passwordFingerprint(hashedPassword),
+	}
+	return cookieHandler.Encode("reset", value)
+}
+
+// Function to check a password reset token. Returns the user the token was issued for.
+func ValidatePasswordResetToken(token string) (*structure.User, error) {
+	value := make(map[string]string)
+	err := cookieHandler.Decode("reset", token, &value)
+	if err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	expires, err := strconv.ParseInt(value["expires"], 10, 64)
+	if err != nil || date.GetCurrentTime().Unix() > expires {
+		return nil, ErrInvalidResetToken
+	}
+	userId, err := strconv.ParseInt(value["user"], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	user, err := database.RetrieveUserWithRole(userId)
+	if err != nil || user.Status == "inactive" {
+		return nil, ErrInvalidResetToken
+	}
+	hashedPassword, err := database.RetrieveHashedPasswordForUser(user.Name)
+	if err != nil || subtle.ConstantTimeCompare([]byte(passwordFingerprint(hashedPassword)), []byte(value["fingerprint"])) != 1 {
+		return nil, ErrInvalidResetToken
+	}
+	return user, nil
+}
+
+func passwordFingerprint(hashedPassword []byte) string {
+	hash := sha256.Sum256(hashedPassword)
+	return hex.EncodeToString(hash[:16])
+}
@@ -1,44 +1,167 @@
 package authentication
 
 import (
-	"github.com/gorilla/securecookie"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/structure"
 )
 
-var cookieHandler = securecookie.New(
-	securecookie.GenerateRandomKey(64),
-	securecookie.GenerateRandomKey(32))
+// A session expires 30 days after the login or after 7 days without any request, whatever comes first
+const sessionLifetime = 30 * 24 * time.Hour
+const sessionIdleTimeout = 7 * 24 * time.Hour
+
+// The last seen time of a session is only written if it is older than this (so that not every request causes a write)
+const sessionLastSeenPrecision = time.Minute
+
+// Length of the keys used to sign (hash key) and encrypt (block key) the session cookie
+const hashKeyLength = 64
+const blockKeyLength = 32
+
+// The keys are stored in content/data so that users stay logged in when Journey is restarted
+var cookieHandler = newCookieHandler()
+
+func newCookieHandler() *securecookie.SecureCookie {
+	hashKey, blockKey, err := loadSessionKeys(filenames.SessionKeysFilename)
+	if err != nil {
+		log.Fatal("Error: Couldn't load session keys:", err)
+	}
+	handler := securecookie.New(hashKey, blockKey)
+	handler.MaxAge(int(sessionLifetime.Seconds()))
+	return handler
+}
+
+// Function to read the session keys from a file. Creates the file with new random keys if it doesn't exist yet.
+func loadSessionKeys(filename string) ([]byte, []byte, error) {
+	keys, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		keys = make([]byte, hashKeyLength+blockKeyLength)
+		_, err = rand.Read(keys)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = ioutil.WriteFile(filename, keys, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) != hashKeyLength+blockKeyLength {
+		return nil, nil, errors.New(filename + " is corrupted. Delete it to generate new keys (this logs out all users).")
+	}
+	return keys[:hashKeyLength], keys[hashKeyLength:], nil
+}
 
-func SetSession(userName string, response http.ResponseWriter) {
+// Function to create a new session for a user and to send the session cookie.
+func SetSession(userId int64, response http.ResponseWriter, request *http.Request) error {
+	currentTime := date.GetCurrentTime()
+	// Remove old sessions (of all users) while we're at it
+	err := database.DeleteExpiredSessions(currentTime, currentTime.Add(-sessionIdleTimeout))
+	if err != nil {
+		log.Println("Couldn't delete expired sessions:", err)
+	}
+	token, err := GenerateToken()
+	if err != nil {
+		return err
+	}
+	_, err = database.InsertSession(HashToken(token), userId, []byte(request.UserAgent()), []byte(RemoteIp(request)), currentTime, currentTime.Add(sessionLifetime))
+	if err != nil {
+		return err
+	}
 	value := map[string]string{
-		"name": userName,
+		"token": token,
 	}
-	if encoded, err := cookieHandler.Encode("session", value); err == nil {
-		cookie := &http.Cookie{
-			Name:  "session",
-			Value: encoded,
-			Path:  "/admin/",
+	encoded, err := cookieHandler.Encode("session", value)
+	if err != nil {
+		return err
+	}
+	cookie := newSessionCookie(request)
+	cookie.Value = encoded
+	cookie.MaxAge = int(sessionLifetime.Seconds())
+	http.SetCookie(response, cookie)
+	return nil
+}
+
+// Function to get the session of a request. Returns nil if there is no valid session.
+func GetSession(request *http.Request) *structure.Session {
+	token := getSessionToken(request)
+	if token == "" {
+		return nil
+	}
+	session, err := database.RetrieveSessionByHash(HashToken(token))
+	if err != nil {
+		return nil
+	}
+	currentTime := date.GetCurrentTime()
+	if session.ExpiresAt.Before(currentTime) || session.LastSeenAt.Add(sessionIdleTimeout).Before(currentTime) {
+		database.DeleteSessionById(session.Id)
+		return nil
+	}
+	if currentTime.Sub(*session.LastSeenAt) >= sessionLastSeenPrecision {
+		// Not being able to record the time shouldn't fail the request
+		database.UpdateSessionLastSeen(session.Id, currentTime)
+		session.LastSeenAt = &currentTime
+	}
+	return session
+}
+
+// Function to end the session of a request (server-side as well as the cookie).
+func ClearSession(response http.ResponseWriter, request *http.Request) {
+	if session := GetSession(request); session != nil {
+		err := database.DeleteSessionById(session.Id)
+		if err != nil {
+			log.Println("Couldn't delete session:", err)
 		}
-		http.SetCookie(response, cookie)
 	}
+	cookie := newSessionCookie(request)
+	cookie.MaxAge = -1
+	http.SetCookie(response, cookie)
 }
 
-func GetUserName(request *http.Request) (userName string) {
+func getSessionToken(request *http.Request) string {
 	if cookie, err := request.Cookie("session"); err == nil {
 		cookieValue := make(map[string]string)
 		if err = cookieHandler.Decode("session", cookie.Value, &cookieValue); err == nil {
-			userName = cookieValue["name"]
+			return cookieValue["token"]
 		}
 	}
-	return userName
+	return ""
 }
 
-func ClearSession(response http.ResponseWriter) {
+func newSessionCookie(request *http.Request) *http.Cookie {
+	return newCookie("session", "/admin/", request)
+}
+
+// Our cookies can't be read by scripts. If the admin area is served over https, they are never sent over plain http either.
+func newCookie(name string, path string, request *http.Request) *http.Cookie {
 	cookie := &http.Cookie{
-		Name:   "session",
-		Value:  "",
-		Path:   "/admin/",
-		MaxAge: -1,
+		Name:     name,
+		Path:     path,
+		HttpOnly: true,
 	}
-	http.SetCookie(response, cookie)
+	if request.TLS != nil {
+		cookie.Secure = true
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	return cookie
+}
+
+// Returns the ip address of the client (without the port)
+func RemoteIp(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
 }
@@ -0,0 +1,107 @@
+package authentication
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/structure"
+)
+
+// Failed logins are counted over this period (for a user name: since the last successful login)
+const loginAttemptWindow = 24 * time.Hour
+
+// Login attempts are kept in the audit log for 90 days
+const loginAttemptRetention = 90 * 24 * time.Hour
+
+// After a number of failed logins, each further attempt has to wait twice as long as the one before.
+// After the maximum number of failed logins, the user name or ip address is locked for loginLockoutDuration.
+const loginLockoutDuration = 15 * time.Minute
+
+type loginLimit struct {
+	freeFailures int
+	maxFailures  int
+}
+
+// Many users may share an ip address, so the limits per ip address are more lenient
+var nameLoginLimit = loginLimit{freeFailures: 3, maxFailures: 10}
+var ipLoginLimit = loginLimit{freeFailures: 10, maxFailures: 50}
+
+// Function to check whether a login attempt is allowed. Returns how long the client has to wait before the next attempt (0 if the attempt is allowed).
+func LoginWaitTime(name string, request *http.Request) time.Duration {
+	currentTime := date.GetCurrentTime()
+	since := currentTime.Add(-loginAttemptWindow)
+	attemptsByName, err := database.RetrieveLoginAttemptsByName([]byte(name), since)
+	if err != nil {
+		log.Println("Couldn't retrieve login attempts:", err)
+		return 0
+	}
+	attemptsByIp, err := database.RetrieveLoginAttemptsByIp([]byte(RemoteIp(request)), since)
+	if err != nil {
+		log.Println("Couldn't retrieve login attempts:", err)
+		return 0
+	}
+	// A successful login resets the failures of the user name but not those of the ip address (otherwise an attacker could reset them with their own account)
+	failures, lastFailure := countFailures(attemptsByName, true)
+	wait := loginWaitTime(nameLoginLimit, failures, lastFailure, currentTime)
+	failures, lastFailure = countFailures(attemptsByIp, false)
+	if ipWait := loginWaitTime(ipLoginLimit, failures, lastFailure, currentTime); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// Function to add a login attempt to the audit log.
+func RecordLoginAttempt(name string, request *http.Request, successful bool) {
+	currentTime := date.GetCurrentTime()
+	err := database.InsertLoginAttempt([]byte(name), []byte(RemoteIp(request)), []byte(request.UserAgent()), successful, currentTime)
+	if err != nil {
+		log.Println("Couldn't record login attempt:", err)
+	}
+	if successful {
+		err = database.DeleteLoginAttemptsBefore(currentTime.Add(-loginAttemptRetention))
+		if err != nil {
+			log.Println("Couldn't delete old login attempts:", err)
+		}
+	}
+}
+
+// Counts the failures in a list of attempts (newest first) and returns the time of the most recent one
+func countFailures(attempts []structure.LoginAttempt, stopAtSuccess bool) (int, *time.Time) {
+	failures := 0
+	var lastFailure *time.Time
+	for index, _ := range attempts {
+		if attempts[index].Successful {
+			if stopAtSuccess {
+				break
+			}
+			continue
+		}
+		if lastFailure == nil {
+			lastFailure = attempts[index].CreatedAt
+		}
+		failures++
+	}
+	return failures, lastFailure
+}
+
+func loginWaitTime(limit loginLimit, failures int, lastFailure *time.Time, currentTime time.Time) time.Duration {
+	if failures < limit.freeFailures || lastFailure == nil {
+		return 0
+	}
+	delay := loginLockoutDuration
+	if failures < limit.maxFailures {
+		// 1 second after the first failure that isn't free, then 2, 4, 8...
+		delay = time.Second << uint(failures-limit.freeFailures)
+		if delay > loginLockoutDuration {
+			delay = loginLockoutDuration
+		}
+	}
+	wait := lastFailure.Add(delay).Sub(currentTime)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
@@ -0,0 +1,122 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/totp"
+)
+
+// After the password has been checked, the user has this long to enter the second factor
+const pendingLoginValidity = 5 * time.Minute
+
+const numberOfRecoveryCodes = 10
+
+// Function to remember a user whose password was correct until the second factor has been entered. No session is created yet.
+func SetPendingLogin(userId int64, response http.ResponseWriter, request *http.Request) error {
+	value := map[string]string{
+		"user":    strconv.FormatInt(userId, 10),
+		"expires": strconv.FormatInt(date.GetCurrentTime().Add(pendingLoginValidity).Unix(), 10),
+	}
+	encoded, err := cookieHandler.Encode("login", value)
+	if err != nil {
+		return err
+	}
+	cookie := newCookie("login", "/admin/login/", request)
+	cookie.Value = encoded
+	cookie.MaxAge = int(pendingLoginValidity.Seconds())
+	http.SetCookie(response, cookie)
+	return nil
+}
+
+// Function to get the id of the user that still needs to enter the second factor. Returns 0 if there is no pending login.
+func GetPendingLogin(request *http.Request) int64 {
+	cookie, err := request.Cookie("login")
+	if err != nil {
+		return 0
+	}
+	value := make(map[string]string)
+	err = cookieHandler.Decode("login", cookie.Value, &value)
+	if err != nil {
+		return 0
+	}
+	expires, err := strconv.ParseInt(value["expires"], 10, 64)
+	if err != nil || date.GetCurrentTime().Unix() > expires {
+		return 0
+	}
+	userId, err := strconv.ParseInt(value["user"], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return userId
+}
+
+func ClearPendingLogin(response http.ResponseWriter, request *http.Request) {
+	cookie := newCookie("login", "/admin/login/", request)
+	cookie.MaxAge = -1
+	http.SetCookie(response, cookie)
+}
+
+// Returns true if the user has confirmed two-factor authentication
+func TwoFactorIsEnabled(userId int64) bool {
+	twoFactor, err := database.RetrieveTwoFactor(userId)
+	return err == nil && twoFactor.Confirmed
+}
+
+// Function to check a TOTP code or a recovery code of a user. Each code can only be used once.
+func SecondFactorIsCorrect(userId int64, code string) bool {
+	twoFactor, err := database.RetrieveTwoFactor(userId)
+	if err != nil || !twoFactor.Confirmed {
+		return false
+	}
+	if step, ok := totp.Validate(twoFactor.Secret, code, date.GetCurrentTime()); ok {
+		accepted, err := database.UpdateTwoFactorLastStep(userId, step)
+		if err != nil {
+			log.Println("Couldn't update two-factor authentication of a user:", err)
+			return false
+		}
+		return accepted
+	}
+	used, err := database.DeleteRecoveryCode(userId, hashRecoveryCode(code))
+	if err != nil {
+		log.Println("Couldn't delete recovery code:", err)
+		return false
+	}
+	return used
+}
+
+// Function to create new recovery codes for a user. Returns the codes, the database only stores their hashes.
+func GenerateRecoveryCodes(userId int64) ([]string, error) {
+	codes := make([]string, numberOfRecoveryCodes)
+	hashes := make([]string, numberOfRecoveryCodes)
+	for index, _ := range codes {
+		random := make([]byte, 5)
+		_, err := rand.Read(random)
+		if err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(random)
+		codes[index] = code[:5] + "-" + code[5:]
+		hashes[index] = hashRecoveryCode(code)
+	}
+	err := database.InsertRecoveryCodes(userId, hashes, date.GetCurrentTime())
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Recovery codes are case insensitive and may be entered with or without the dash
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Replace(strings.Replace(strings.TrimSpace(code), "-", "", -1), " ", "", -1))
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
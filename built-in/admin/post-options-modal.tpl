@@ -16,6 +16,18 @@
                     <input spellcheck="true" type="text" class="form-control" id="post-meta-description" ng-model="shared.post.MetaDescription" value="{{shared.post.MetaDescription}}">
                 </div>
             </div>
+            <div class="form-group">
+                <label for="post-codeinjection-head" class="col-sm-2 control-label">Post Header</label>
+                <div class="col-sm-10">
+                    <textarea spellcheck="false" class="form-control" rows="3" id="post-codeinjection-head" ng-model="shared.post.CodeinjectionHead"></textarea>
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-codeinjection-foot" class="col-sm-2 control-label">Post Footer</label>
+                <div class="col-sm-10">
+                    <textarea spellcheck="false" class="form-control" rows="3" id="post-codeinjection-foot" ng-model="shared.post.CodeinjectionFoot"></textarea>
+                </div>
+            </div>
             <div class="form-group">
                 <label for="post-cover" class="col-sm-2 control-label">Cover</label>
                 <div class="col-sm-10">
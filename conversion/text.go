@@ -0,0 +1,23 @@
+package conversion
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// Converts plain text (e.g. a comment) to html: the text is escaped, blank lines separate paragraphs, and single line breaks become <br>.
+func GenerateHtmlFromText(input []byte) []byte {
+	text := strings.Replace(strings.Replace(string(input), "\r\n", "\n", -1), "\r", "\n", -1)
+	var buffer bytes.Buffer
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		buffer.WriteString("<p>")
+		buffer.WriteString(strings.Replace(html.EscapeString(paragraph), "\n", "<br>\n", -1))
+		buffer.WriteString("</p>\n")
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,23 @@
+package conversion
+
+import (
+	"testing"
+)
+
+var textTests = []struct {
+	in  string
+	out string
+}{
+	{in: "Hello", out: "<p>Hello</p>\n"},
+	{in: "First line\r\nsecond line\n\n\n\nNext paragraph ", out: "<p>First line<br>\nsecond line</p>\n<p>Next paragraph</p>\n"},
+	{in: "<script>alert(\"1\")</script> & more", out: "<p>&lt;script&gt;alert(&#34;1&#34;)&lt;/script&gt; &amp; more</p>\n"},
+	{in: " \n ", out: ""},
+}
+
+func TestGenerateHtmlFromText(t *testing.T) {
+	for _, test := range textTests {
+		if output := string(GenerateHtmlFromText([]byte(test.in))); output != test.out {
+			t.Errorf("GenerateHtmlFromText(%q): expected %q, got %q", test.in, test.out, output)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/kabukky/journey/helpers"
+)
+
+// copiedTable: a table that is copied from an existing journey.db into a new database backend, and the column its
+// rows are copied in order of. Tables ordered by id have a serial id.
+type copiedTable struct {
+	name    string
+	orderBy string
+}
+
+var copiedTables = []copiedTable{
+	copiedTable{"posts", "id"},
+	copiedTable{"users", "id"},
+	copiedTable{"tags", "id"},
+	copiedTable{"posts_tags", "id"},
+	copiedTable{"settings", "id"},
+	copiedTable{"roles", "id"},
+	copiedTable{"roles_users", "id"},
+	copiedTable{"invites", "id"},
+	copiedTable{"post_revisions", "id"},
+	copiedTable{"content_keys", "id"},
+	copiedTable{"api_tokens", "id"},
+	copiedTable{"sessions", "id"},
+	copiedTable{"login_attempts", "id"},
+	copiedTable{"two_factor", "user_id"},
+	copiedTable{"recovery_codes", "id"},
+	copiedTable{"media", "id"},
+	copiedTable{"posts_media", "id"},
+	copiedTable{"comments", "id"},
+	copiedTable{"webhooks", "id"},
+	copiedTable{"webhook_deliveries", "id"},
+}
+
+const stmtRetrieveSqliteTableCount = "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+
+// Function to copy the content of an existing SQLite database (journey.db) into the configured database. Existing rows in the configured database are replaced.
+func CopyFromSqlite(filename string) error {
+	if currentDialect == DialectSqlite {
+		return errors.New("The configured database is SQLite already. Set DatabaseDialect and DatabaseConnection in config.json to the new database backend first.")
+	}
+	if !helpers.FileExists(filename) {
+		return errors.New("Couldn't find " + filename)
+	}
+	sourceDB, err := sql.Open(DialectSqlite, filename)
+	if err != nil {
+		return err
+	}
+	defer sourceDB.Close()
+	return copyTables(sourceDB, readDB)
+}
+
+func copyTables(sourceDB *sql.DB, targetDB *DB) error {
+	writeDB, err := targetDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	for _, table := range copiedTables {
+		// Older versions of journey.db may not contain all tables
+		var count int64
+		err = sourceDB.QueryRow(stmtRetrieveSqliteTableCount, table.name).Scan(&count)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		// The new database contains the default roles and settings already
+		_, err = writeDB.Exec("DELETE FROM " + table.name)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+		copied, err := copyTable(sourceDB, writeDB, table)
+		if err != nil {
+			writeDB.Rollback()
+			return errors.New("Couldn't copy table " + table.name + ": " + err.Error())
+		}
+		if table.orderBy == "id" {
+			err = writeDB.resetSequence(table.name)
+			if err != nil {
+				writeDB.Rollback()
+				return err
+			}
+		}
+		log.Println("Copied " + strconv.FormatInt(copied, 10) + " rows of table " + table.name + ".")
+	}
+	return writeDB.Commit()
+}
+
+func copyTable(sourceDB *sql.DB, writeDB *Tx, table copiedTable) (int64, error) {
+	// Only copy the columns that exist in both databases (journey.db may contain additional columns if it was converted from Ghost)
+	targetColumns, err := retrieveColumns(writeDB.Tx.Query("SELECT * FROM " + table.name + " WHERE 1 = 0"))
+	if err != nil {
+		return 0, err
+	}
+	sourceColumns, err := retrieveColumns(sourceDB.Query("SELECT * FROM " + table.name + " WHERE 1 = 0"))
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]string, 0)
+	for _, column := range targetColumns {
+		for _, sourceColumn := range sourceColumns {
+			if column == sourceColumn {
+				columns = append(columns, "\""+column+"\"")
+				break
+			}
+		}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	stmtInsert := "INSERT INTO " + table.name + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders + ")"
+	rows, err := sourceDB.Query("SELECT " + strings.Join(columns, ", ") + " FROM " + table.name + " ORDER BY " + table.orderBy + " ASC")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var copied int64
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for index, _ := range values {
+			pointers[index] = &values[index]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return copied, err
+		}
+		_, err = writeDB.Exec(stmtInsert, values...)
+		if err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, rows.Err()
+}
+
+func retrieveColumns(rows *sql.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
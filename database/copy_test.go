@@ -0,0 +1,76 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kabukky/journey/database/migration"
+)
+
+// Copies a database with all migrations applied. The migrations run on both databases, so the copy has to handle
+// every table (e.g. tables without an id column).
+func TestCopyTables(t *testing.T) {
+	defer func(dialect string) { currentDialect = dialect }(currentDialect)
+	currentDialect = DialectSqlite
+	directory, err := ioutil.TempDir("", "journey-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	databases := make([]*DB, 2)
+	for index, name := range []string{"source.db", "target.db"} {
+		databases[index], err = openDatabase(DialectSqlite, filepath.Join(directory, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer databases[index].Close()
+		err = migration.Up(migrationDatabase{databases[index]})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sourceDB, targetDB := databases[0], databases[1]
+	currentTime := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, err = sourceDB.Exec("INSERT INTO two_factor (user_id, secret, confirmed, last_step, created_at) VALUES (?, ?, ?, ?, ?)", 1, "secret", 1, 0, currentTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sourceDB.Exec("INSERT INTO tags (uuid, name, slug, created_at, created_by) VALUES (?, ?, ?, ?, ?)", "uuid", "News", "news", currentTime, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = copyTables(sourceDB.DB, targetDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"two_factor", "tags"} {
+		var count int64
+		err = targetDB.QueryRow("SELECT count(*) FROM " + table).Scan(&count)
+		if err != nil || count != 1 {
+			t.Errorf("Rows of %s after the copy = %d, %v, want 1", table, count, err)
+		}
+	}
+	// Every table has to be copied, except for the ones that journey creates itself
+	rows, err := sourceDB.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations' AND name NOT LIKE 'posts_search%'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied := false
+		for _, table := range copiedTables {
+			copied = copied || table.name == name
+		}
+		if !copied {
+			t.Errorf("The table %s isn't copied", name)
+		}
+	}
+}
@@ -1,7 +1,37 @@
 package database
 
+import (
+	"database/sql"
+	"time"
+)
+
 const stmtDeletePostTagsByPostId = "DELETE FROM posts_tags WHERE post_id = ?"
 const stmtDeletePostById = "DELETE FROM posts WHERE id = ?"
+const stmtDeletePostRevisionsByPostId = "DELETE FROM post_revisions WHERE post_id = ?"
+const stmtDeletePostRevisionsUpToId = "DELETE FROM post_revisions WHERE post_id = ? AND id <= ?"
+const stmtDeleteContentKeyById = "DELETE FROM content_keys WHERE id = ?"
+const stmtDeleteLoginAttemptsBefore = "DELETE FROM login_attempts WHERE created_at < ?"
+const stmtDeleteTwoFactorByUserId = "DELETE FROM two_factor WHERE user_id = ?"
+const stmtDeleteRecoveryCodesByUserId = "DELETE FROM recovery_codes WHERE user_id = ?"
+const stmtDeleteRecoveryCode = "DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?"
+const stmtDeleteSessionById = "DELETE FROM sessions WHERE id = ?"
+const stmtDeleteSessionsByUserId = "DELETE FROM sessions WHERE user_id = ?"
+const stmtDeleteOtherSessionsByUserId = "DELETE FROM sessions WHERE user_id = ? AND id <> ?"
+const stmtDeleteExpiredSessions = "DELETE FROM sessions WHERE expires_at < ? OR last_seen_at < ?"
+const stmtDeleteApiTokenById = "DELETE FROM api_tokens WHERE id = ?"
+const stmtDeleteApiTokensByUserId = "DELETE FROM api_tokens WHERE user_id = ?"
+const stmtDeleteUserById = "DELETE FROM users WHERE id = ?"
+const stmtDeleteRoleUserByUserId = "DELETE FROM roles_users WHERE user_id = ?"
+const stmtDeleteInviteById = "DELETE FROM invites WHERE id = ?"
+const stmtDeleteExpiredInvites = "DELETE FROM invites WHERE expires_at < ?"
+const stmtDeleteMediaById = "DELETE FROM media WHERE id = ?"
+const stmtDeletePostMediaByMediaId = "DELETE FROM posts_media WHERE media_id = ?"
+const stmtDeletePostMediaByPostId = "DELETE FROM posts_media WHERE post_id = ?"
+const stmtDeleteCommentById = "DELETE FROM comments WHERE id = ?"
+const stmtDeleteWebhookById = "DELETE FROM webhooks WHERE id = ?"
+const stmtDeleteWebhookDeliveriesByWebhookId = "DELETE FROM webhook_deliveries WHERE webhook_id = ?"
+const stmtDeleteWebhookDeliveriesUpToId = "DELETE FROM webhook_deliveries WHERE webhook_id = ? AND id <= ?"
+const stmtDeleteCommentsByPostId = "DELETE FROM comments WHERE post_id = ?"
 
 func DeletePostTagsForPostId(post_id int64) error {
 	writeDB, err := readDB.Begin()
@@ -30,3 +60,346 @@ func DeletePostById(id int64) error {
 	}
 	return writeDB.Commit()
 }
+
+func DeletePostRevisionsForPostId(post_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostRevisionsByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Function to delete all but the newest revisions of a post
+func DeleteOldPostRevisions(post_id int64, keep int64) error {
+	// Find the newest revision that has to go. All older revisions go as well.
+	var id int64
+	row := readDB.QueryRow(stmtRetrieveOldestKeptPostRevisionId, post_id, keep)
+	err := row.Scan(&id)
+	if err == sql.ErrNoRows {
+		// Not enough revisions to prune anything
+		return nil
+	} else if err != nil {
+		return err
+	}
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostRevisionsUpToId, post_id, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteContentKeyById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteContentKeyById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteLoginAttemptsBefore(before time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteLoginAttemptsBefore, before)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Turns off two-factor authentication for a user
+func DeleteTwoFactorForUser(user_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTwoFactorByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Deletes a recovery code. Returns false if the user doesn't have the code (recovery codes can only be used once).
+func DeleteRecoveryCode(user_id int64, code_hash string) (bool, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	result, err := writeDB.Exec(stmtDeleteRecoveryCode, user_id, code_hash)
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	return rows == 1, writeDB.Commit()
+}
+
+func DeleteSessionById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSessionById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Deletes all sessions of a user except the given one (pass 0 to delete all sessions)
+func DeleteOtherSessionsForUser(user_id int64, keep_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteOtherSessionsByUserId, user_id, keep_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Deletes all sessions that have expired or haven't been used since idle_before
+func DeleteExpiredSessions(now time.Time, idle_before time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteExpiredSessions, now, idle_before)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteApiTokenById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteApiTokenById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteUserById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRoleUserByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSessionsByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTwoFactorByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteApiTokensByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteUserById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteInviteById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteInviteById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteExpiredInvites(now time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteExpiredInvites, now)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteMediaById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostMediaByMediaId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteMediaById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeletePostMediaForPostId(post_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostMediaByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Deletes a comment. Its replies become replies to the parent of the comment (or top-level comments).
+func DeleteCommentById(id int64, parent_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateCommentParents, parent_id, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteCommentById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteCommentsForPostId(post_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteCommentsByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Deletes a webhook and its delivery log
+func DeleteWebhookById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteWebhookDeliveriesByWebhookId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteWebhookById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Keeps only the newest entries of the delivery log of a webhook
+func DeleteOldWebhookDeliveries(webhook_id int64, keep int64) error {
+	// Find the newest delivery that has to go. All older deliveries go as well.
+	var id int64
+	row := readDB.QueryRow(stmtRetrieveOldestKeptWebhookDeliveryId, webhook_id, keep)
+	err := row.Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteWebhookDeliveriesUpToId, webhook_id, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
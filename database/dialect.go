@@ -0,0 +1,248 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/kabukky/journey/database/migration"
+)
+
+// Supported database dialects (DatabaseDialect in config.json)
+const (
+	DialectSqlite   = "sqlite3"
+	DialectPostgres = "postgres"
+	DialectMysql    = "mysql"
+)
+
+// Dialect of the database that is currently in use. All statements in this package are written for SQLite and translated for the other dialects.
+var currentDialect = DialectSqlite
+
+// DB and Tx wrap the database/sql types to translate statements and arguments for the current dialect.
+type DB struct {
+	*sql.DB
+}
+
+type Tx struct {
+	*sql.Tx
+}
+
+func openDatabase(dialect string, connection string) (*DB, error) {
+	if dialect != DialectSqlite && dialect != DialectPostgres && dialect != DialectMysql {
+		return nil, errors.New("Unknown database dialect '" + dialect + "'. Use sqlite3, postgres, or mysql.")
+	}
+	if dialect == DialectMysql && !strings.Contains(connection, "parseTime=") {
+		// Dates have to be scanned into time.Time
+		if strings.Contains(connection, "?") {
+			connection = connection + "&parseTime=true"
+		} else {
+			connection = connection + "?parseTime=true"
+		}
+	}
+	db, err := sql.Open(dialect, connection)
+	if err != nil {
+		return nil, errors.New("Couldn't open " + dialect + " database (was Journey built with the '" + dialect + "' build tag?): " + err.Error())
+	}
+	return &DB{db}, nil
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	return &Tx{tx}, err
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(rebind(query), convertArguments(args)...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(rebind(query), convertArguments(args)...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(rebind(query), convertArguments(args)...)
+}
+
+// Rollback may be called on a transaction that couldn't be started (as done throughout this package).
+func (tx *Tx) Rollback() error {
+	if tx.Tx == nil {
+		return nil
+	}
+	return tx.Tx.Rollback()
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(rebind(query), convertArguments(args)...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(rebind(query), convertArguments(args)...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(rebind(query), convertArguments(args)...)
+}
+
+// Executes an INSERT statement and returns the id of the new row. PostgreSQL doesn't support LastInsertId, so the id is returned by the statement itself.
+func (tx *Tx) insert(query string, args ...interface{}) (int64, error) {
+	if currentDialect == DialectPostgres {
+		var id int64
+		err := tx.Tx.QueryRow(rebind(query+" RETURNING id"), convertArguments(args)...).Scan(&id)
+		return id, err
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// PostgreSQL doesn't advance the id sequence of a table if rows are inserted with explicit ids.
+func (tx *Tx) resetSequence(table string) error {
+	if currentDialect != DialectPostgres {
+		return nil
+	}
+	_, err := tx.Tx.Exec("SELECT setval(pg_get_serial_sequence('" + table + "', 'id'), COALESCE((SELECT MAX(id) FROM " + table + "), 0) + 1, false)")
+	return err
+}
+
+// Calls resetSequence for all tables with a serial id.
+func (tx *Tx) resetSequences() error {
+	if currentDialect != DialectPostgres {
+		return nil
+	}
+	rows, err := tx.Tx.Query("SELECT table_name FROM information_schema.columns WHERE table_schema = current_schema() AND column_name = 'id' AND column_default LIKE 'nextval(%'")
+	if err != nil {
+		return err
+	}
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		err = tx.resetSequence(table)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrationDatabase and migrationTransaction translate the schema migrations to the current dialect.
+type migrationDatabase struct {
+	db *DB
+}
+
+type migrationTransaction struct {
+	*Tx
+}
+
+func (m migrationDatabase) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return m.db.DB.Exec(translateSchema(query), convertArguments(args)...)
+}
+
+func (m migrationDatabase) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return m.db.Query(query, args...)
+}
+
+func (m migrationDatabase) Begin() (migration.Transaction, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return migrationTransaction{tx}, nil
+}
+
+// Optional migrations are only applied if the database supports the features they require.
+func (m migrationDatabase) Supports(feature string) bool {
+	switch feature {
+	case migration.FeatureFts5:
+		return supportsFts5(m.db)
+	}
+	return false
+}
+
+func (m migrationTransaction) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return m.Tx.Tx.Exec(translateSchema(query), convertArguments(args)...)
+}
+
+// Translates the placeholders and identifier quotes of a statement to the current dialect. String literals in single
+// quotes (e.g. 'published') are copied as they are, so they can contain question marks and double quotes.
+func rebind(query string) string {
+	if currentDialect == DialectSqlite {
+		return query
+	}
+	var buffer bytes.Buffer
+	placeholders := 0
+	inLiteral := false
+	for index := 0; index < len(query); index++ {
+		character := query[index]
+		switch {
+		case character == '\'':
+			// A quote inside of a literal is written as two quotes ('It''s'), which toggles twice
+			inLiteral = !inLiteral
+			buffer.WriteByte(character)
+		case inLiteral:
+			buffer.WriteByte(character)
+		case character == '?' && currentDialect == DialectPostgres:
+			// PostgreSQL uses numbered placeholders ($1, $2, ...)
+			placeholders++
+			buffer.WriteString("$" + strconv.Itoa(placeholders))
+		case character == '"' && currentDialect == DialectMysql:
+			// MySQL quotes identifiers (like the reserved word key) with backticks
+			buffer.WriteByte('`')
+		default:
+			buffer.WriteByte(character)
+		}
+	}
+	return buffer.String()
+}
+
+// Translates a schema statement (e.g. CREATE TABLE) to the current dialect.
+func translateSchema(statement string) string {
+	switch currentDialect {
+	case DialectPostgres:
+		statement = strings.Replace(statement, "integer NOT NULL PRIMARY KEY AUTOINCREMENT", "serial PRIMARY KEY", -1)
+		statement = strings.Replace(statement, "datetime", "timestamp", -1)
+		statement = strings.Replace(statement, "tinyint", "smallint", -1)
+	case DialectMysql:
+		statement = strings.Replace(statement, "AUTOINCREMENT", "AUTO_INCREMENT", -1)
+	}
+	return rebind(statement)
+}
+
+// lib/pq sends []byte as bytea and doesn't convert bool to the smallint columns that are used for flags.
+func convertArguments(args []interface{}) []interface{} {
+	if currentDialect != DialectPostgres {
+		return args
+	}
+	converted := make([]interface{}, len(args))
+	for index, arg := range args {
+		switch value := arg.(type) {
+		case []byte:
+			converted[index] = string(value)
+		case bool:
+			if value {
+				converted[index] = int64(1)
+			} else {
+				converted[index] = int64(0)
+			}
+		default:
+			converted[index] = arg
+		}
+	}
+	return converted
+}
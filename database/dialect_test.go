@@ -0,0 +1,128 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kabukky/journey/database/migration"
+)
+
+func TestRebind(t *testing.T) {
+	defer func(dialect string) { currentDialect = dialect }(currentDialect)
+	query := `SELECT value FROM settings WHERE "key" = ? AND value != 'Why "not"?' AND type = ?`
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{DialectSqlite, query},
+		{DialectPostgres, `SELECT value FROM settings WHERE "key" = $1 AND value != 'Why "not"?' AND type = $2`},
+		{DialectMysql, "SELECT value FROM settings WHERE `key` = ? AND value != 'Why \"not\"?' AND type = ?"},
+	}
+	for _, test := range tests {
+		currentDialect = test.dialect
+		if got := rebind(query); got != test.want {
+			t.Errorf("rebind with %s = %q, want %q", test.dialect, got, test.want)
+		}
+	}
+	currentDialect = DialectPostgres
+	if got, want := rebind("SELECT 'It''s ?', ?"), "SELECT 'It''s ?', $1"; got != want {
+		t.Errorf("rebind with escaped quote = %q, want %q", got, want)
+	}
+}
+
+func TestMigrationsSqlite(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journey-database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	testMigrations(t, DialectSqlite, filepath.Join(directory, "journey.db"))
+}
+
+// Applies all migrations to an empty database, checks that the settings can be written and read, and reverts all migrations again.
+// The posts have to survive reverting the migrations that rebuild the posts table.
+// Used by the smoke tests of the other dialects (see driver_postgres_test.go and driver_mysql_test.go).
+func testMigrations(t *testing.T, dialect string, connection string) {
+	defer func(dialect string) { currentDialect = dialect }(currentDialect)
+	currentDialect = dialect
+	db, err := openDatabase(dialect, connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	err = migration.Up(migrationDatabase{db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migration.Status(migrationDatabase{db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil && (status.Requires == "" || migrationDatabase{db}.Supports(status.Requires)) {
+			t.Errorf("Migration %d (%s) wasn't applied", status.Version, status.Name)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO settings (uuid, "key", value, type, created_at, created_by) VALUES (?, ?, ?, 'blog', ?, ?)`, "uuid", "title", "Who's there?", statuses[0].AppliedAt, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var title string
+	err = db.QueryRow(stmtRetrieveBlog, "title").Scan(&title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Who's there?" {
+		t.Errorf("title = %q, want %q", title, "Who's there?")
+	}
+	defer func(db *DB) { readDB = db }(readDB)
+	readDB = db
+	err = checkBlogSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uuids int64
+	err = db.QueryRow(`SELECT COUNT(DISTINCT uuid) FROM settings WHERE "key" IN ('codeinjection_head', 'codeinjection_foot')`).Scan(&uuids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uuids != 2 {
+		t.Errorf("The code injection settings have %d different uuids, want 2", uuids)
+	}
+	_, err = db.Exec(stmtInsertTestPost, "uuid", "Hello", statuses[0].AppliedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		reverted, err := migrateDown(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reverted == nil {
+			break
+		}
+		if reverted.Version != 12 {
+			continue
+		}
+		// New posts get the next id in the rebuilt table
+		_, err = db.Exec(stmtInsertTestPost, "uuid", "World", statuses[0].AppliedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var id int64
+		err = db.QueryRow("SELECT id FROM posts WHERE title = ?", "World").Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != 2 {
+			t.Errorf("id of the post inserted after reverting migration 12 = %d, want 2", id)
+		}
+	}
+}
+
+const stmtInsertTestPost = "INSERT INTO posts (uuid, title, slug, author_id, created_at, created_by) VALUES (?, ?, 'hello', 1, ?, 1)"
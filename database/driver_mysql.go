@@ -0,0 +1,8 @@
+// +build mysql
+
+package database
+
+// The MySQL driver is only included if Journey is built with the 'mysql' build tag
+import (
+	_ "github.com/go-sql-driver/mysql"
+)
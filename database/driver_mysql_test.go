@@ -0,0 +1,17 @@
+// +build mysql
+
+package database
+
+import (
+	"os"
+	"testing"
+)
+
+// JOURNEY_TEST_MYSQL: connection string of an empty database, e.g. "journey:secret@tcp(localhost:3306)/journey_test"
+func TestMigrationsMysql(t *testing.T) {
+	connection := os.Getenv("JOURNEY_TEST_MYSQL")
+	if connection == "" {
+		t.Skip("JOURNEY_TEST_MYSQL is not set")
+	}
+	testMigrations(t, DialectMysql, connection)
+}
@@ -0,0 +1,8 @@
+// +build postgres
+
+package database
+
+// The PostgreSQL driver is only included if Journey is built with the 'postgres' build tag
+import (
+	_ "github.com/lib/pq"
+)
@@ -0,0 +1,366 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kabukky/journey/nql"
+	"github.com/kabukky/journey/structure"
+)
+
+// The queries of the {{#get}} helper and the content api. Their filters (nql expressions) and orders are translated to SQL.
+const stmtRetrievePostsByFilter = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.codeinjection_head, posts.codeinjection_foot, posts.author_id, posts.published_at, posts.updated_at FROM posts WHERE posts.status = 'published' AND posts.page = ? AND "
+const stmtRetrieveTagsByFilter = "SELECT tags.id, tags.name, tags.slug, " + stmtTagPostCount + " FROM tags WHERE "
+const stmtRetrieveAuthorsByFilter = "SELECT users.id, users.name, users.slug, users.email, users.image, users.cover, users.bio, users.website, users.location, " + stmtAuthorPostCount + " FROM users WHERE users.status != 'inactive' AND "
+const stmtCountPostsByFilter = "SELECT count(*) FROM posts WHERE posts.status = 'published' AND posts.page = ? AND "
+const stmtCountTagsByFilter = "SELECT count(*) FROM tags WHERE "
+const stmtCountAuthorsByFilter = "SELECT count(*) FROM users WHERE users.status != 'inactive' AND "
+const stmtTagPostCount = "(SELECT count(*) FROM posts_tags, posts WHERE posts_tags.tag_id = tags.id AND posts_tags.post_id = posts.id AND posts.page = 0 AND posts.status = 'published')"
+const stmtAuthorPostCount = "(SELECT count(*) FROM posts WHERE posts.author_id = users.id AND posts.page = 0 AND posts.status = 'published')"
+
+// FilterError: the filter or order can't be translated to SQL (e.g. it contains an unknown field)
+type FilterError struct {
+	message string
+}
+
+func (e *FilterError) Error() string {
+	return e.message
+}
+
+// Escapes the wildcards of LIKE patterns. ! is the escape character because a backslash starts an escape sequence in MySQL strings.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// Kinds of values a filter field can have
+const (
+	filterText = iota
+	filterNumber
+	filterBool
+	filterDate
+)
+
+// filterField: the SQL expression of a field. Fields with multiple values (e.g. the tags of a post) are matched
+// with a subquery: set contains the condition that the expression is used in (%s).
+type filterField struct {
+	expression string
+	kind       int
+	set        string
+}
+
+const postTagsSet = "posts.id IN (SELECT posts_tags.post_id FROM posts_tags, tags WHERE posts_tags.tag_id = tags.id AND %s)"
+const postAuthorsSet = "posts.author_id IN (SELECT users.id FROM users WHERE %s)"
+
+var postFilterFields = map[string]filterField{
+	"id":               {"posts.id", filterNumber, ""},
+	"uuid":             {"posts.uuid", filterText, ""},
+	"slug":             {"posts.slug", filterText, ""},
+	"title":            {"posts.title", filterText, ""},
+	"featured":         {"posts.featured", filterBool, ""},
+	"page":             {"posts.page", filterBool, ""},
+	"status":           {"posts.status", filterText, ""},
+	"visibility":       {"'public'", filterText, ""},
+	"feature_image":    {"posts.image", filterText, ""},
+	"meta_description": {"posts.meta_description", filterText, ""},
+	"published_at":     {"posts.published_at", filterDate, ""},
+	"updated_at":       {"posts.updated_at", filterDate, ""},
+	"tag":              {"tags.slug", filterText, postTagsSet},
+	"tags":             {"tags.slug", filterText, postTagsSet},
+	"tags.slug":        {"tags.slug", filterText, postTagsSet},
+	"tags.name":        {"tags.name", filterText, postTagsSet},
+	"primary_tag":      {"(SELECT tags.slug FROM posts_tags, tags WHERE posts_tags.tag_id = tags.id AND posts_tags.post_id = posts.id ORDER BY posts_tags.id ASC LIMIT 1)", filterText, ""},
+	"author":           {"users.slug", filterText, postAuthorsSet},
+	"authors":          {"users.slug", filterText, postAuthorsSet},
+	"authors.slug":     {"users.slug", filterText, postAuthorsSet},
+	"primary_author":   {"users.slug", filterText, postAuthorsSet},
+}
+
+var tagFilterFields = map[string]filterField{
+	"id":          {"tags.id", filterNumber, ""},
+	"slug":        {"tags.slug", filterText, ""},
+	"name":        {"tags.name", filterText, ""},
+	"visibility":  {"'public'", filterText, ""},
+	"count.posts": {stmtTagPostCount, filterNumber, ""},
+}
+
+var authorFilterFields = map[string]filterField{
+	"id":          {"users.id", filterNumber, ""},
+	"slug":        {"users.slug", filterText, ""},
+	"name":        {"users.name", filterText, ""},
+	"location":    {"users.location", filterText, ""},
+	"website":     {"users.website", filterText, ""},
+	"count.posts": {stmtAuthorPostCount, filterNumber, ""},
+}
+
+// Retrieves the published posts (or pages) that match the filter. Order is a comma separated list of fields with
+// an optional direction (e.g. "featured desc, published_at desc"). A limit of 0 retrieves all posts.
+func RetrievePostsByFilter(filter nql.Expression, order string, pages bool, limit int64, offset int64) ([]structure.Post, error) {
+	query, args, err := buildFilterQuery(stmtRetrievePostsByFilter, postFilterFields, filter, order, "posts.published_at DESC", "posts.id DESC", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readDB.Query(query, append([]interface{}{pages}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// Retrieves the tags that match the filter, with the number of their published posts
+func RetrieveTagsByFilter(filter nql.Expression, order string, limit int64, offset int64) ([]structure.Tag, error) {
+	query, args, err := buildFilterQuery(stmtRetrieveTagsByFilter, tagFilterFields, filter, order, "tags.name ASC", "tags.id ASC", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make([]structure.Tag, 0)
+	for rows.Next() {
+		tag := structure.Tag{}
+		err := rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.PostCount)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Retrieves the users that match the filter (except suspended ones), with the number of their published posts
+func RetrieveAuthorsByFilter(filter nql.Expression, order string, limit int64, offset int64) ([]structure.User, error) {
+	query, args, err := buildFilterQuery(stmtRetrieveAuthorsByFilter, authorFilterFields, filter, order, "users.name ASC", "users.id ASC", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make([]structure.User, 0)
+	for rows.Next() {
+		user := structure.User{}
+		err := rows.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.PostCount)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Retrieves the number of published posts (or pages) that match the filter
+func CountPostsByFilter(filter nql.Expression, pages bool) (int64, error) {
+	return countByFilter(stmtCountPostsByFilter, postFilterFields, filter, pages)
+}
+
+// Retrieves the number of tags that match the filter
+func CountTagsByFilter(filter nql.Expression) (int64, error) {
+	return countByFilter(stmtCountTagsByFilter, tagFilterFields, filter)
+}
+
+// Retrieves the number of users that match the filter (except suspended ones)
+func CountAuthorsByFilter(filter nql.Expression) (int64, error) {
+	return countByFilter(stmtCountAuthorsByFilter, authorFilterFields, filter)
+}
+
+func countByFilter(statement string, fields map[string]filterField, filter nql.Expression, args ...interface{}) (int64, error) {
+	condition, conditionArgs, err := filterCondition(filter, fields)
+	if err != nil {
+		return 0, &FilterError{err.Error()}
+	}
+	var count int64
+	err = readDB.QueryRow(statement+condition, append(args, conditionArgs...)...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Appends the condition, order, and limit to a statement that ends with "WHERE " (or "AND ")
+func buildFilterQuery(statement string, fields map[string]filterField, filter nql.Expression, order string, defaultOrder string, lastOrder string, limit int64, offset int64) (string, []interface{}, error) {
+	condition, args, err := filterCondition(filter, fields)
+	if err != nil {
+		return "", nil, &FilterError{err.Error()}
+	}
+	orderBy, err := filterOrder(order, fields)
+	if err != nil {
+		return "", nil, &FilterError{err.Error()}
+	}
+	if orderBy == "" {
+		orderBy = defaultOrder
+	}
+	query := statement + condition + " ORDER BY " + orderBy + ", " + lastOrder
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+	return query, args, nil
+}
+
+func filterCondition(expression nql.Expression, fields map[string]filterField) (string, []interface{}, error) {
+	switch expression := expression.(type) {
+	case nql.And:
+		return joinFilterConditions(expression, " AND ", "1 = 1", fields)
+	case nql.Or:
+		return joinFilterConditions(expression, " OR ", "1 = 0", fields)
+	case nql.Comparison:
+		return comparisonCondition(expression, fields)
+	case nil:
+		return "1 = 1", nil, nil
+	}
+	return "", nil, errors.New("Unknown filter expression.")
+}
+
+func joinFilterConditions(expressions []nql.Expression, separator string, empty string, fields map[string]filterField) (string, []interface{}, error) {
+	if len(expressions) == 0 {
+		return empty, nil, nil
+	}
+	conditions := make([]string, 0, len(expressions))
+	args := make([]interface{}, 0)
+	for _, expression := range expressions {
+		condition, conditionArgs, err := filterCondition(expression, fields)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+	return "(" + strings.Join(conditions, separator) + ")", args, nil
+}
+
+// Translates a comparison the same way nql matches it: text is compared case-insensitively, null matches empty
+// fields, and a negation (field:-value) also matches empty fields.
+func comparisonCondition(comparison nql.Comparison, fields map[string]filterField) (string, []interface{}, error) {
+	field, ok := fields[comparison.Field]
+	if !ok {
+		return "", nil, errors.New("Unknown filter field '" + comparison.Field + "'.")
+	}
+	if len(comparison.Values) == 1 && comparison.Values[0] == "null" {
+		isNull := field.expression + " IS NULL"
+		if field.kind == filterText {
+			isNull = "(" + isNull + " OR " + field.expression + " = '')"
+		}
+		if field.set != "" {
+			// No value at all: not in the set of records with any value
+			isNull = "NOT " + fmt.Sprintf(field.set, "1 = 1")
+		}
+		switch comparison.Operator {
+		case nql.OperatorEqual:
+			return isNull, nil, nil
+		case nql.OperatorNot:
+			return "NOT " + isNull, nil, nil
+		}
+		return "", nil, errors.New("Null can only be compared with ':' and ':-' (field '" + comparison.Field + "').")
+	}
+	operator := comparison.Operator
+	if operator == nql.OperatorNot {
+		operator = nql.OperatorEqual
+	}
+	conditions := make([]string, 0, len(comparison.Values))
+	args := make([]interface{}, 0, len(comparison.Values))
+	for _, value := range comparison.Values {
+		condition, arg, err := valueCondition(field, comparison.Field, operator, value)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	condition := strings.Join(conditions, " OR ")
+	if field.set != "" {
+		condition = fmt.Sprintf(field.set, "("+condition+")")
+		if comparison.Operator == nql.OperatorNot {
+			return "NOT " + condition, args, nil
+		}
+		return condition, args, nil
+	}
+	if comparison.Operator == nql.OperatorNot {
+		return "(" + field.expression + " IS NULL OR NOT (" + condition + "))", args, nil
+	}
+	return "(" + condition + ")", args, nil
+}
+
+func valueCondition(field filterField, name string, operator string, value string) (string, interface{}, error) {
+	var arg interface{}
+	switch field.kind {
+	case filterNumber:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", nil, errors.New("The filter field '" + name + "' needs a number.")
+		}
+		arg = number
+	case filterBool:
+		if value != "true" && value != "false" {
+			return "", nil, errors.New("The filter field '" + name + "' needs true or false.")
+		}
+		arg = value == "true"
+	case filterDate:
+		date, ok := nql.ParseDate(value)
+		if !ok {
+			return "", nil, errors.New("The filter field '" + name + "' needs a date (e.g. 2017-01-31).")
+		}
+		arg = date
+	default:
+		arg = value
+	}
+	switch operator {
+	case nql.OperatorEqual:
+		if field.kind == filterText {
+			return "lower(" + field.expression + ") = lower(?)", arg, nil
+		}
+		return field.expression + " = ?", arg, nil
+	case nql.OperatorGreater, nql.OperatorGreaterOrEqual, nql.OperatorLess, nql.OperatorLessOrEqual:
+		if field.kind == filterBool {
+			break
+		}
+		return field.expression + " " + operator + " ?", arg, nil
+	case nql.OperatorContains, nql.OperatorStartsWith, nql.OperatorEndsWith:
+		if field.kind != filterText {
+			break
+		}
+		// % and _ in the value match themselves (like in nql)
+		pattern := likeEscaper.Replace(strings.ToLower(value))
+		if operator != nql.OperatorStartsWith {
+			pattern = "%" + pattern
+		}
+		if operator != nql.OperatorEndsWith {
+			pattern = pattern + "%"
+		}
+		return "lower(" + field.expression + ") LIKE ? ESCAPE '!'", pattern, nil
+	}
+	return "", nil, errors.New("The filter field '" + name + "' can't be compared with '" + operator + "'.")
+}
+
+// Translates an order like "published_at desc, title" to SQL. Fields with multiple values can't be used.
+func filterOrder(order string, fields map[string]filterField) (string, error) {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(order, ",") {
+		words := strings.Fields(strings.ToLower(part))
+		if len(words) == 0 {
+			continue
+		}
+		field, ok := fields[words[0]]
+		if !ok || field.set != "" || len(words) > 2 {
+			return "", errors.New("Can't order by '" + strings.TrimSpace(part) + "'.")
+		}
+		direction := "ASC"
+		if len(words) == 2 {
+			if words[1] == "desc" {
+				direction = "DESC"
+			} else if words[1] != "asc" {
+				return "", errors.New("Can't order by '" + strings.TrimSpace(part) + "'.")
+			}
+		}
+		parts = append(parts, field.expression+" "+direction)
+	}
+	return strings.Join(parts, ", "), nil
+}
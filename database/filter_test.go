@@ -0,0 +1,145 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kabukky/journey/database/migration"
+	"github.com/kabukky/journey/nql"
+)
+
+// Opens a temporary SQLite database with all migrations applied and the posts of the filter tests as the database of the package.
+// The returned function closes and removes it again.
+func openFilterTestDatabase(t *testing.T) func() {
+	directory, err := ioutil.TempDir("", "journey-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialect, db := currentDialect, readDB
+	currentDialect = DialectSqlite
+	readDB, err = openDatabase(DialectSqlite, filepath.Join(directory, "journey.db"))
+	if err == nil {
+		err = migration.Up(migrationDatabase{readDB})
+	}
+	if err == nil {
+		err = insertFilterTestPosts()
+	}
+	if err != nil {
+		os.RemoveAll(directory)
+		t.Fatal(err)
+	}
+	return func() {
+		readDB.Close()
+		currentDialect, readDB = dialect, db
+		os.RemoveAll(directory)
+	}
+}
+
+func insertFilterTestPosts() error {
+	createdAt := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	userId, err := InsertUser([]byte("Jane"), "jane", "", []byte("jane@example.com"), nil, nil, createdAt, 1)
+	if err != nil {
+		return err
+	}
+	tagId, err := InsertTag([]byte("News"), "news", createdAt, userId)
+	if err != nil {
+		return err
+	}
+	posts := []struct {
+		title     string
+		slug      string
+		page      bool
+		published bool
+		tagged    bool
+	}{
+		{"50% off", "sale", false, true, true},
+		{"500 visitors", "visitors", false, true, false},
+		{"A_B testing", "a_b", false, true, true},
+		{"AxB", "axb", false, true, false},
+		{"Draft", "draft", false, false, true},
+		{"About", "about", true, true, false},
+	}
+	for index, post := range posts {
+		postId, err := InsertPost([]byte(post.title), post.slug, nil, nil, false, post.page, post.published, nil, nil, nil, nil, createdAt.Add(time.Duration(index)*time.Hour), userId, nil)
+		if err != nil {
+			return err
+		}
+		if post.tagged {
+			err = InsertPostTag(postId, tagId)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestCountByFilter(t *testing.T) {
+	defer openFilterTestDatabase(t)()
+	tagged := nql.Comparison{Field: "tag", Operator: nql.OperatorEqual, Values: []string{"news"}}
+	count, err := CountPostsByFilter(nil, false)
+	if err != nil || count != 4 {
+		t.Errorf("CountPostsByFilter(nil, false) = %d, %v, want 4", count, err)
+	}
+	count, err = CountPostsByFilter(tagged, false)
+	if err != nil || count != 2 {
+		t.Errorf("CountPostsByFilter(tag:news, false) = %d, %v, want 2", count, err)
+	}
+	count, err = CountPostsByFilter(nil, true)
+	if err != nil || count != 1 {
+		t.Errorf("CountPostsByFilter(nil, true) = %d, %v, want 1", count, err)
+	}
+	count, err = CountTagsByFilter(nil)
+	if err != nil || count != 1 {
+		t.Errorf("CountTagsByFilter(nil) = %d, %v, want 1", count, err)
+	}
+	count, err = CountAuthorsByFilter(nil)
+	if err != nil || count != 1 {
+		t.Errorf("CountAuthorsByFilter(nil) = %d, %v, want 1", count, err)
+	}
+	posts, err := RetrievePostsByFilter(tagged, "title asc", false, 1, 1)
+	if err != nil || len(posts) != 1 || string(posts[0].Title) != "A_B testing" {
+		t.Errorf("RetrievePostsByFilter(tag:news) with limit 1 and offset 1 = %v, %v, want A_B testing", posts, err)
+	}
+	_, err = CountPostsByFilter(nql.Comparison{Field: "unknown", Operator: nql.OperatorEqual, Values: []string{"news"}}, false)
+	if _, ok := err.(*FilterError); !ok {
+		t.Errorf("CountPostsByFilter(unknown:news) = %v, want a FilterError", err)
+	}
+}
+
+// % and _ in the values of ~, ~^, and ~$ match themselves, like in nql.Match
+func TestFilterLike(t *testing.T) {
+	defer openFilterTestDatabase(t)()
+	tests := []struct {
+		filter string
+		want   string // slugs of the posts
+	}{
+		{"title:~'50%'", "sale"},
+		{"title:~^'a_b'", "a_b"},
+		{"slug:~$'_b'", "a_b"},
+		{"title:~'0!'", ""},
+		{"title:~'visitors'", "visitors"},
+	}
+	for _, test := range tests {
+		filter, err := nql.Parse(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		posts, err := RetrievePostsByFilter(filter, "title asc", false, 0, 0)
+		if err != nil {
+			t.Errorf("%s: %v", test.filter, err)
+			continue
+		}
+		slugs := make([]string, 0)
+		for _, post := range posts {
+			slugs = append(slugs, post.Slug)
+		}
+		if got := strings.Join(slugs, ", "); got != test.want {
+			t.Errorf("%s = %q, want %q", test.filter, got, test.want)
+		}
+	}
+}
@@ -1,8 +1,7 @@
 package database
 
 import (
-	"database/sql"
-
+	"github.com/kabukky/journey/configuration"
 	"github.com/kabukky/journey/database/migration"
 	"github.com/kabukky/journey/date"
 	"github.com/kabukky/journey/filenames"
@@ -13,139 +12,63 @@ import (
 )
 
 // Handler for read access
-var readDB *sql.DB
+var readDB *DB
 
-var stmtInitialization = `CREATE TABLE IF NOT EXISTS
-	posts (
-		id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		uuid				varchar(36) NOT NULL,
-		title				varchar(150) NOT NULL,
-		slug				varchar(150) NOT NULL,
-		markdown			text,
-		html				text,
-		image				text,
-		featured			tinyint NOT NULL DEFAULT '0',
-		page				tinyint NOT NULL DEFAULT '0',
-		status				varchar(150) NOT NULL DEFAULT 'draft',
-		language			varchar(6) NOT NULL DEFAULT 'en_US',
-		meta_title			varchar(150),
-		meta_description	varchar(200),
-		author_id			integer NOT NULL,
-		created_at			datetime NOT NULL,
-		created_by			integer NOT NULL,
-		updated_at			datetime,
-		updated_by			integer,
-		published_at		datetime,
-		published_by		integer
-	);
-	CREATE TABLE IF NOT EXISTS
-	users (
-		id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		uuid				varchar(36) NOT NULL,
-		name				varchar(150) NOT NULL,
-		slug				varchar(150) NOT NULL,
-		password			varchar(60) NOT NULL,
-		email				varchar(254) NOT NULL,
-		image				text,
-		cover				text,
-		bio					varchar(200),
-		website				text,
-		location			text,
-		accessibility		text,
-		status				varchar(150) NOT NULL DEFAULT 'active',
-		language			varchar(6) NOT NULL DEFAULT 'en_US',
-		meta_title			varchar(150),
-		meta_description	varchar(200),
-		last_login			datetime,
-		created_at			datetime NOT NULL,
-		created_by			integer NOT NULL,
-		updated_at			datetime,
-		updated_by			integer
-	);
-	CREATE TABLE IF NOT EXISTS
-	tags (
-		id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		uuid				varchar(36) NOT NULL,
-		name				varchar(150) NOT NULL,
-		slug				varchar(150) NOT NULL,
-		description			varchar(200),
-		parent_id			integer,
-		meta_title			varchar(150),
-		meta_description	varchar(200),
-		created_at			datetime NOT NULL,
-		created_by			integer NOT NULL,
-		updated_at			datetime,
-		updated_by			integer
-	);
-	CREATE TABLE IF NOT EXISTS
-	posts_tags (
-		id		integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		post_id	integer NOT NULL,
-		tag_id	integer NOT NULL
-	);
-	CREATE TABLE IF NOT EXISTS
-	settings (
-		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		uuid		varchar(36) NOT NULL,
-		key			varchar(150) NOT NULL,
-		value		text,
-		type		varchar(150) NOT NULL DEFAULT 'core',
-		created_at	datetime NOT NULL,
-		created_by	integer NOT NULL,
-		updated_at	datetime,
-		updated_by	integer
-	);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (1, ?, 'title', 'My Blog', 'blog', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (2, ?, 'description', 'Just another Blog', 'blog', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (3, ?, 'email', '', 'blog', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (4, ?, 'logo', '/public/images/blog-logo.jpg', 'blog', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (5, ?, 'cover', '/public/images/blog-cover.jpg', 'blog', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (6, ?, 'postsPerPage', 5, 'blog', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (7, ?, 'activeTheme', 'promenade', 'theme', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (8, ?, 'navigation', '[{"label":"Home", "url":"/"}]', 'blog', ?, 1, ?, 1);
-	CREATE TABLE IF NOT EXISTS
-	roles (
-		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		uuid		varchar(36) NOT NULL,
-		name		varchar(150) NOT NULL,
-		description	varchar(200),
-		created_at	datetime NOT NULL,
-		created_by	integer NOT NULL,
-		updated_at	datetime,
-		updated_by	integer
-	);
-	INSERT OR IGNORE INTO roles (id, uuid, name, description, created_at, created_by, updated_at, updated_by) VALUES (1, ?, 'Administrator', 'Administrators', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO roles (id, uuid, name, description, created_at, created_by, updated_at, updated_by) VALUES (2, ?, 'Editor', 'Editors', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO roles (id, uuid, name, description, created_at, created_by, updated_at, updated_by) VALUES (3, ?, 'Author', 'Authors', ?, 1, ?, 1);
-	INSERT OR IGNORE INTO roles (id, uuid, name, description, created_at, created_by, updated_at, updated_by) VALUES (4, ?, 'Owner', 'Blog Owner', ?, 1, ?, 1);
-	CREATE TABLE IF NOT EXISTS
-	roles_users (
-		id		integer NOT NULL PRIMARY KEY AUTOINCREMENT,
-		role_id	integer NOT NULL,
-		user_id	integer NOT NULL
-	);
-	`
+const stmtRetrieveRoleCountById = "SELECT count(*) FROM roles WHERE id = ?"
+const stmtInsertRole = "INSERT INTO roles (id, uuid, name, description, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
 
-func Initialize() error {
-	// If journey.db does not exist, look for a Ghost database to convert
-	if !helpers.FileExists(filenames.DatabaseFilename) {
-		// Convert Ghost database if available (time format needs to change to be compatible with journey)
-		migration.Ghost()
+type defaultRole struct {
+	Id          int
+	Name        string
+	Description string
+}
+
+// The ids of the roles are fixed (see structure.RoleAdministrator etc.)
+var defaultRoles = []defaultRole{
+	defaultRole{Id: structure.RoleAdministrator, Name: "Administrator", Description: "Administrators"},
+	defaultRole{Id: structure.RoleEditor, Name: "Editor", Description: "Editors"},
+	defaultRole{Id: structure.RoleAuthor, Name: "Author", Description: "Authors"},
+	defaultRole{Id: structure.RoleOwner, Name: "Owner", Description: "Blog Owner"},
+}
+
+// Function to open the configured database without changing it (see Initialize).
+func Open() error {
+	currentDialect = configuration.Config.DatabaseDialect
+	connection := configuration.Config.DatabaseConnection
+	if currentDialect == DialectSqlite && connection == "" {
+		connection = filenames.DatabaseFilename
+		// If journey.db does not exist, look for a Ghost database to convert
+		if !helpers.FileExists(filenames.DatabaseFilename) {
+			// Convert Ghost database if available (time format needs to change to be compatible with journey)
+			migration.Ghost()
+		}
 	}
-	// Open or create database file
+	// Open or create database
 	var err error
-	readDB, err = sql.Open("sqlite3", filenames.DatabaseFilename)
+	readDB, err = openDatabase(currentDialect, connection)
 	if err != nil {
 		return err
 	}
 	readDB.SetMaxIdleConns(256) // TODO: is this enough?
-	err = readDB.Ping()
+	return readDB.Ping()
+}
+
+// Function to open the configured database and bring its schema up to date.
+func Initialize() error {
+	err := Open()
 	if err != nil {
 		return err
 	}
-	currentTime := date.GetCurrentTime()
-	_, err = readDB.Exec(stmtInitialization, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime, uuid.NewV4().String(), currentTime, currentTime)
-	// TODO: Is Commit()/Rollback() needed for DB.Exec()?
+	err = migration.Up(migrationDatabase{readDB})
+	if err != nil {
+		return err
+	}
+	err = checkRoles()
+	if err != nil {
+		return err
+	}
+	// The search index is optional (the SQLite library may have been built without FTS5)
+	err = checkSearchIndex()
 	if err != nil {
 		return err
 	}
@@ -156,6 +79,67 @@ func Initialize() error {
 	return nil
 }
 
+func MigrationStatus() ([]migration.MigrationStatus, error) {
+	return migration.Status(migrationDatabase{readDB})
+}
+
+// Function to revert the most recently applied schema migration.
+func MigrateDown() (*migration.Migration, error) {
+	return migrateDown(readDB)
+}
+
+// Migrations that rebuild a table (e.g. to drop columns on SQLite) give its ids a new sequence on PostgreSQL, so the
+// sequences are set to the highest ids after reverting a migration.
+func migrateDown(db *DB) (*migration.Migration, error) {
+	reverted, err := migration.Down(migrationDatabase{db})
+	if err != nil || reverted == nil {
+		return reverted, err
+	}
+	writeDB, err := db.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return nil, err
+	}
+	err = writeDB.resetSequences()
+	if err != nil {
+		writeDB.Rollback()
+		return nil, err
+	}
+	return reverted, writeDB.Commit()
+}
+
+// Function to insert any missing roles into the database.
+func checkRoles() error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	currentTime := date.GetCurrentTime()
+	for _, role := range defaultRoles {
+		var count int64
+		err = writeDB.QueryRow(stmtRetrieveRoleCountById, role.Id).Scan(&count)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+		if count != 0 {
+			continue
+		}
+		_, err = writeDB.Exec(stmtInsertRole, role.Id, uuid.NewV4().String(), role.Name, role.Description, currentTime, 1, currentTime, 1)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+	}
+	err = writeDB.resetSequence("roles")
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
 // Function to check and insert any missing blog settings into the database (settings could be missing if migrating from Ghost).
 func checkBlogSettings() error {
 	tempBlog := structure.Blog{}
@@ -241,5 +225,24 @@ func checkBlogSettings() error {
 			return err
 		}
 	}
+	// Check for code injection
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_head")
+	err = row.Scan(&tempBlog.CodeinjectionHead)
+	if err != nil {
+		// Insert codeinjection_head
+		err = insertSettingString("codeinjection_head", "", "blog", date.GetCurrentTime(), 1)
+		if err != nil {
+			return err
+		}
+	}
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_foot")
+	err = row.Scan(&tempBlog.CodeinjectionFoot)
+	if err != nil {
+		// Insert codeinjection_foot
+		err = insertSettingString("codeinjection_foot", "", "blog", date.GetCurrentTime(), 1)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
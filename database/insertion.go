@@ -1,23 +1,37 @@
 package database
 
 import (
-	"database/sql"
 	"time"
 
 	"github.com/satori/go.uuid"
 )
 
-const stmtInsertPost = "INSERT INTO posts (id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, created_at, created_by, updated_at, updated_by, published_at, published_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-const stmtInsertUser = "INSERT INTO users (id, uuid, name, slug, password, email, image, cover, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
-const stmtInsertRoleUser = "INSERT INTO roles_users (id, role_id, user_id) VALUES (?, ?, ?)"
-const stmtInsertTag = "INSERT INTO tags (id, uuid, name, slug, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
-const stmtInsertPostTag = "INSERT INTO posts_tags (id, post_id, tag_id) VALUES (?, ?, ?)"
-const stmtInsertSetting = "INSERT INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertPost = "INSERT INTO posts (uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, created_at, created_by, updated_at, updated_by, published_at, published_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertUser = "INSERT INTO users (uuid, name, slug, password, email, image, cover, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertRoleUser = "INSERT INTO roles_users (role_id, user_id) VALUES (?, ?)"
+const stmtInsertTag = "INSERT INTO tags (uuid, name, slug, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertWebhook = "INSERT INTO webhooks (event, target_url, secret, created_at, created_by) VALUES (?, ?, ?, ?, ?)"
+const stmtInsertWebhookDelivery = "INSERT INTO webhook_deliveries (webhook_id, event, target_url, payload, attempt, status, status_code, error, duration_ms, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertPostTag = "INSERT INTO posts_tags (post_id, tag_id) VALUES (?, ?)"
+const stmtInsertInvite = "INSERT INTO invites (token, email, role_id, expires_at, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?)"
+const stmtInsertPostRevision = "INSERT INTO post_revisions (post_id, title, markdown, html, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?)"
+const stmtInsertContentKey = "INSERT INTO content_keys (name, secret, created_at, created_by) VALUES (?, ?, ?, ?)"
+const stmtInsertLoginAttempt = "INSERT INTO login_attempts (name, ip, user_agent, successful, created_at) VALUES (?, ?, ?, ?, ?)"
+const stmtInsertTwoFactor = "INSERT INTO two_factor (user_id, secret, confirmed, last_step, created_at) VALUES (?, ?, ?, ?, ?)"
+const stmtInsertRecoveryCode = "INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)"
+const stmtInsertSession = "INSERT INTO sessions (token_hash, user_id, user_agent, ip, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertApiToken = "INSERT INTO api_tokens (name, token_hash, user_id, created_at) VALUES (?, ?, ?, ?)"
+const stmtInsertSetting = "INSERT INTO settings (uuid, \"key\", value, type, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertMedia = "INSERT INTO media (path, mime_type, size, width, height, uploaded_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertPostMediaByPath = "INSERT INTO posts_media (post_id, media_id) SELECT ?, id FROM media WHERE path = ?"
+const stmtInsertComment = "INSERT INTO comments (post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 
-func InsertPost(title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, meta_description []byte, image []byte, created_at time.Time, created_by int64) (int64, error) {
+func InsertPost(title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, meta_description []byte, image []byte, codeinjection_head []byte, codeinjection_foot []byte, created_at time.Time, created_by int64, scheduled_at *time.Time) (int64, error) {
 
 	status := "draft"
-	if published {
+	if scheduled_at != nil {
+		status = "scheduled"
+	} else if published {
 		status = "published"
 	}
 	writeDB, err := readDB.Begin()
@@ -25,50 +39,71 @@ func InsertPost(title []byte, slug string, markdown []byte, html []byte, feature
 		writeDB.Rollback()
 		return 0, err
 	}
-	var result sql.Result
-	if published {
-		result, err = writeDB.Exec(stmtInsertPost, nil, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, created_by, created_at, created_by, created_at, created_by, created_at, created_by)
+	var postId int64
+	if scheduled_at != nil {
+		// Scheduled posts get their future publication date right away
+		postId, err = writeDB.insert(stmtInsertPost, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, codeinjection_head, codeinjection_foot, created_by, created_at, created_by, created_at, created_by, *scheduled_at, created_by)
+	} else if published {
+		postId, err = writeDB.insert(stmtInsertPost, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, codeinjection_head, codeinjection_foot, created_by, created_at, created_by, created_at, created_by, created_at, created_by)
 	} else {
-		result, err = writeDB.Exec(stmtInsertPost, nil, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, created_by, created_at, created_by, created_at, created_by, nil, nil)
+		postId, err = writeDB.insert(stmtInsertPost, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, codeinjection_head, codeinjection_foot, created_by, created_at, created_by, created_at, created_by, nil, nil)
 	}
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	postId, err := result.LastInsertId()
+	return postId, writeDB.Commit()
+}
+
+func InsertUser(name []byte, slug string, password string, email []byte, image []byte, cover []byte, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	return postId, writeDB.Commit()
+	userId, err := writeDB.insert(stmtInsertUser, uuid.NewV4().String(), name, slug, password, email, image, cover, created_at, created_by, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return userId, writeDB.Commit()
 }
 
-func InsertUser(name []byte, slug string, password string, email []byte, image []byte, cover []byte, created_at time.Time, created_by int64) (int64, error) {
+func InsertRoleUser(role_id int, user_id int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
-		return 0, err
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertRoleUser, role_id, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
 	}
-	result, err := writeDB.Exec(stmtInsertUser, nil, uuid.NewV4().String(), name, slug, password, email, image, cover, created_at, created_by, created_at, created_by)
+	return writeDB.Commit()
+}
+
+func InsertTag(name []byte, slug string, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	userId, err := result.LastInsertId()
+	tagId, err := writeDB.insert(stmtInsertTag, uuid.NewV4().String(), name, slug, created_at, created_by, created_at, created_by)
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	return userId, writeDB.Commit()
+	return tagId, writeDB.Commit()
 }
 
-func InsertRoleUser(role_id int, user_id int64) error {
+func InsertPostTag(post_id int64, tag_id int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return err
 	}
-	_, err = writeDB.Exec(stmtInsertRoleUser, nil, role_id, user_id)
+	_, err = writeDB.Exec(stmtInsertPostTag, post_id, tag_id)
 	if err != nil {
 		writeDB.Rollback()
 		return err
@@ -76,32 +111,69 @@ func InsertRoleUser(role_id int, user_id int64) error {
 	return writeDB.Commit()
 }
 
-func InsertTag(name []byte, slug string, created_at time.Time, created_by int64) (int64, error) {
+func InsertInvite(token string, email []byte, role_id int, expires_at time.Time, created_at time.Time, created_by int64) (int64, error) {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	result, err := writeDB.Exec(stmtInsertTag, nil, uuid.NewV4().String(), name, slug, created_at, created_by, created_at, created_by)
+	inviteId, err := writeDB.insert(stmtInsertInvite, token, email, role_id, expires_at, created_at, created_by)
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	tagId, err := result.LastInsertId()
+	return inviteId, writeDB.Commit()
+}
+
+func InsertPostRevision(post_id int64, title []byte, markdown []byte, html []byte, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	return tagId, writeDB.Commit()
+	revisionId, err := writeDB.insert(stmtInsertPostRevision, post_id, title, markdown, html, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return revisionId, writeDB.Commit()
 }
 
-func InsertPostTag(post_id int64, tag_id int64) error {
+func InsertContentKey(name []byte, secret string, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	keyId, err := writeDB.insert(stmtInsertContentKey, name, secret, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return keyId, writeDB.Commit()
+}
+
+func InsertApiToken(name []byte, token_hash string, user_id int64, created_at time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	tokenId, err := writeDB.insert(stmtInsertApiToken, name, token_hash, user_id, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return tokenId, writeDB.Commit()
+}
+
+func InsertLoginAttempt(name []byte, ip []byte, user_agent []byte, successful bool, created_at time.Time) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return err
 	}
-	_, err = writeDB.Exec(stmtInsertPostTag, nil, post_id, tag_id)
+	_, err = writeDB.Exec(stmtInsertLoginAttempt, name, ip, user_agent, successful, created_at)
 	if err != nil {
 		writeDB.Rollback()
 		return err
@@ -109,13 +181,74 @@ func InsertPostTag(post_id int64, tag_id int64) error {
 	return writeDB.Commit()
 }
 
+// Inserts a new (unconfirmed) TOTP secret for a user. Replaces an existing secret and its recovery codes.
+func InsertTwoFactor(user_id int64, secret string, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTwoFactorByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertTwoFactor, user_id, secret, false, 0, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Inserts new recovery codes for a user. Replaces the existing ones.
+func InsertRecoveryCodes(user_id int64, code_hashes []string, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	for _, code_hash := range code_hashes {
+		_, err = writeDB.Exec(stmtInsertRecoveryCode, user_id, code_hash, created_at)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+	}
+	return writeDB.Commit()
+}
+
+func InsertSession(token_hash string, user_id int64, user_agent []byte, ip []byte, created_at time.Time, expires_at time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	sessionId, err := writeDB.insert(stmtInsertSession, token_hash, user_id, user_agent, ip, created_at, created_at, expires_at)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return sessionId, writeDB.Commit()
+}
+
 func insertSettingString(key string, value string, setting_type string, created_at time.Time, created_by int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return err
 	}
-	_, err = writeDB.Exec(stmtInsertSetting, nil, uuid.NewV4().String(), key, value, setting_type, created_at, created_by, created_at, created_by)
+	_, err = writeDB.Exec(stmtInsertSetting, uuid.NewV4().String(), key, value, setting_type, created_at, created_by, created_at, created_by)
 	if err != nil {
 		writeDB.Rollback()
 		return err
@@ -129,7 +262,63 @@ func insertSettingInt64(key string, value int64, setting_type string, created_at
 		writeDB.Rollback()
 		return err
 	}
-	_, err = writeDB.Exec(stmtInsertSetting, nil, uuid.NewV4().String(), key, value, setting_type, created_at, created_by, created_at, created_by)
+	_, err = writeDB.Exec(stmtInsertSetting, uuid.NewV4().String(), key, value, setting_type, created_at, created_by, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func InsertMedia(path string, mime_type string, size int64, width int, height int, uploaded_by int64, created_at time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	mediaId, err := writeDB.insert(stmtInsertMedia, path, mime_type, size, width, height, uploaded_by, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return mediaId, writeDB.Commit()
+}
+
+func InsertComment(post_id int64, parent_id int64, author_name []byte, author_email []byte, author_url []byte, content []byte, status string, ip []byte, user_agent []byte, created_at time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	commentId, err := writeDB.insert(stmtInsertComment, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return commentId, writeDB.Commit()
+}
+
+func InsertWebhook(event string, target_url string, secret string, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	webhookId, err := writeDB.insert(stmtInsertWebhook, event, target_url, secret, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return webhookId, writeDB.Commit()
+}
+
+func InsertWebhookDelivery(webhook_id int64, event string, target_url string, payload []byte, attempt int, status string, status_code int, delivery_error string, duration_ms int64, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertWebhookDelivery, webhook_id, event, target_url, payload, attempt, status, status_code, delivery_error, duration_ms, created_at)
 	if err != nil {
 		writeDB.Rollback()
 		return err
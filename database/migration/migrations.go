@@ -0,0 +1,404 @@
+package migration
+
+// Statements are written in SQLite syntax. They are translated to the configured database dialect before they are executed.
+// Never change a migration that has been released. Add a new migration with the next version number instead.
+// MySQL commits schema statements (CREATE, ALTER, DROP) immediately, so the statements of a migration that fails halfway
+// stay applied there and the migration isn't recorded.
+var Migrations = []Migration{
+	Migration{
+		Version: 1,
+		Name:    "initial schema",
+		// IF NOT EXISTS: databases created before schema migrations existed contain these tables already
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS
+				posts (
+					id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					uuid				varchar(36) NOT NULL,
+					title				varchar(150) NOT NULL,
+					slug				varchar(150) NOT NULL,
+					markdown			text,
+					html				text,
+					image				text,
+					featured			tinyint NOT NULL DEFAULT '0',
+					page				tinyint NOT NULL DEFAULT '0',
+					status				varchar(150) NOT NULL DEFAULT 'draft',
+					language			varchar(6) NOT NULL DEFAULT 'en_US',
+					meta_title			varchar(150),
+					meta_description	varchar(200),
+					author_id			integer NOT NULL,
+					created_at			datetime NOT NULL,
+					created_by			integer NOT NULL,
+					updated_at			datetime,
+					updated_by			integer,
+					published_at		datetime,
+					published_by		integer
+				)`,
+			`CREATE TABLE IF NOT EXISTS
+				users (
+					id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					uuid				varchar(36) NOT NULL,
+					name				varchar(150) NOT NULL,
+					slug				varchar(150) NOT NULL,
+					password			varchar(60) NOT NULL,
+					email				varchar(254) NOT NULL,
+					image				text,
+					cover				text,
+					bio					varchar(200),
+					website				text,
+					location			text,
+					accessibility		text,
+					status				varchar(150) NOT NULL DEFAULT 'active',
+					language			varchar(6) NOT NULL DEFAULT 'en_US',
+					meta_title			varchar(150),
+					meta_description	varchar(200),
+					last_login			datetime,
+					created_at			datetime NOT NULL,
+					created_by			integer NOT NULL,
+					updated_at			datetime,
+					updated_by			integer
+				)`,
+			`CREATE TABLE IF NOT EXISTS
+				tags (
+					id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					uuid				varchar(36) NOT NULL,
+					name				varchar(150) NOT NULL,
+					slug				varchar(150) NOT NULL,
+					description			varchar(200),
+					parent_id			integer,
+					meta_title			varchar(150),
+					meta_description	varchar(200),
+					created_at			datetime NOT NULL,
+					created_by			integer NOT NULL,
+					updated_at			datetime,
+					updated_by			integer
+				)`,
+			`CREATE TABLE IF NOT EXISTS
+				posts_tags (
+					id		integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					post_id	integer NOT NULL,
+					tag_id	integer NOT NULL
+				)`,
+			`CREATE TABLE IF NOT EXISTS
+				settings (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					uuid		varchar(36) NOT NULL,
+					"key"		varchar(150) NOT NULL,
+					value		text,
+					type		varchar(150) NOT NULL DEFAULT 'core',
+					created_at	datetime NOT NULL,
+					created_by	integer NOT NULL,
+					updated_at	datetime,
+					updated_by	integer
+				)`,
+			`CREATE TABLE IF NOT EXISTS
+				roles (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					uuid		varchar(36) NOT NULL,
+					name		varchar(150) NOT NULL,
+					description	varchar(200),
+					created_at	datetime NOT NULL,
+					created_by	integer NOT NULL,
+					updated_at	datetime,
+					updated_by	integer
+				)`,
+			`CREATE TABLE IF NOT EXISTS
+				roles_users (
+					id		integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					role_id	integer NOT NULL,
+					user_id	integer NOT NULL
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE roles_users",
+			"DROP TABLE roles",
+			"DROP TABLE settings",
+			"DROP TABLE posts_tags",
+			"DROP TABLE tags",
+			"DROP TABLE users",
+			"DROP TABLE posts",
+		},
+	},
+	Migration{
+		Version: 2,
+		Name:    "invites",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS
+				invites (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					token		varchar(64) NOT NULL,
+					email		varchar(254) NOT NULL,
+					role_id		integer NOT NULL,
+					expires_at	datetime NOT NULL,
+					created_at	datetime NOT NULL,
+					created_by	integer NOT NULL
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE invites",
+		},
+	},
+	Migration{
+		Version: 3,
+		Name:    "post revisions",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS
+				post_revisions (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					post_id		integer NOT NULL,
+					title		varchar(150) NOT NULL,
+					markdown	text,
+					html		text,
+					created_at	datetime NOT NULL,
+					created_by	integer NOT NULL
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE post_revisions",
+		},
+	},
+	Migration{
+		Version: 4,
+		Name:    "content api keys",
+		Up: []string{
+			`CREATE TABLE
+				content_keys (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					name		varchar(150) NOT NULL,
+					secret		varchar(64) NOT NULL,
+					created_at	datetime NOT NULL,
+					created_by	integer NOT NULL
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE content_keys",
+		},
+	},
+	Migration{
+		Version: 5,
+		Name:    "admin api tokens",
+		Up: []string{
+			`CREATE TABLE
+				api_tokens (
+					id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					name			varchar(150) NOT NULL,
+					token_hash		varchar(64) NOT NULL UNIQUE,
+					user_id			integer NOT NULL,
+					created_at		datetime NOT NULL,
+					last_used_at	datetime
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE api_tokens",
+		},
+	},
+	Migration{
+		Version: 6,
+		Name:    "sessions",
+		Up: []string{
+			`CREATE TABLE
+				sessions (
+					id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					token_hash		varchar(64) NOT NULL UNIQUE,
+					user_id			integer NOT NULL,
+					user_agent		varchar(254),
+					ip				varchar(45),
+					created_at		datetime NOT NULL,
+					last_seen_at	datetime NOT NULL,
+					expires_at		datetime NOT NULL
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE sessions",
+		},
+	},
+	Migration{
+		Version: 7,
+		Name:    "login attempts",
+		Up: []string{
+			`CREATE TABLE
+				login_attempts (
+					id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					name			varchar(150) NOT NULL,
+					ip				varchar(45) NOT NULL,
+					user_agent		varchar(254),
+					successful		tinyint NOT NULL DEFAULT '0',
+					created_at		datetime NOT NULL
+				)`,
+			"CREATE INDEX login_attempts_name ON login_attempts (name)",
+			"CREATE INDEX login_attempts_ip ON login_attempts (ip)",
+		},
+		Down: []string{
+			"DROP TABLE login_attempts",
+		},
+	},
+	Migration{
+		Version: 8,
+		Name:    "two-factor authentication",
+		Up: []string{
+			`CREATE TABLE
+				two_factor (
+					user_id		integer NOT NULL PRIMARY KEY,
+					secret		varchar(64) NOT NULL,
+					confirmed	tinyint NOT NULL DEFAULT '0',
+					last_step	integer NOT NULL DEFAULT '0',
+					created_at	datetime NOT NULL
+				)`,
+			`CREATE TABLE
+				recovery_codes (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					user_id		integer NOT NULL,
+					code_hash	varchar(64) NOT NULL,
+					created_at	datetime NOT NULL
+				)`,
+		},
+		Down: []string{
+			"DROP TABLE recovery_codes",
+			"DROP TABLE two_factor",
+		},
+	},
+	Migration{
+		Version: 9,
+		Name:    "media library",
+		Up: []string{
+			`CREATE TABLE
+				media (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					path		varchar(255) NOT NULL,
+					mime_type	varchar(50) NOT NULL,
+					size		integer NOT NULL DEFAULT '0',
+					width		integer NOT NULL DEFAULT '0',
+					height		integer NOT NULL DEFAULT '0',
+					alt			text,
+					caption		text,
+					uploaded_by	integer NOT NULL,
+					created_at	datetime NOT NULL
+				)`,
+			"CREATE UNIQUE INDEX media_path ON media (path)",
+			`CREATE TABLE
+				posts_media (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					post_id		integer NOT NULL,
+					media_id	integer NOT NULL
+				)`,
+			"CREATE INDEX posts_media_post_id ON posts_media (post_id)",
+			"CREATE INDEX posts_media_media_id ON posts_media (media_id)",
+		},
+		Down: []string{
+			"DROP TABLE posts_media",
+			"DROP TABLE media",
+		},
+	},
+	Migration{
+		Version: 10,
+		Name:    "comments",
+		Up: []string{
+			`CREATE TABLE
+				comments (
+					id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					post_id			integer NOT NULL,
+					parent_id		integer NOT NULL DEFAULT '0',
+					author_name		varchar(150) NOT NULL,
+					author_email	varchar(254) NOT NULL,
+					author_url		varchar(2000),
+					content			text NOT NULL,
+					status			varchar(150) NOT NULL DEFAULT 'pending',
+					ip				varchar(45) NOT NULL,
+					user_agent		varchar(254),
+					created_at		datetime NOT NULL
+				)`,
+			"CREATE INDEX comments_post_id ON comments (post_id)",
+			"CREATE INDEX comments_status ON comments (status)",
+			"CREATE INDEX comments_ip ON comments (ip)",
+		},
+		Down: []string{
+			"DROP TABLE comments",
+		},
+	},
+	Migration{
+		Version: 11,
+		Name:    "webhooks",
+		Up: []string{
+			`CREATE TABLE
+				webhooks (
+					id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					event		varchar(50) NOT NULL,
+					target_url	varchar(2000) NOT NULL,
+					secret		varchar(64) NOT NULL,
+					created_at	datetime NOT NULL,
+					created_by	integer NOT NULL
+				)`,
+			"CREATE INDEX webhooks_event ON webhooks (event)",
+			`CREATE TABLE
+				webhook_deliveries (
+					id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					webhook_id		integer NOT NULL,
+					event			varchar(50) NOT NULL,
+					target_url		varchar(2000) NOT NULL,
+					payload			text NOT NULL,
+					attempt			integer NOT NULL DEFAULT '1',
+					status			varchar(50) NOT NULL,
+					status_code		integer NOT NULL DEFAULT '0',
+					error			text,
+					duration_ms		integer NOT NULL DEFAULT '0',
+					created_at		datetime NOT NULL
+				)`,
+			"CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id)",
+		},
+		Down: []string{
+			"DROP TABLE webhook_deliveries",
+			"DROP TABLE webhooks",
+		},
+	},
+	Migration{
+		Version: 12,
+		Name:    "post code injection",
+		// The site-wide code injection settings are inserted by checkBlogSettings (they exist already in databases converted
+		// from Ghost) and are left in place when reverting
+		Up: []string{
+			"ALTER TABLE posts ADD COLUMN codeinjection_head text",
+			"ALTER TABLE posts ADD COLUMN codeinjection_foot text",
+		},
+		// SQLite can't drop columns before version 3.35, so the posts table is rebuilt without them
+		Down: []string{
+			`CREATE TABLE
+				posts_new (
+					id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+					uuid				varchar(36) NOT NULL,
+					title				varchar(150) NOT NULL,
+					slug				varchar(150) NOT NULL,
+					markdown			text,
+					html				text,
+					image				text,
+					featured			tinyint NOT NULL DEFAULT '0',
+					page				tinyint NOT NULL DEFAULT '0',
+					status				varchar(150) NOT NULL DEFAULT 'draft',
+					language			varchar(6) NOT NULL DEFAULT 'en_US',
+					meta_title			varchar(150),
+					meta_description	varchar(200),
+					author_id			integer NOT NULL,
+					created_at			datetime NOT NULL,
+					created_by			integer NOT NULL,
+					updated_at			datetime,
+					updated_by			integer,
+					published_at		datetime,
+					published_by		integer
+				)`,
+			`INSERT INTO posts_new (id, uuid, title, slug, markdown, html, image, featured, page, status, language, meta_title, meta_description, author_id, created_at, created_by, updated_at, updated_by, published_at, published_by)
+				SELECT id, uuid, title, slug, markdown, html, image, featured, page, status, language, meta_title, meta_description, author_id, created_at, created_by, updated_at, updated_by, published_at, published_by FROM posts`,
+			"DROP TABLE posts",
+			"ALTER TABLE posts_new RENAME TO posts",
+		},
+	},
+	Migration{
+		Version: 13,
+		Name:    "search index",
+		// The rowid of each entry is the id of the indexed post. IF NOT EXISTS: the index was created outside of the migrations before.
+		Up: []string{
+			"CREATE VIRTUAL TABLE IF NOT EXISTS posts_search USING fts5(title, content, tags)",
+		},
+		Down: []string{
+			"DROP TABLE posts_search",
+		},
+		Requires: FeatureFts5,
+	},
+}
@@ -0,0 +1,159 @@
+package migration
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/date"
+)
+
+const stmtInitializeSchemaMigrations = `CREATE TABLE IF NOT EXISTS
+	schema_migrations (
+		version		integer NOT NULL PRIMARY KEY,
+		name		varchar(150) NOT NULL,
+		applied_at	datetime NOT NULL
+	)`
+const stmtRetrieveSchemaMigrations = "SELECT version, applied_at FROM schema_migrations ORDER BY version ASC"
+const stmtInsertSchemaMigration = "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"
+const stmtDeleteSchemaMigration = "DELETE FROM schema_migrations WHERE version = ?"
+
+// Features that a migration can require (see Migration.Requires)
+const (
+	FeatureFts5 = "fts5" // SQLite full-text search
+)
+
+// Migration: a numbered change of the database schema. Down reverts the changes made by Up.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []string
+	Down    []string
+	// Requires: a feature the database has to support (e.g. FeatureFts5). If it doesn't, the migration is skipped and stays pending.
+	Requires string
+}
+
+// MigrationStatus: a migration and the time it was applied at (nil if the migration is pending).
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Database: the database the migrations are applied to. Implemented by the database package, which translates the statements to the configured dialect.
+type Database interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Begin() (Transaction, error)
+	Supports(feature string) bool
+}
+
+type Transaction interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// Function to apply all pending migrations in order. Every migration runs in its own transaction (except for the schema
+// statements on MySQL, which can't be rolled back).
+func Up(db Database) error {
+	statuses, err := Status(db)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+		if status.Requires != "" && !db.Supports(status.Requires) {
+			log.Println("Skipped database migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + "): the database doesn't support " + status.Requires + ".")
+			continue
+		}
+		err = apply(db, status.Migration.Up, func(tx Transaction) error {
+			_, err := tx.Exec(stmtInsertSchemaMigration, status.Version, status.Name, date.GetCurrentTime())
+			return err
+		})
+		if err != nil {
+			return errors.New("Couldn't apply migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + "): " + err.Error())
+		}
+		log.Println("Applied database migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + ").")
+	}
+	return nil
+}
+
+// Function to revert the most recently applied migration. Returns the reverted migration or nil if no migration was applied.
+func Down(db Database) (*Migration, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	for index := len(statuses) - 1; index >= 0; index-- {
+		status := statuses[index]
+		if status.AppliedAt == nil {
+			continue
+		}
+		err = apply(db, status.Migration.Down, func(tx Transaction) error {
+			_, err := tx.Exec(stmtDeleteSchemaMigration, status.Version)
+			return err
+		})
+		if err != nil {
+			return nil, errors.New("Couldn't revert migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + "): " + err.Error())
+		}
+		log.Println("Reverted database migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + ").")
+		return &status.Migration, nil
+	}
+	return nil, nil
+}
+
+// Function to get all known migrations and whether they have been applied.
+func Status(db Database) ([]MigrationStatus, error) {
+	_, err := db.Exec(stmtInitializeSchemaMigrations)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]*time.Time)
+	rows, err := db.Query(stmtRetrieveSchemaMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = &appliedAt
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(Migrations))
+	for index, migration := range Migrations {
+		statuses[index] = MigrationStatus{Migration: migration, AppliedAt: applied[migration.Version]}
+	}
+	return statuses, nil
+}
+
+func apply(db Database, statements []string, record func(Transaction) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	err = record(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
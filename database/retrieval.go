@@ -10,12 +10,14 @@ import (
 const stmtRetrievePostsCount = "SELECT count(*) FROM posts WHERE page = 0 AND status = 'published'"
 const stmtRetrievePostsCountByUser = "SELECT count(*) FROM posts WHERE page = 0 AND status = 'published' AND author_id = ?"
 const stmtRetrievePostsCountByTag = "SELECT count(*) FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published'"
-const stmtRetrievePostsForIndex = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE page = 0 AND status = 'published' ORDER BY published_at DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostsForApi = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts ORDER BY id DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostsByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE page = 0 AND status = 'published' AND author_id = ? ORDER BY published_at DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostsByTag = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.author_id, posts.published_at FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published' ORDER BY posts.published_at DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostById = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE id = ?"
-const stmtRetrievePostBySlug = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE slug = ?"
+const stmtRetrievePostsForIndex = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE page = 0 AND status = 'published' ORDER BY published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApi = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApiByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE author_id = ? ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE page = 0 AND status = 'published' AND author_id = ? ORDER BY published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsByTag = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.codeinjection_head, posts.codeinjection_foot, posts.author_id, posts.published_at, posts.updated_at FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published' ORDER BY posts.published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrieveScheduledPostsDue = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE status = 'scheduled' AND published_at <= ? ORDER BY published_at ASC"
+const stmtRetrievePostById = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE id = ?"
+const stmtRetrievePostBySlug = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE slug = ?"
 const stmtRetrieveUserById = "SELECT id, name, slug, email, image, cover, bio, website, location FROM users WHERE id = ?"
 const stmtRetrieveUserBySlug = "SELECT id, name, slug, email, image, cover, bio, website, location FROM users WHERE slug = ?"
 const stmtRetrieveUserByName = "SELECT id, name, slug, email, image, cover, bio, website, location FROM users WHERE name = ?"
@@ -23,10 +25,54 @@ const stmtRetrieveTags = "SELECT tag_id FROM posts_tags WHERE post_id = ?"
 const stmtRetrieveTagById = "SELECT id, name, slug FROM tags WHERE id = ?"
 const stmtRetrieveTagBySlug = "SELECT id, name, slug FROM tags WHERE slug = ?"
 const stmtRetrieveTagIdBySlug = "SELECT id FROM tags WHERE slug = ?"
-const stmtRetrieveHashedPasswordByName = "SELECT password FROM users WHERE name = ?"
+const stmtRetrieveUsers = "SELECT users.id, users.name, users.slug, users.email, users.image, users.cover, users.bio, users.website, users.location, users.status, roles_users.role_id FROM users, roles_users WHERE roles_users.user_id = users.id ORDER BY users.id ASC"
+const stmtRetrieveUserWithRoleById = "SELECT users.id, users.name, users.slug, users.email, users.image, users.cover, users.bio, users.website, users.location, users.status, roles_users.role_id FROM users, roles_users WHERE roles_users.user_id = users.id AND users.id = ?"
+const stmtRetrieveUserWithRoleByName = "SELECT users.id, users.name, users.slug, users.email, users.image, users.cover, users.bio, users.website, users.location, users.status, roles_users.role_id FROM users, roles_users WHERE roles_users.user_id = users.id AND users.name = ?"
+const stmtRetrieveUserWithRoleByEmail = "SELECT users.id, users.name, users.slug, users.email, users.image, users.cover, users.bio, users.website, users.location, users.status, roles_users.role_id FROM users, roles_users WHERE roles_users.user_id = users.id AND lower(users.email) = lower(?) ORDER BY users.id ASC LIMIT 1"
+const stmtRetrieveInviteByToken = "SELECT id, email, role_id, expires_at, created_by FROM invites WHERE token = ?"
+const stmtRetrieveHashedPasswordByName = "SELECT password FROM users WHERE name = ? AND status != 'inactive'"
+const stmtRetrievePostRevisions = "SELECT id, post_id, title, markdown, html, created_at, created_by FROM post_revisions WHERE post_id = ? ORDER BY id DESC"
+const stmtRetrievePostRevisionById = "SELECT id, post_id, title, markdown, html, created_at, created_by FROM post_revisions WHERE id = ? AND post_id = ?"
+const stmtRetrieveOldestKeptPostRevisionId = "SELECT id FROM post_revisions WHERE post_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?"
+const stmtRetrievePublishedPostsAndPages = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, codeinjection_head, codeinjection_foot, author_id, published_at, updated_at FROM posts WHERE status = 'published' ORDER BY published_at DESC"
+const stmtRetrieveAllTags = "SELECT id, name, slug FROM tags ORDER BY name ASC"
+const stmtRetrieveContentKeys = "SELECT id, name, secret, created_at, created_by FROM content_keys ORDER BY id ASC"
+const stmtRetrieveContentKeyBySecret = "SELECT id, name, secret, created_at, created_by FROM content_keys WHERE secret = ?"
+const stmtRetrieveLoginAttempts = "SELECT id, name, ip, user_agent, successful, created_at FROM login_attempts ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveFailedLoginAttempts = "SELECT id, name, ip, user_agent, successful, created_at FROM login_attempts WHERE successful = 0 ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveLoginAttemptsByName = "SELECT id, name, ip, user_agent, successful, created_at FROM login_attempts WHERE name = ? AND created_at > ? ORDER BY created_at DESC, id DESC"
+const stmtRetrieveLoginAttemptsByIp = "SELECT id, name, ip, user_agent, successful, created_at FROM login_attempts WHERE ip = ? AND created_at > ? ORDER BY created_at DESC, id DESC"
+const stmtRetrieveTwoFactorByUserId = "SELECT user_id, secret, confirmed, last_step, created_at FROM two_factor WHERE user_id = ?"
+const stmtRetrieveRecoveryCodesCountByUserId = "SELECT count(*) FROM recovery_codes WHERE user_id = ?"
+const stmtRetrieveSessionsByUserId = "SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions WHERE user_id = ? ORDER BY last_seen_at DESC"
+const stmtRetrieveSessionByHash = "SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions WHERE token_hash = ?"
+const stmtRetrieveApiTokensByUserId = "SELECT id, name, user_id, created_at, last_used_at FROM api_tokens WHERE user_id = ? ORDER BY id ASC"
+const stmtRetrieveApiTokenById = "SELECT id, name, user_id, created_at, last_used_at FROM api_tokens WHERE id = ?"
+const stmtRetrieveApiTokenByHash = "SELECT id, name, user_id, created_at, last_used_at FROM api_tokens WHERE token_hash = ?"
 const stmtRetrieveUsersCount = "SELECT count(*) FROM users"
-const stmtRetrieveBlog = "SELECT value FROM settings WHERE key = ?"
+const stmtRetrieveBlog = "SELECT value FROM settings WHERE \"key\" = ?"
 const stmtRetrievePostCreationDateById = "SELECT created_at FROM posts WHERE id = ?"
+const stmtRetrieveMedia = "SELECT id, path, mime_type, size, width, height, alt, caption, uploaded_by, created_at FROM media ORDER BY id DESC LIMIT ? OFFSET ?"
+
+// Media that isn't referenced by a post, the blog settings (logo and cover), or a user profile (image and cover)
+const stmtRetrieveUnusedMedia = "SELECT id, path, mime_type, size, width, height, alt, caption, uploaded_by, created_at FROM media WHERE id NOT IN (SELECT media_id FROM posts_media) AND path NOT IN (SELECT value FROM settings WHERE \"key\" IN ('logo', 'cover') AND value IS NOT NULL) AND path NOT IN (SELECT image FROM users WHERE image IS NOT NULL) AND path NOT IN (SELECT cover FROM users WHERE cover IS NOT NULL) ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveMediaById = "SELECT id, path, mime_type, size, width, height, alt, caption, uploaded_by, created_at FROM media WHERE id = ?"
+const stmtRetrieveMediaByPath = "SELECT id, path, mime_type, size, width, height, alt, caption, uploaded_by, created_at FROM media WHERE path = ?"
+const stmtRetrievePostIdsByMediaId = "SELECT post_id FROM posts_media WHERE media_id = ? ORDER BY post_id ASC"
+const stmtRetrieveMediaPaths = "SELECT path FROM media"
+const stmtRetrieveCommentsByPostIdAndStatus = "SELECT id, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at FROM comments WHERE post_id = ? AND status = ? ORDER BY created_at ASC, id ASC"
+const stmtRetrieveCommentsCountByPostIdAndStatus = "SELECT count(*) FROM comments WHERE post_id = ? AND status = ?"
+const stmtRetrieveComments = "SELECT id, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at FROM comments ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveCommentsByStatus = "SELECT id, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at FROM comments WHERE status = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveCommentsByAuthorId = "SELECT id, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at FROM comments WHERE post_id IN (SELECT id FROM posts WHERE author_id = ?) ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveCommentsByAuthorIdAndStatus = "SELECT id, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at FROM comments WHERE post_id IN (SELECT id FROM posts WHERE author_id = ?) AND status = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveCommentById = "SELECT id, post_id, parent_id, author_name, author_email, author_url, content, status, ip, user_agent, created_at FROM comments WHERE id = ?"
+const stmtRetrieveCommentsCountByIp = "SELECT count(*) FROM comments WHERE ip = ? AND created_at > ?"
+const stmtRetrieveWebhooks = "SELECT id, event, target_url, secret, created_at, created_by FROM webhooks ORDER BY id ASC"
+const stmtRetrieveWebhooksByEvent = "SELECT id, event, target_url, secret, created_at, created_by FROM webhooks WHERE event = ? ORDER BY id ASC"
+const stmtRetrieveWebhookById = "SELECT id, event, target_url, secret, created_at, created_by FROM webhooks WHERE id = ?"
+const stmtRetrieveWebhookDeliveries = "SELECT id, webhook_id, event, target_url, payload, attempt, status, status_code, error, duration_ms, created_at FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveOldestKeptWebhookDeliveryId = "SELECT id FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?"
 
 func RetrievePostById(id int64) (*structure.Post, error) {
 	// Retrieve post
@@ -96,13 +142,55 @@ func RetrievePostsForApi(limit int64, offset int64) ([]structure.Post, error) {
 	return *posts, nil
 }
 
+func RetrievePostsForApiByUser(user_id int64, limit int64, offset int64) ([]structure.Post, error) {
+	// Retrieve posts
+	rows, err := readDB.Query(stmtRetrievePostsForApiByUser, user_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// Retrieves all published posts and pages (newest first)
+func RetrievePublishedPostsAndPages() ([]structure.Post, error) {
+	rows, err := readDB.Query(stmtRetrievePublishedPostsAndPages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+func RetrieveScheduledPostsDue(now time.Time) ([]structure.Post, error) {
+	// Retrieve scheduled posts whose publication date has passed
+	rows, err := readDB.Query(stmtRetrieveScheduledPostsDue, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
 func extractPosts(rows *sql.Rows) (*[]structure.Post, error) {
 	posts := make([]structure.Post, 0)
 	for rows.Next() {
 		post := structure.Post{}
 		var userId int64
 		var status string
-		err := rows.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.Image, &userId, &post.Date)
+		err := rows.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.Image, &post.CodeinjectionHead, &post.CodeinjectionFoot, &userId, &post.Date, &post.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -119,6 +207,7 @@ func extractPosts(rows *sql.Rows) (*[]structure.Post, error) {
 		} else {
 			post.IsPublished = false
 		}
+		post.IsScheduled = status == "scheduled"
 		// Retrieve user
 		post.Author, err = RetrieveUser(userId)
 		if err != nil {
@@ -138,7 +227,7 @@ func extractPost(row *sql.Row) (*structure.Post, error) {
 	post := structure.Post{}
 	var userId int64
 	var status string
-	err := row.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.Image, &userId, &post.Date)
+	err := row.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.Image, &post.CodeinjectionHead, &post.CodeinjectionFoot, &userId, &post.Date, &post.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +244,7 @@ func extractPost(row *sql.Row) (*structure.Post, error) {
 	} else {
 		post.IsPublished = false
 	}
+	post.IsScheduled = status == "scheduled"
 	// Retrieve user
 	post.Author, err = RetrieveUser(userId)
 	if err != nil {
@@ -245,6 +335,97 @@ func RetrieveUserByName(name []byte) (*structure.User, error) {
 	return &user, nil
 }
 
+func RetrieveUsers() ([]structure.User, error) {
+	users := make([]structure.User, 0)
+	// Retrieve users
+	rows, err := readDB.Query(stmtRetrieveUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user := structure.User{}
+		err := rows.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.Role)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func RetrieveUserWithRole(id int64) (*structure.User, error) {
+	user := structure.User{}
+	// Retrieve user including role and status
+	row := readDB.QueryRow(stmtRetrieveUserWithRoleById, id)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func RetrieveUserWithRoleByName(name []byte) (*structure.User, error) {
+	user := structure.User{}
+	// Retrieve user including role and status
+	row := readDB.QueryRow(stmtRetrieveUserWithRoleByName, name)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func RetrieveUserWithRoleByEmail(email []byte) (*structure.User, error) {
+	user := structure.User{}
+	// Retrieve user including role and status
+	row := readDB.QueryRow(stmtRetrieveUserWithRoleByEmail, email)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func RetrieveInviteByToken(token string) (*structure.Invite, error) {
+	invite := structure.Invite{}
+	row := readDB.QueryRow(stmtRetrieveInviteByToken, token)
+	err := row.Scan(&invite.Id, &invite.Email, &invite.Role, &invite.ExpiresAt, &invite.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func RetrievePostRevisions(post_id int64) ([]structure.PostRevision, error) {
+	revisions := make([]structure.PostRevision, 0)
+	// Retrieve revisions (newest first)
+	rows, err := readDB.Query(stmtRetrievePostRevisions, post_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		revision := structure.PostRevision{}
+		err := rows.Scan(&revision.Id, &revision.PostId, &revision.Title, &revision.Markdown, &revision.Html, &revision.CreatedAt, &revision.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func RetrievePostRevision(id int64, post_id int64) (*structure.PostRevision, error) {
+	revision := structure.PostRevision{}
+	row := readDB.QueryRow(stmtRetrievePostRevisionById, id, post_id)
+	err := row.Scan(&revision.Id, &revision.PostId, &revision.Title, &revision.Markdown, &revision.Html, &revision.CreatedAt, &revision.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
 func RetrieveTags(postId int64) ([]structure.Tag, error) {
 	tags := make([]structure.Tag, 0)
 	// Retrieve tags
@@ -268,6 +449,184 @@ func RetrieveTags(postId int64) ([]structure.Tag, error) {
 	return tags, nil
 }
 
+func RetrieveAllTags() ([]structure.Tag, error) {
+	tags := make([]structure.Tag, 0)
+	rows, err := readDB.Query(stmtRetrieveAllTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tag := structure.Tag{}
+		err := rows.Scan(&tag.Id, &tag.Name, &tag.Slug)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func RetrieveContentKeys() ([]structure.ContentKey, error) {
+	keys := make([]structure.ContentKey, 0)
+	rows, err := readDB.Query(stmtRetrieveContentKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key := structure.ContentKey{}
+		err := rows.Scan(&key.Id, &key.Name, &key.Secret, &key.CreatedAt, &key.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func RetrieveContentKeyBySecret(secret string) (*structure.ContentKey, error) {
+	key := structure.ContentKey{}
+	row := readDB.QueryRow(stmtRetrieveContentKeyBySecret, secret)
+	err := row.Scan(&key.Id, &key.Name, &key.Secret, &key.CreatedAt, &key.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func RetrieveLoginAttempts(onlyFailed bool, limit int64, offset int64) ([]structure.LoginAttempt, error) {
+	stmt := stmtRetrieveLoginAttempts
+	if onlyFailed {
+		stmt = stmtRetrieveFailedLoginAttempts
+	}
+	rows, err := readDB.Query(stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractLoginAttempts(rows)
+}
+
+// Retrieves the login attempts with a user name since the given time (newest first)
+func RetrieveLoginAttemptsByName(name []byte, since time.Time) ([]structure.LoginAttempt, error) {
+	rows, err := readDB.Query(stmtRetrieveLoginAttemptsByName, name, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractLoginAttempts(rows)
+}
+
+// Retrieves the login attempts from an ip address since the given time (newest first)
+func RetrieveLoginAttemptsByIp(ip []byte, since time.Time) ([]structure.LoginAttempt, error) {
+	rows, err := readDB.Query(stmtRetrieveLoginAttemptsByIp, ip, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractLoginAttempts(rows)
+}
+
+func extractLoginAttempts(rows *sql.Rows) ([]structure.LoginAttempt, error) {
+	attempts := make([]structure.LoginAttempt, 0)
+	for rows.Next() {
+		attempt := structure.LoginAttempt{}
+		err := rows.Scan(&attempt.Id, &attempt.Name, &attempt.Ip, &attempt.UserAgent, &attempt.Successful, &attempt.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func RetrieveTwoFactor(user_id int64) (*structure.TwoFactor, error) {
+	twoFactor := structure.TwoFactor{}
+	row := readDB.QueryRow(stmtRetrieveTwoFactorByUserId, user_id)
+	err := row.Scan(&twoFactor.UserId, &twoFactor.Secret, &twoFactor.Confirmed, &twoFactor.LastStep, &twoFactor.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func RetrieveNumberOfRecoveryCodes(user_id int64) (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveRecoveryCodesCountByUserId, user_id)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func RetrieveSessionsForUser(user_id int64) ([]structure.Session, error) {
+	sessions := make([]structure.Session, 0)
+	rows, err := readDB.Query(stmtRetrieveSessionsByUserId, user_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		session := structure.Session{}
+		err := rows.Scan(&session.Id, &session.UserId, &session.UserAgent, &session.Ip, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func RetrieveSessionByHash(token_hash string) (*structure.Session, error) {
+	session := structure.Session{}
+	row := readDB.QueryRow(stmtRetrieveSessionByHash, token_hash)
+	err := row.Scan(&session.Id, &session.UserId, &session.UserAgent, &session.Ip, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func RetrieveApiTokensForUser(user_id int64) ([]structure.ApiToken, error) {
+	tokens := make([]structure.ApiToken, 0)
+	rows, err := readDB.Query(stmtRetrieveApiTokensByUserId, user_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token := structure.ApiToken{}
+		err := rows.Scan(&token.Id, &token.Name, &token.UserId, &token.CreatedAt, &token.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func RetrieveApiToken(id int64) (*structure.ApiToken, error) {
+	token := structure.ApiToken{}
+	row := readDB.QueryRow(stmtRetrieveApiTokenById, id)
+	err := row.Scan(&token.Id, &token.Name, &token.UserId, &token.CreatedAt, &token.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func RetrieveApiTokenByHash(token_hash string) (*structure.ApiToken, error) {
+	token := structure.ApiToken{}
+	row := readDB.QueryRow(stmtRetrieveApiTokenByHash, token_hash)
+	err := row.Scan(&token.Id, &token.Name, &token.UserId, &token.CreatedAt, &token.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
 func RetrieveTag(tagId int64) (*structure.Tag, error) {
 	tag := structure.Tag{}
 	// Retrieve tag
@@ -365,6 +724,17 @@ func RetrieveBlog() (*structure.Blog, error) {
 	if err != nil {
 		return &tempBlog, err
 	}
+	// Code injection
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_head")
+	err = row.Scan(&tempBlog.CodeinjectionHead)
+	if err != nil {
+		return &tempBlog, err
+	}
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_foot")
+	err = row.Scan(&tempBlog.CodeinjectionFoot)
+	if err != nil {
+		return &tempBlog, err
+	}
 	return &tempBlog, err
 }
 
@@ -396,3 +766,229 @@ func makeNavigation(navigation []byte) ([]structure.Navigation, error) {
 	}
 	return navigationItems, nil
 }
+
+func RetrieveMedia(onlyUnused bool, limit int64, offset int64) ([]structure.Media, error) {
+	statement := stmtRetrieveMedia
+	if onlyUnused {
+		statement = stmtRetrieveUnusedMedia
+	}
+	rows, err := readDB.Query(statement, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractMedia(rows)
+}
+
+func extractMedia(rows *sql.Rows) ([]structure.Media, error) {
+	media := make([]structure.Media, 0)
+	for rows.Next() {
+		medium := structure.Media{}
+		err := rows.Scan(&medium.Id, &medium.Path, &medium.MimeType, &medium.Size, &medium.Width, &medium.Height, &medium.Alt, &medium.Caption, &medium.UploadedBy, &medium.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, medium)
+	}
+	return media, rows.Err()
+}
+
+func RetrieveMediaById(id int64) (*structure.Media, error) {
+	medium := structure.Media{}
+	row := readDB.QueryRow(stmtRetrieveMediaById, id)
+	err := row.Scan(&medium.Id, &medium.Path, &medium.MimeType, &medium.Size, &medium.Width, &medium.Height, &medium.Alt, &medium.Caption, &medium.UploadedBy, &medium.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &medium, nil
+}
+
+func RetrieveMediaByPath(path string) (*structure.Media, error) {
+	medium := structure.Media{}
+	row := readDB.QueryRow(stmtRetrieveMediaByPath, path)
+	err := row.Scan(&medium.Id, &medium.Path, &medium.MimeType, &medium.Size, &medium.Width, &medium.Height, &medium.Alt, &medium.Caption, &medium.UploadedBy, &medium.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &medium, nil
+}
+
+// Returns the ids of the posts that reference the media
+func RetrievePostIdsForMedia(media_id int64) ([]int64, error) {
+	rows, err := readDB.Query(stmtRetrievePostIdsByMediaId, media_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	postIds := make([]int64, 0)
+	for rows.Next() {
+		var postId int64
+		err := rows.Scan(&postId)
+		if err != nil {
+			return nil, err
+		}
+		postIds = append(postIds, postId)
+	}
+	return postIds, rows.Err()
+}
+
+// Returns the paths of all media (e.g. to find the files that haven't been imported yet)
+func RetrieveMediaPaths() (map[string]bool, error) {
+	rows, err := readDB.Query(stmtRetrieveMediaPaths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	paths := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		err := rows.Scan(&path)
+		if err != nil {
+			return nil, err
+		}
+		paths[path] = true
+	}
+	return paths, rows.Err()
+}
+
+// Retrieves the comments of a post with the given status (oldest first)
+func RetrieveCommentsForPost(post_id int64, status string) ([]structure.Comment, error) {
+	rows, err := readDB.Query(stmtRetrieveCommentsByPostIdAndStatus, post_id, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractComments(rows)
+}
+
+func RetrieveNumberOfCommentsForPost(post_id int64, status string) (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveCommentsCountByPostIdAndStatus, post_id, status)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Retrieves the comments of all posts (newest first). An empty status retrieves the comments with any status.
+func RetrieveComments(status string, limit int64, offset int64) ([]structure.Comment, error) {
+	var rows *sql.Rows
+	var err error
+	if status == "" {
+		rows, err = readDB.Query(stmtRetrieveComments, limit, offset)
+	} else {
+		rows, err = readDB.Query(stmtRetrieveCommentsByStatus, status, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractComments(rows)
+}
+
+// Retrieves the comments on the posts of an author (newest first). An empty status retrieves the comments with any status.
+func RetrieveCommentsForAuthor(author_id int64, status string, limit int64, offset int64) ([]structure.Comment, error) {
+	var rows *sql.Rows
+	var err error
+	if status == "" {
+		rows, err = readDB.Query(stmtRetrieveCommentsByAuthorId, author_id, limit, offset)
+	} else {
+		rows, err = readDB.Query(stmtRetrieveCommentsByAuthorIdAndStatus, author_id, status, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractComments(rows)
+}
+
+func extractComments(rows *sql.Rows) ([]structure.Comment, error) {
+	comments := make([]structure.Comment, 0)
+	for rows.Next() {
+		comment := structure.Comment{}
+		err := rows.Scan(&comment.Id, &comment.PostId, &comment.ParentId, &comment.AuthorName, &comment.AuthorEmail, &comment.AuthorUrl, &comment.Content, &comment.Status, &comment.Ip, &comment.UserAgent, &comment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func RetrieveCommentById(id int64) (*structure.Comment, error) {
+	comment := structure.Comment{}
+	row := readDB.QueryRow(stmtRetrieveCommentById, id)
+	err := row.Scan(&comment.Id, &comment.PostId, &comment.ParentId, &comment.AuthorName, &comment.AuthorEmail, &comment.AuthorUrl, &comment.Content, &comment.Status, &comment.Ip, &comment.UserAgent, &comment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Counts the comments that were written from an ip address since the given time (used to limit the rate of new comments)
+func RetrieveNumberOfCommentsByIp(ip []byte, since time.Time) (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveCommentsCountByIp, ip, since)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Retrieves all webhooks, or only the ones for an event if event isn't empty
+func RetrieveWebhooks(event string) ([]structure.Webhook, error) {
+	var rows *sql.Rows
+	var err error
+	if event == "" {
+		rows, err = readDB.Query(stmtRetrieveWebhooks)
+	} else {
+		rows, err = readDB.Query(stmtRetrieveWebhooksByEvent, event)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := make([]structure.Webhook, 0)
+	for rows.Next() {
+		webhook := structure.Webhook{}
+		err := rows.Scan(&webhook.Id, &webhook.Event, &webhook.TargetUrl, &webhook.Secret, &webhook.CreatedAt, &webhook.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func RetrieveWebhookById(id int64) (*structure.Webhook, error) {
+	webhook := structure.Webhook{}
+	row := readDB.QueryRow(stmtRetrieveWebhookById, id)
+	err := row.Scan(&webhook.Id, &webhook.Event, &webhook.TargetUrl, &webhook.Secret, &webhook.CreatedAt, &webhook.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Retrieves the delivery log of a webhook (newest first)
+func RetrieveWebhookDeliveries(webhook_id int64, limit int64, offset int64) ([]structure.WebhookDelivery, error) {
+	rows, err := readDB.Query(stmtRetrieveWebhookDeliveries, webhook_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := make([]structure.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := structure.WebhookDelivery{}
+		var deliveryError sql.NullString
+		err := rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Event, &delivery.TargetUrl, &delivery.Payload, &delivery.Attempt, &delivery.Status, &delivery.StatusCode, &deliveryError, &delivery.DurationMs, &delivery.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Error = deliveryError.String
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/kabukky/journey/structure"
+)
+
+// The search index is an SQLite FTS5 table (created by a migration). The rowid of each entry is the id of the indexed post.
+const stmtCheckFts5 = "CREATE VIRTUAL TABLE temp.fts5_check USING fts5(content)"
+const stmtInsertSearchIndex = "INSERT INTO posts_search (rowid, title, content, tags) VALUES (?, ?, ?, ?)"
+const stmtDeleteSearchIndexByPostId = "DELETE FROM posts_search WHERE rowid = ?"
+const stmtDeleteSearchIndex = "DELETE FROM posts_search"
+const stmtRetrieveSearchIndexCount = "SELECT count(*) FROM posts_search"
+const stmtRetrieveAllPostsCount = "SELECT count(*) FROM posts"
+const stmtRetrievePostsCountBySearch = "SELECT count(*) FROM posts_search, posts WHERE posts_search MATCH ? AND posts.id = posts_search.rowid AND posts.status = 'published'"
+const stmtRetrievePostsBySearch = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.codeinjection_head, posts.codeinjection_foot, posts.author_id, posts.published_at, posts.updated_at FROM posts_search, posts WHERE posts_search MATCH ? AND posts.id = posts_search.rowid AND posts.status = 'published' ORDER BY posts_search.rank LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApiBySearch = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.codeinjection_head, posts.codeinjection_foot, posts.author_id, posts.published_at, posts.updated_at FROM posts_search, posts WHERE posts_search MATCH ? AND posts.id = posts_search.rowid ORDER BY posts_search.rank LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApiBySearchAndUser = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.codeinjection_head, posts.codeinjection_foot, posts.author_id, posts.published_at, posts.updated_at FROM posts_search, posts WHERE posts_search MATCH ? AND posts.id = posts_search.rowid AND posts.author_id = ? ORDER BY posts_search.rank LIMIT ? OFFSET ?"
+
+var ErrSearchNotAvailable = errors.New("Search is not available. Search needs the sqlite3 database dialect and Journey needs to be built with the 'fts5' build tag.")
+
+// Set during initialization. False if the database isn't SQLite or the SQLite library doesn't support FTS5.
+var searchIsAvailable = false
+
+// Function to check if the search index has been created by its migration (the migration is skipped without FTS5).
+func checkSearchIndex() error {
+	searchIsAvailable = false
+	if !supportsFts5(readDB) {
+		log.Println("Warning: Search needs the sqlite3 database dialect and Journey needs to be built with the 'fts5' build tag, search is disabled.")
+		return nil
+	}
+	var count int64
+	err := readDB.QueryRow(stmtRetrieveSqliteTableCount, "posts_search").Scan(&count)
+	if err != nil {
+		return err
+	}
+	searchIsAvailable = count != 0
+	return nil
+}
+
+// Creates an FTS5 table inside of a transaction that is rolled back, so the table is never kept.
+func supportsFts5(db *DB) bool {
+	if currentDialect != DialectSqlite {
+		return false
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return false
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(stmtCheckFts5)
+	return err == nil
+}
+
+func SearchIsAvailable() bool {
+	return searchIsAvailable
+}
+
+// Function to add a post to the search index (or to replace its existing entry)
+func UpdateSearchIndex(post_id int64, title []byte, content []byte, tags []byte) error {
+	if !searchIsAvailable {
+		return nil
+	}
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSearchIndexByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertSearchIndex, post_id, title, content, tags)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteSearchIndexForPostId(post_id int64) error {
+	if !searchIsAvailable {
+		return nil
+	}
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSearchIndexByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteSearchIndex() error {
+	if !searchIsAvailable {
+		return nil
+	}
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSearchIndex)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func RetrieveNumberOfIndexedPosts() (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveSearchIndexCount)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Returns the number of all posts, including pages, drafts, and scheduled posts
+func RetrieveNumberOfAllPosts() (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveAllPostsCount)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func RetrieveNumberOfPostsBySearch(query string) (int64, error) {
+	if !searchIsAvailable {
+		return 0, ErrSearchNotAvailable
+	}
+	match := searchMatchExpression(query)
+	if match == "" {
+		return 0, nil
+	}
+	var count int64
+	row := readDB.QueryRow(stmtRetrievePostsCountBySearch, match)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Retrieves the published posts and pages that match the query (best match first)
+func RetrievePostsBySearch(query string, limit int64, offset int64) ([]structure.Post, error) {
+	if !searchIsAvailable {
+		return nil, ErrSearchNotAvailable
+	}
+	match := searchMatchExpression(query)
+	if match == "" {
+		return make([]structure.Post, 0), nil
+	}
+	rows, err := readDB.Query(stmtRetrievePostsBySearch, match, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// Retrieves all posts that match the query, regardless of their status
+func RetrievePostsForApiBySearch(query string, limit int64, offset int64) ([]structure.Post, error) {
+	if !searchIsAvailable {
+		return nil, ErrSearchNotAvailable
+	}
+	match := searchMatchExpression(query)
+	if match == "" {
+		return make([]structure.Post, 0), nil
+	}
+	rows, err := readDB.Query(stmtRetrievePostsForApiBySearch, match, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+func RetrievePostsForApiBySearchAndUser(query string, user_id int64, limit int64, offset int64) ([]structure.Post, error) {
+	if !searchIsAvailable {
+		return nil, ErrSearchNotAvailable
+	}
+	match := searchMatchExpression(query)
+	if match == "" {
+		return make([]structure.Post, 0), nil
+	}
+	rows, err := readDB.Query(stmtRetrievePostsForApiBySearchAndUser, match, user_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// Turns user input into an FTS5 match expression. Every word is quoted so that
+// characters like '-' or '*' aren't interpreted as FTS5 syntax. All words must match.
+func searchMatchExpression(query string) string {
+	words := strings.Fields(query)
+	for index, word := range words {
+		words[index] = "\"" + strings.Replace(word, "\"", "\"\"", -1) + "\""
+	}
+	return strings.Join(words, " ")
+}
@@ -4,20 +4,33 @@ import (
 	"time"
 )
 
-const stmtUpdatePost = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, updated_at = ?, updated_by = ? WHERE id = ?"
-const stmtUpdatePostPublished = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, updated_at = ?, updated_by = ?, published_at = ?, published_by = ? WHERE id = ?"
-const stmtUpdateSettings = "UPDATE settings SET value = ?, updated_at = ?, updated_by = ? WHERE key = ?"
+const stmtUpdatePost = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, codeinjection_head = ?, codeinjection_foot = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+const stmtUpdatePostPublished = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, codeinjection_head = ?, codeinjection_foot = ?, updated_at = ?, updated_by = ?, published_at = ?, published_by = ? WHERE id = ?"
+const stmtUpdateSettings = "UPDATE settings SET value = ?, updated_at = ?, updated_by = ? WHERE \"key\" = ?"
 const stmtUpdateUser = "UPDATE users SET name = ?, slug = ?, email = ?, image = ?, cover = ?, bio = ?, website = ?, location = ?, updated_at = ?, updated_by = ? WHERE id = ?"
 const stmtUpdateLastLogin = "UPDATE users SET last_login = ? WHERE id = ?"
 const stmtUpdateUserPassword = "UPDATE users SET password = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+const stmtUpdateUserStatus = "UPDATE users SET status = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+const stmtUpdateRoleUser = "UPDATE roles_users SET role_id = ? WHERE user_id = ?"
+const stmtUpdateScheduledPostPublished = "UPDATE posts SET status = 'published' WHERE id = ? AND status = 'scheduled'"
+const stmtUpdatePostsAuthor = "UPDATE posts SET author_id = ? WHERE author_id = ?"
+const stmtUpdateTwoFactorConfirmed = "UPDATE two_factor SET confirmed = ?, last_step = ? WHERE user_id = ?"
+const stmtUpdateTwoFactorLastStep = "UPDATE two_factor SET last_step = ? WHERE user_id = ? AND last_step < ?"
+const stmtUpdateSessionLastSeen = "UPDATE sessions SET last_seen_at = ? WHERE id = ?"
+const stmtUpdateApiTokenLastUsed = "UPDATE api_tokens SET last_used_at = ? WHERE id = ?"
+const stmtUpdateMediaDetails = "UPDATE media SET alt = ?, caption = ? WHERE id = ?"
+const stmtUpdateCommentStatus = "UPDATE comments SET status = ? WHERE id = ?"
+const stmtUpdateCommentParents = "UPDATE comments SET parent_id = ? WHERE parent_id = ?"
 
-func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, meta_description []byte, image []byte, updated_at time.Time, updated_by int64) error {
+func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, meta_description []byte, image []byte, codeinjection_head []byte, codeinjection_foot []byte, updated_at time.Time, updated_by int64, scheduled_at *time.Time) error {
 	currentPost, err := RetrievePostById(id)
 	if err != nil {
 		return err
 	}
 	status := "draft"
-	if published {
+	if scheduled_at != nil {
+		status = "scheduled"
+	} else if published {
 		status = "published"
 	}
 	writeDB, err := readDB.Begin()
@@ -25,11 +38,14 @@ func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byt
 		writeDB.Rollback()
 		return err
 	}
-	// If the updated post is published for the first time, add publication date and user
-	if published && !currentPost.IsPublished {
-		_, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, updated_at, updated_by, id)
+	if scheduled_at != nil {
+		// If the updated post is scheduled, set the future publication date
+		_, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, codeinjection_head, codeinjection_foot, updated_at, updated_by, *scheduled_at, updated_by, id)
+	} else if published && !currentPost.IsPublished {
+		// If the updated post is published for the first time, add publication date and user
+		_, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, codeinjection_head, codeinjection_foot, updated_at, updated_by, updated_at, updated_by, id)
 	} else {
-		_, err = writeDB.Exec(stmtUpdatePost, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, id)
+		_, err = writeDB.Exec(stmtUpdatePost, title, slug, markdown, html, featured, isPage, status, meta_description, image, codeinjection_head, codeinjection_foot, updated_at, updated_by, id)
 	}
 	if err != nil {
 		writeDB.Rollback()
@@ -38,7 +54,7 @@ func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byt
 	return writeDB.Commit()
 }
 
-func UpdateSettings(title []byte, description []byte, logo []byte, cover []byte, postsPerPage int64, activeTheme string, navigation []byte, updated_at time.Time, updated_by int64) error {
+func UpdateSettings(title []byte, description []byte, logo []byte, cover []byte, postsPerPage int64, activeTheme string, navigation []byte, codeinjection_head []byte, codeinjection_foot []byte, updated_at time.Time, updated_by int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
@@ -86,6 +102,17 @@ func UpdateSettings(title []byte, description []byte, logo []byte, cover []byte,
 		writeDB.Rollback()
 		return err
 	}
+	// Code injection
+	_, err = writeDB.Exec(stmtUpdateSettings, codeinjection_head, updated_at, updated_by, "codeinjection_head")
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateSettings, codeinjection_foot, updated_at, updated_by, "codeinjection_foot")
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
 	return writeDB.Commit()
 }
 
@@ -144,3 +171,171 @@ func UpdateUserPassword(id int64, password string, updated_at time.Time, updated
 	}
 	return writeDB.Commit()
 }
+
+func UpdateUserStatus(id int64, status string, updated_at time.Time, updated_by int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateUserStatus, status, updated_at, updated_by, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateRoleUser(role_id int, user_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateRoleUser, role_id, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdatePostsAuthor(old_author_id int64, new_author_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdatePostsAuthor, new_author_id, old_author_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateScheduledPostPublished(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateScheduledPostPublished, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateApiTokenLastUsed(id int64, last_used_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateApiTokenLastUsed, last_used_at, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateSessionLastSeen(id int64, last_seen_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateSessionLastSeen, last_seen_at, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateTwoFactorConfirmed(user_id int64, last_step int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateTwoFactorConfirmed, true, last_step, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Records the time step of an accepted code. Returns false if a code of the same or a later time step has been accepted before.
+func UpdateTwoFactorLastStep(user_id int64, last_step int64) (bool, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	result, err := writeDB.Exec(stmtUpdateTwoFactorLastStep, last_step, user_id, last_step)
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	return rows == 1, writeDB.Commit()
+}
+
+func UpdateMediaDetails(id int64, alt []byte, caption []byte) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateMediaDetails, alt, caption, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// Function to replace the media a post references. Paths that aren't in the media table are ignored.
+func UpdatePostMedia(post_id int64, paths []string) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostMediaByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	for _, path := range paths {
+		_, err = writeDB.Exec(stmtInsertPostMediaByPath, post_id, path)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+	}
+	return writeDB.Commit()
+}
+
+func UpdateCommentStatus(id int64, status string) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateCommentStatus, status, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
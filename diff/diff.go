@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"strings"
+)
+
+const (
+	Equal  = "="
+	Insert = "+"
+	Delete = "-"
+)
+
+// Line: a single line of a line based diff
+type Line struct {
+	Operation string // Equal, Insert, or Delete
+	Text      string
+}
+
+// Function to compute the line based diff between two texts (using the longest common subsequence of lines).
+func Lines(a []byte, b []byte) []Line {
+	linesA := splitLines(a)
+	linesB := splitLines(b)
+	output := make([]Line, 0, len(linesA)+len(linesB))
+	// Skip the common prefix and suffix to keep the lcs table small
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		output = append(output, Line{Operation: Equal, Text: linesA[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix && linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+	middleA := linesA[prefix : len(linesA)-suffix]
+	middleB := linesB[prefix : len(linesB)-suffix]
+	// lcs[i][j] holds the length of the longest common subsequence of middleA[i:] and middleB[j:]
+	lcs := make([][]int, len(middleA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(middleB)+1)
+	}
+	for i := len(middleA) - 1; i >= 0; i-- {
+		for j := len(middleB) - 1; j >= 0; j-- {
+			if middleA[i] == middleB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(middleA) && j < len(middleB) {
+		if middleA[i] == middleB[j] {
+			output = append(output, Line{Operation: Equal, Text: middleA[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			output = append(output, Line{Operation: Delete, Text: middleA[i]})
+			i++
+		} else {
+			output = append(output, Line{Operation: Insert, Text: middleB[j]})
+			j++
+		}
+	}
+	for ; i < len(middleA); i++ {
+		output = append(output, Line{Operation: Delete, Text: middleA[i]})
+	}
+	for ; j < len(middleB); j++ {
+		output = append(output, Line{Operation: Insert, Text: middleB[j]})
+	}
+	for index := len(linesA) - suffix; index < len(linesA); index++ {
+		output = append(output, Line{Operation: Equal, Text: linesA[index]})
+	}
+	return output
+}
+
+func splitLines(input []byte) []string {
+	if len(input) == 0 {
+		return []string{}
+	}
+	text := strings.Replace(string(input), "\r\n", "\n", -1)
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
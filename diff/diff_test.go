@@ -0,0 +1,51 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+var linesTests = []struct {
+	a   string
+	b   string
+	out []Line
+}{
+	{
+		a:   "",
+		b:   "",
+		out: []Line{},
+	},
+	{
+		a:   "one\ntwo\n",
+		b:   "one\ntwo\n",
+		out: []Line{{Equal, "one"}, {Equal, "two"}},
+	},
+	{
+		a:   "",
+		b:   "new",
+		out: []Line{{Insert, "new"}},
+	},
+	{
+		a:   "one\ntwo\nthree",
+		b:   "one\nthree",
+		out: []Line{{Equal, "one"}, {Delete, "two"}, {Equal, "three"}},
+	},
+	{
+		a:   "one\ntwo\nthree",
+		b:   "one\n2\nthree\nfour",
+		out: []Line{{Equal, "one"}, {Delete, "two"}, {Insert, "2"}, {Equal, "three"}, {Insert, "four"}},
+	},
+	{
+		a:   "a\r\nb\r\n",
+		b:   "a\nc\n",
+		out: []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "c"}},
+	},
+}
+
+func TestLines(t *testing.T) {
+	for _, test := range linesTests {
+		if actual := Lines([]byte(test.a), []byte(test.b)); !reflect.DeepEqual(actual, test.out) {
+			t.Errorf("Expected %v, received %v for '%s' -> '%s'", test.out, actual, test.a, test.b)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Name of the file in the export directory that lists all files of the last export
+const manifestFilename = ".journey-export"
+
+// Root-relative urls in attributes, e.g. href="/tag/news/" (but not protocol-relative ones like src="//cdn.example.com/...")
+var rootRelativeUrlPattern = regexp.MustCompile(`((?:href|src|action|poster|content)=["'])/([^/])`)
+var srcsetPattern = regexp.MustCompile(`(srcset=["'])([^"']*)`)
+
+// Site: a static export of the blog. Files are only rewritten if their content has changed since the last export,
+// and files of the last export that aren't part of this one anymore are removed by Finish.
+type Site struct {
+	Directory string
+	// Url the exported site will be served at (without a trailing slash), e.g. https://cdn.example.com/blog
+	BaseUrl string
+	// Urls of the blog that are replaced with BaseUrl
+	SourceUrls []string
+	// Number of files that have been written and that were unchanged
+	Written   int
+	Unchanged int
+	files     map[string]bool
+}
+
+func New(directory string, baseUrl string, sourceUrls ...string) (*Site, error) {
+	if directory == "" {
+		return nil, errors.New("No export directory given.")
+	}
+	err := os.MkdirAll(directory, 0776)
+	if err != nil {
+		return nil, err
+	}
+	site := &Site{Directory: directory, BaseUrl: strings.TrimSuffix(baseUrl, "/"), files: make(map[string]bool)}
+	for _, sourceUrl := range sourceUrls {
+		sourceUrl = strings.TrimSuffix(sourceUrl, "/")
+		if sourceUrl != "" && sourceUrl != site.BaseUrl {
+			site.SourceUrls = append(site.SourceUrls, sourceUrl)
+		}
+	}
+	// Replace longer urls first (so that a url that is a prefix of another one doesn't break it)
+	sort.Slice(site.SourceUrls, func(i, j int) bool { return len(site.SourceUrls[i]) > len(site.SourceUrls[j]) })
+	return site, nil
+}
+
+// Writes a rendered page. Urls ending in a slash (e.g. /tag/news/) are written to index.html in that directory.
+func (s *Site) WritePage(urlPath string, data []byte) error {
+	return s.writeFile(FilePath(urlPath), RewriteUrls(data, s.BaseUrl, s.SourceUrls))
+}
+
+// Copies all files in source to the directory at urlPath (e.g. /assets/). Files with the same size and modification time are skipped.
+func (s *Site) CopyDirectory(source string, urlPath string) error {
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(source, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(source, filePath)
+		if err != nil {
+			return err
+		}
+		return s.copyFile(path.Join(directoryPath(urlPath), filepath.ToSlash(relativePath)), info.Size(), info.ModTime(), func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		})
+	})
+}
+
+// Copies a file that isn't on the local disk (e.g. an image in an object storage) to urlPath. open is only called if
+// there is no file with the same size and modification time in the export yet.
+func (s *Site) CopyFile(urlPath string, size int64, modTime time.Time, open func() (io.ReadCloser, error)) error {
+	return s.copyFile(FilePath(urlPath), size, modTime, open)
+}
+
+// Removes the files of the last export that haven't been written or copied during this one and saves the list of exported files
+func (s *Site) Finish() error {
+	for _, name := range s.readManifest() {
+		if s.files[name] {
+			continue
+		}
+		err := os.Remove(filepath.Join(s.Directory, filepath.FromSlash(name)))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// Remove directories that are empty now (os.Remove fails for directories that still contain files)
+		for directory := path.Dir(name); directory != "."; directory = path.Dir(directory) {
+			if os.Remove(filepath.Join(s.Directory, filepath.FromSlash(directory))) != nil {
+				break
+			}
+		}
+	}
+	names := make([]string, 0, len(s.files))
+	for name, _ := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buffer bytes.Buffer
+	for _, name := range names {
+		buffer.WriteString(name)
+		buffer.WriteString("\n")
+	}
+	return ioutil.WriteFile(filepath.Join(s.Directory, manifestFilename), buffer.Bytes(), 0666)
+}
+
+func (s *Site) writeFile(name string, data []byte) error {
+	s.files[name] = true
+	filePath := filepath.Join(s.Directory, filepath.FromSlash(name))
+	existing, err := ioutil.ReadFile(filePath)
+	if err == nil && bytes.Equal(existing, data) {
+		s.Unchanged++
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0776)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filePath, data, 0666)
+	if err != nil {
+		return err
+	}
+	s.Written++
+	return nil
+}
+
+func (s *Site) copyFile(name string, size int64, modTime time.Time, open func() (io.ReadCloser, error)) error {
+	s.files[name] = true
+	filePath := filepath.Join(s.Directory, filepath.FromSlash(name))
+	existing, err := os.Stat(filePath)
+	if err == nil && existing.Size() == size && existing.ModTime().Equal(modTime) {
+		s.Unchanged++
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0776)
+	if err != nil {
+		return err
+	}
+	sourceFile, err := open()
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, sourceFile)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	s.Written++
+	// Keep the modification time so that the file is skipped during the next export if the source hasn't changed
+	return os.Chtimes(filePath, modTime, modTime)
+}
+
+func (s *Site) readManifest() []string {
+	file, err := os.Open(filepath.Join(s.Directory, manifestFilename))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	names := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := path.Clean(strings.TrimSpace(scanner.Text()))
+		// Never touch anything outside of the export directory
+		if name == "." || strings.HasPrefix(name, "../") || strings.HasPrefix(name, "/") || name == manifestFilename {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Returns the file a url is exported to, relative to the export directory (e.g. tag/news/index.html for /tag/news/)
+func FilePath(urlPath string) string {
+	name := path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		name = path.Join(name, "index.html")
+	}
+	return strings.TrimPrefix(name, "/")
+}
+
+// Returns the directory a url is exported to, relative to the export directory (e.g. assets for /assets/)
+func directoryPath(urlPath string) string {
+	return strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+}
+
+// Replaces the urls of the blog and root-relative urls (e.g. /assets/css/screen.css) with urls that start with baseUrl
+func RewriteUrls(data []byte, baseUrl string, sourceUrls []string) []byte {
+	for _, sourceUrl := range sourceUrls {
+		data = bytes.Replace(data, []byte(sourceUrl), []byte(baseUrl), -1)
+	}
+	if baseUrl == "" {
+		return data
+	}
+	data = rootRelativeUrlPattern.ReplaceAll(data, []byte("${1}"+escapeReplacement(baseUrl)+"/${2}"))
+	return srcsetPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		parts := srcsetPattern.FindSubmatch(match)
+		candidates := strings.Split(string(parts[2]), ",")
+		for index, candidate := range candidates {
+			trimmed := strings.TrimLeft(candidate, " \t\n")
+			if strings.HasPrefix(trimmed, "/") && !strings.HasPrefix(trimmed, "//") {
+				candidates[index] = candidate[:len(candidate)-len(trimmed)] + baseUrl + trimmed
+			}
+		}
+		return []byte(string(parts[1]) + strings.Join(candidates, ","))
+	})
+}
+
+// A literal $ in the base url would be taken for a submatch reference otherwise
+func escapeReplacement(value string) string {
+	return strings.Replace(value, "$", "$$", -1)
+}
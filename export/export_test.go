@@ -0,0 +1,160 @@
+package export
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var filePathTests = []struct {
+	in  string
+	out string
+}{
+	{in: "/", out: "index.html"},
+	{in: "/welcome/", out: "welcome/index.html"},
+	{in: "/tag/news/page/2/", out: "tag/news/page/2/index.html"},
+	{in: "/feed.json", out: "feed.json"},
+	{in: "/../../etc/passwd", out: "etc/passwd"},
+}
+
+func TestFilePath(t *testing.T) {
+	for _, test := range filePathTests {
+		if output := FilePath(test.in); output != test.out {
+			t.Errorf("FilePath(%q): expected %q, got %q", test.in, test.out, output)
+		}
+	}
+}
+
+var rewriteTests = []struct {
+	in  string
+	out string
+}{
+	{
+		in:  `<link rel="canonical" href="http://127.0.0.1:8084/welcome/">`,
+		out: `<link rel="canonical" href="https://cdn.example.com/blog/welcome/">`,
+	},
+	{
+		in:  `<a href="/">Home</a><a href='/tag/news/'>News</a>`,
+		out: `<a href="https://cdn.example.com/blog/">Home</a><a href='https://cdn.example.com/blog/tag/news/'>News</a>`,
+	},
+	{
+		in:  `<script src="//cdn.example.org/jquery.js"></script><img src="/images/a.jpg">`,
+		out: `<script src="//cdn.example.org/jquery.js"></script><img src="https://cdn.example.com/blog/images/a.jpg">`,
+	},
+	{
+		in:  `<img srcset="/images/a-s.jpg 300w, /images/a-m.jpg 600w, https://example.org/b.jpg 900w">`,
+		out: `<img srcset="https://cdn.example.com/blog/images/a-s.jpg 300w, https://cdn.example.com/blog/images/a-m.jpg 600w, https://example.org/b.jpg 900w">`,
+	},
+	{
+		in:  `<p>Paths like /tag/news/ in text stay as they are.</p>`,
+		out: `<p>Paths like /tag/news/ in text stay as they are.</p>`,
+	},
+}
+
+func TestRewriteUrls(t *testing.T) {
+	for _, test := range rewriteTests {
+		output := string(RewriteUrls([]byte(test.in), "https://cdn.example.com/blog", []string{"http://127.0.0.1:8084"}))
+		if output != test.out {
+			t.Errorf("RewriteUrls(%q):\nexpected %q\n     got %q", test.in, test.out, output)
+		}
+	}
+}
+
+func TestIncrementalExport(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journey-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	// First export
+	site, err := New(directory, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = site.WritePage("/", []byte("index")); err != nil {
+		t.Fatal(err)
+	}
+	if err = site.WritePage("/old-post/", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if err = site.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if site.Written != 2 || site.Unchanged != 0 {
+		t.Errorf("First export: expected 2 written and 0 unchanged files, got %d and %d", site.Written, site.Unchanged)
+	}
+
+	// Second export: the index is unchanged, the old post has been deleted, and there is a new post
+	site, err = New(directory, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = site.WritePage("/", []byte("index")); err != nil {
+		t.Fatal(err)
+	}
+	if err = site.WritePage("/new-post/", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err = site.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if site.Written != 1 || site.Unchanged != 1 {
+		t.Errorf("Second export: expected 1 written and 1 unchanged file, got %d and %d", site.Written, site.Unchanged)
+	}
+	if _, err := os.Stat(filepath.Join(directory, "old-post")); !os.IsNotExist(err) {
+		t.Errorf("Expected the directory of the deleted post to be removed")
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(directory, "new-post", "index.html")); err != nil || string(data) != "new" {
+		t.Errorf("Expected new-post/index.html to contain %q, got %q (%v)", "new", data, err)
+	}
+}
+
+func TestCopyDirectory(t *testing.T) {
+	source, err := ioutil.TempDir("", "journey-export-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(source)
+	directory, err := ioutil.TempDir("", "journey-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	if err = os.MkdirAll(filepath.Join(source, "css"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(source, "css", "screen.css"), []byte("body {}"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for run := 1; run <= 2; run++ {
+		site, err := New(directory, "https://example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = site.CopyDirectory(source, "/assets/"); err != nil {
+			t.Fatal(err)
+		}
+		opened := false
+		err = site.CopyFile("/images/2016/01/image.jpg", 5, time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), func() (io.ReadCloser, error) {
+			opened = true
+			return ioutil.NopCloser(strings.NewReader("image")), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The second export skips both files (same size and modification time)
+		if run == 2 && (opened || site.Unchanged != 2) {
+			t.Errorf("Second export: expected 2 unchanged files, got %d", site.Unchanged)
+		}
+	}
+	for _, name := range []string{filepath.Join("assets", "css", "screen.css"), filepath.Join("images", "2016", "01", "image.jpg")} {
+		if _, err := os.Stat(filepath.Join(directory, name)); err != nil {
+			t.Errorf("Expected %s to be copied: %v", name, err)
+		}
+	}
+}
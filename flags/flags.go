@@ -3,6 +3,8 @@ package flags
 import (
 	"flag"
 	"log"
+	"os"
+	"strings"
 )
 
 var (
@@ -11,6 +13,15 @@ var (
 	IsInDevMode = false
 	HttpPort    = ""
 	HttpsPort   = ""
+	// Path of an existing journey.db whose content should be copied into the configured database backend
+	CopyFromSqlite = ""
+	MigrateStatus  = false
+	MigrateDown    = false
+	// Directory the blog should be exported to as a static site and the url the export will be served at
+	Export    = ""
+	ExportUrl = ""
+	// Add existing files in content/images to the media library
+	ImportMedia = false
 )
 
 func init() {
@@ -32,5 +43,29 @@ func parseFlags() {
 	flag.StringVar(&HttpPort, "http-port", "", "Use this option to override the HTTP port that was set in the config.json. Example: -http-port=8080")
 	// Check if the http port that was set in the config was overridden by the user
 	flag.StringVar(&HttpsPort, "https-port", "", "Use this option to override the HTTPS port that was set in the config.json. Example: -https-port=8081")
-	flag.Parse()
+	// Check if an existing SQLite database should be copied into the configured database backend
+	flag.StringVar(&CopyFromSqlite, "copy-from-sqlite", "", "Use this option to copy the content of an existing SQLite database into the database backend that is set in the config.json (DatabaseDialect and DatabaseConnection). Journey exits afterwards. Example: -copy-from-sqlite=content/data/journey.db")
+	// Check if the status of the database schema migrations should be shown
+	flag.BoolVar(&MigrateStatus, "migrate-status", false, "Use this flag to show which database schema migrations have been applied. Journey exits afterwards. Example: -migrate-status")
+	// Check if the most recent database schema migration should be reverted
+	flag.BoolVar(&MigrateDown, "migrate-down", false, "Use this flag to revert the most recently applied database schema migration. Journey exits afterwards. Example: -migrate-down")
+	// Check if the blog should be exported as a static site
+	flag.StringVar(&Export, "export", "", "Use this option to export the blog as static files (e.g. to host it on a CDN). Only files that have changed since the last export are rewritten. Journey exits afterwards. Example: -export=path/to/directory")
+	// Check if the url the export will be served at was provided by the user
+	flag.StringVar(&ExportUrl, "export-url", "", "Use this option together with -export to set the url the exported blog will be served at. Defaults to the url in the config.json. Example: -export-url=https://cdn.example.com/blog")
+	// Check if the existing images should be added to the media library
+	flag.BoolVar(&ImportMedia, "import-media", false, "Use this flag to add the images in content/images that were uploaded with an older version of Journey to the media library. Journey exits afterwards. Example: -import-media")
+	// go test passes its own flags (e.g. -test.v). The testing package parses them together with the ones above.
+	if !isTest(os.Args[1:]) {
+		flag.Parse()
+	}
+}
+
+func isTest(arguments []string) bool {
+	for _, argument := range arguments {
+		if strings.HasPrefix(argument, "-test.") {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,221 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedType = errors.New("Unsupported file type. Only JPEG, PNG, GIF, and WebP images can be uploaded.")
+	ErrTooLarge        = errors.New("The image is too large.")
+	ErrInvalidImage    = errors.New("The image is damaged.")
+)
+
+// Default settings
+const (
+	DefaultMaxUploadMegabytes = 10
+	DefaultMaxMegapixels      = 50
+	DefaultJpegQuality        = 85
+	DefaultCwebpPath          = "cwebp"
+)
+
+var DefaultSizes = []Size{Size{Name: "s", Width: 300}, Size{Name: "m", Width: 600}, Size{Name: "l", Width: 1200}}
+
+// Settings: how uploaded images are checked and processed
+type Settings struct {
+	// Uploads that are larger than this are rejected
+	MaxUploadMegabytes int64
+	// Images with more pixels than this are rejected (decoding them would take too much memory)
+	MaxMegapixels int64
+	// Widths of the resized variants. The names are used by the img_url theme helper, e.g. {{img_url image size="m"}}.
+	Sizes []Size
+	// Quality (1-100) of resized JPEG and WebP variants
+	JpegQuality int
+	// Path of the cwebp tool (https://developers.google.com/speed/webp/docs/cwebp) that creates the WebP variants.
+	// WebP variants are skipped if it isn't installed. Set to an empty string to disable WebP variants.
+	CwebpPath string
+}
+
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Image: an uploaded image without its metadata and its resized variants
+type Image struct {
+	// File extension of the format of the image (.jpg, .png, .gif, or .webp)
+	Extension string
+	Width     int
+	Height    int
+	Data      []byte
+	Variants  []Variant
+}
+
+type Variant struct {
+	Width     int
+	Extension string
+	Data      []byte
+}
+
+// Function to check and process an uploaded image: the type is detected by the content (not the file name), EXIF, GPS, and other
+// metadata is removed, and resized variants are created for all sizes that are smaller than the image (JPEG and PNG images only).
+func Process(data []byte, settings Settings) (*Image, error) {
+	if settings.MaxUploadMegabytes > 0 && int64(len(data)) > settings.MaxUploadMegabytes<<20 {
+		return nil, ErrTooLarge
+	}
+	extension, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	if extension == ".gif" || extension == ".webp" {
+		// Animated images would lose their animation and there is no WebP decoder, so these are only stripped
+		if extension == ".webp" {
+			data, err = stripWebp(data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		width, height, err := Dimensions(data)
+		if err != nil {
+			return nil, err
+		}
+		if settings.MaxMegapixels > 0 && int64(width)*int64(height) > settings.MaxMegapixels*1000000 {
+			return nil, ErrTooLarge
+		}
+		return &Image{Extension: extension, Width: width, Height: height, Data: data}, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if settings.MaxMegapixels > 0 && int64(config.Width)*int64(config.Height) > settings.MaxMegapixels*1000000 {
+		return nil, ErrTooLarge
+	}
+	orientation := 1
+	if extension == ".jpg" {
+		data, orientation, err = stripJpeg(data)
+	} else {
+		data, err = stripPng(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	quality := settings.JpegQuality
+	if quality < 1 || quality > 100 {
+		quality = DefaultJpegQuality
+	}
+	// The orientation is part of the removed metadata, so the pixels have to be rotated instead
+	if orientation != 1 {
+		decoded = applyOrientation(decoded, orientation)
+		var buffer bytes.Buffer
+		err = jpeg.Encode(&buffer, decoded, &jpeg.Options{Quality: quality})
+		if err != nil {
+			return nil, err
+		}
+		data = buffer.Bytes()
+	}
+	result := &Image{Extension: extension, Width: decoded.Bounds().Dx(), Height: decoded.Bounds().Dy(), Data: data}
+	cwebp := webpEncoder(settings.CwebpPath)
+	for _, width := range widths(settings.Sizes) {
+		if width >= decoded.Bounds().Dx() {
+			continue
+		}
+		resized := Resize(decoded, width)
+		var pngBuffer bytes.Buffer
+		if extension == ".png" || cwebp != "" {
+			err = png.Encode(&pngBuffer, resized)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if extension == ".png" {
+			result.Variants = append(result.Variants, Variant{Width: width, Extension: extension, Data: pngBuffer.Bytes()})
+		} else {
+			var buffer bytes.Buffer
+			err = jpeg.Encode(&buffer, resized, &jpeg.Options{Quality: quality})
+			if err != nil {
+				return nil, err
+			}
+			result.Variants = append(result.Variants, Variant{Width: width, Extension: extension, Data: buffer.Bytes()})
+		}
+		if cwebp != "" {
+			webp, err := encodeWebp(cwebp, pngBuffer.Bytes(), quality)
+			if err != nil {
+				return nil, err
+			}
+			result.Variants = append(result.Variants, Variant{Width: width, Extension: ".webp", Data: webp})
+		}
+	}
+	return result, nil
+}
+
+// Returns the file extension for the type of an image, determined by its content
+func Sniff(data []byte) (string, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg", nil
+	case "image/png":
+		return ".png", nil
+	case "image/gif":
+		return ".gif", nil
+	case "image/webp":
+		return ".webp", nil
+	}
+	return "", ErrUnsupportedType
+}
+
+// Returns the width of the size with the given name
+func SizeWidth(sizes []Size, name string) (int, bool) {
+	for _, size := range sizes {
+		if size.Name == name {
+			return size.Width, true
+		}
+	}
+	return 0, false
+}
+
+// Returns the url of a variant of an image, e.g. /images/size/w600/2016/01/image.jpg for /images/2016/01/image.jpg.
+// The file extension is replaced if extension isn't empty. Returns an empty string for images that aren't uploaded ones.
+func VariantPath(imagePath string, width int, extension string) string {
+	prefix := ""
+	for _, candidate := range []string{"/images/", "/content/images/"} {
+		if strings.HasPrefix(imagePath, candidate) {
+			prefix = candidate
+			break
+		}
+	}
+	if prefix == "" || strings.HasPrefix(imagePath, prefix+"size/") {
+		return ""
+	}
+	relativePath := strings.TrimPrefix(imagePath, prefix)
+	if extension != "" {
+		relativePath = strings.TrimSuffix(relativePath, path.Ext(relativePath)) + extension
+	}
+	return prefix + "size/w" + strconv.Itoa(width) + "/" + relativePath
+}
+
+// Sorted and without duplicates
+func widths(sizes []Size) []int {
+	result := make([]int, 0, len(sizes))
+	seen := make(map[int]bool)
+	for _, size := range sizes {
+		if size.Width > 0 && !seen[size.Width] {
+			seen[size.Width] = true
+			result = append(result, size.Width)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
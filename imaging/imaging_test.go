@@ -0,0 +1,239 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+// Returns an APP1 segment with EXIF data that only contains the orientation tag
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	ifd := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(ifd[0:], 1)      // Number of entries
+	binary.BigEndian.PutUint16(ifd[2:], 0x0112) // Orientation
+	binary.BigEndian.PutUint16(ifd[4:], 3)      // Type: short
+	binary.BigEndian.PutUint32(ifd[6:], 1)      // Count
+	binary.BigEndian.PutUint16(ifd[10:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, ifd...)...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func testJpeg(t *testing.T, width int, height int, orientation uint16) []byte {
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, testImage(width, height), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	// Insert the EXIF segment after the start of image marker
+	return append(append(append([]byte{}, data[:2]...), exifSegment(orientation)...), data[2:]...)
+}
+
+func TestSniff(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, testImage(2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if extension, err := Sniff(buffer.Bytes()); err != nil || extension != ".png" {
+		t.Errorf("Expected .png, got %q (%v)", extension, err)
+	}
+	if extension, err := Sniff(testJpeg(t, 2, 2, 1)); err != nil || extension != ".jpg" {
+		t.Errorf("Expected .jpg, got %q (%v)", extension, err)
+	}
+	for _, data := range []string{"<html><script>alert(1)</script></html>", "<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>", "GIF8"} {
+		if _, err := Sniff([]byte(data)); err != ErrUnsupportedType {
+			t.Errorf("Expected %q to be rejected, got %v", data, err)
+		}
+	}
+}
+
+func TestStripJpeg(t *testing.T) {
+	data := testJpeg(t, 4, 4, 6)
+	stripped, orientation, err := stripJpeg(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orientation != 6 {
+		t.Errorf("Expected orientation 6, got %d", orientation)
+	}
+	if bytes.Contains(stripped, []byte("Exif")) {
+		t.Errorf("Expected the EXIF segment to be removed")
+	}
+	if len(stripped) != len(data)-len(exifSegment(6)) {
+		t.Errorf("Expected only the EXIF segment to be removed (%d bytes), got %d bytes less", len(exifSegment(6)), len(data)-len(stripped))
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("Stripped image can't be decoded: %v", err)
+	}
+}
+
+func TestStripPng(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, testImage(2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	// Insert a tEXt chunk after the IHDR chunk (8 bytes signature + 25 bytes IHDR)
+	text := []byte("\x00\x00\x00\x0btEXtGPS\x0052.5200crc!")
+	withText := append(append(append([]byte{}, data[:33]...), text...), data[33:]...)
+	stripped, err := stripPng(withText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, data) {
+		t.Errorf("Expected the tEXt chunk to be removed")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	settings := Settings{MaxUploadMegabytes: 1, MaxMegapixels: 1, Sizes: []Size{{"s", 30}, {"m", 60}, {"l", 1200}}}
+	// 80x40 pixels, rotated by 90° clockwise
+	result, err := Process(testJpeg(t, 80, 40, 6), settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Extension != ".jpg" || bytes.Contains(result.Data, []byte("Exif")) {
+		t.Errorf("Expected a JPEG image without EXIF data")
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(result.Data))
+	if err != nil || config.Width != 40 || config.Height != 80 {
+		t.Errorf("Expected the image to be rotated to 40x80 pixels, got %dx%d (%v)", config.Width, config.Height, err)
+	}
+	// Only the 30 pixels variant is smaller than the (rotated) image
+	widths := make([]int, 0)
+	for _, variant := range result.Variants {
+		if variant.Extension == ".jpg" {
+			widths = append(widths, variant.Width)
+		}
+	}
+	if len(widths) != 1 || widths[0] != 30 {
+		t.Errorf("Expected one variant with a width of 30 pixels, got %v", widths)
+	}
+	if _, err := Process(testJpeg(t, 1001, 1000, 1), settings); err != ErrTooLarge {
+		t.Errorf("Expected an image with more than one megapixel to be rejected, got %v", err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	// A black and white checkerboard becomes gray
+	source := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			if (x+y)%2 == 0 {
+				source.Set(x, y, color.RGBA{255, 255, 255, 255})
+			} else {
+				source.Set(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	resized := Resize(source, 10)
+	if resized.Bounds().Dx() != 10 || resized.Bounds().Dy() != 5 {
+		t.Fatalf("Expected 10x5 pixels, got %v", resized.Bounds())
+	}
+	r, _, _, a := resized.At(3, 3).RGBA()
+	if r>>8 < 120 || r>>8 > 135 || a>>8 != 255 {
+		t.Errorf("Expected gray, got %d (alpha %d)", r>>8, a>>8)
+	}
+}
+
+var variantPathTests = []struct {
+	in        string
+	extension string
+	out       string
+}{
+	{"/images/2016/01/image.jpg", "", "/images/size/w600/2016/01/image.jpg"},
+	{"/images/2016/01/image.jpg", ".webp", "/images/size/w600/2016/01/image.webp"},
+	{"/content/images/2016/01/image.png", "", "/content/images/size/w600/2016/01/image.png"},
+	{"/images/size/w300/2016/01/image.jpg", "", ""},
+	{"https://example.com/image.jpg", "", ""},
+}
+
+func TestVariantPath(t *testing.T) {
+	for _, test := range variantPathTests {
+		if output := VariantPath(test.in, 600, test.extension); output != test.out {
+			t.Errorf("VariantPath(%q, 600, %q): expected %q, got %q", test.in, test.extension, test.out, output)
+		}
+	}
+}
+
+func TestAddSrcset(t *testing.T) {
+	sizes := []Size{{"s", 300}, {"m", 600}}
+	existing := map[string]bool{
+		"/images/a/x.jpg":             true,
+		"/images/size/w300/a/x.jpg":   true,
+		"/images/size/w600/a/x.jpg":   true,
+		"/images/size/w300/a/y.jpg":   true,
+		"/images/size/w300/a/y.webp":  true,
+		"/images/size/w300/a/z.jpg":   true,
+		"/images/size/w600/a/z.jpg":   true,
+		"/images/size/w600/a/z.webp":  true,
+		"/images/size/w300/a/no.jpeg": false,
+	}
+	exists := func(path string) bool { return existing[path] }
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{
+			`<p><img src="/images/a/x.jpg" alt="X"></p>`,
+			`<p><img src="/images/a/x.jpg" alt="X" srcset="/images/size/w300/a/x.jpg 300w, /images/size/w600/a/x.jpg 600w" sizes="(max-width: 600px) 100vw, 600px"></p>`,
+		},
+		{
+			`<img src="/images/a/y.jpg" />`,
+			`<picture><source type="image/webp" srcset="/images/size/w300/a/y.webp 300w" sizes="(max-width: 300px) 100vw, 300px"><img src="/images/a/y.jpg" srcset="/images/size/w300/a/y.jpg 300w" sizes="(max-width: 300px) 100vw, 300px"/></picture>`,
+		},
+		{
+			`<img src="/images/a/no.jpeg"><img src="https://example.com/a.jpg"><img src="/images/a/z.jpg" srcset="/z.jpg 2x">`,
+			`<img src="/images/a/no.jpeg"><img src="https://example.com/a.jpg"><img src="/images/a/z.jpg" srcset="/z.jpg 2x">`,
+		},
+	}
+	for _, test := range tests {
+		if output := string(AddSrcset([]byte(test.in), sizes, exists)); output != test.out {
+			t.Errorf("AddSrcset(%q):\nexpected %q\n     got %q", test.in, test.out, output)
+		}
+	}
+}
+
+func TestReferencedImages(t *testing.T) {
+	markdown := []byte("![A](/images/2016/01/a.jpg) and ![B](/content/images/2016/01/b.png \"B\").\nSee /images/2016/01/a.jpg.")
+	html := []byte(`<img src="/images/size/w600/2016/01/c.jpg" srcset="/images/size/w300/2016/01/c.jpg 300w"><a href="https://example.com/page">x</a>`)
+	image := []byte("/images/2016/02/cover.jpg?v=2")
+	expected := []string{"/images/2016/01/a.jpg", "/images/2016/01/b.png", "/images/2016/01/c.jpg", "/images/2016/02/cover.jpg"}
+	output := ReferencedImages(markdown, html, image)
+	if len(output) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, output)
+	}
+	for index, _ := range expected {
+		if output[index] != expected[index] {
+			t.Errorf("Expected %v, got %v", expected, output)
+			break
+		}
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	if width, height, err := Dimensions(testJpeg(t, 7, 3, 1)); err != nil || width != 7 || height != 3 {
+		t.Errorf("Expected 7x3 pixels, got %dx%d (%v)", width, height, err)
+	}
+	// Lossless WebP header of a 5x4 pixels image
+	webp := []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x04\xc0\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	if width, height, err := Dimensions(webp); err != nil || width != 5 || height != 4 {
+		t.Errorf("Expected 5x4 pixels, got %dx%d (%v)", width, height, err)
+	}
+}
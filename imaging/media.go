@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	"regexp"
+	"strings"
+)
+
+var imagePathPattern = regexp.MustCompile(`/(?:content/)?images/[^\s"'()<>\[\]\\]+`)
+var variantPathPattern = regexp.MustCompile(`^/images/size/w[0-9]+/`)
+
+// Returns the uploaded images that are referenced in texts (e.g. the markdown, html, and cover image of a post) as
+// /images/... paths. References to /content/images/ and to resized variants count as references to the original image.
+func ReferencedImages(texts ...[]byte) []string {
+	paths := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, match := range imagePathPattern.FindAll(text, -1) {
+			path := strings.TrimRight(string(match), ".,;:!?")
+			path = strings.TrimPrefix(path, "/content")
+			path = variantPathPattern.ReplaceAllString(path, "/images/")
+			// Cut off queries and fragments
+			if index := strings.IndexAny(path, "?#"); index != -1 {
+				path = path[:index]
+			}
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// Returns the dimensions of an image. Only the header is read.
+func Dimensions(data []byte) (int, int, error) {
+	if bytes.HasPrefix(data, []byte("RIFF")) && len(data) >= 30 && string(data[8:12]) == "WEBP" {
+		return webpDimensions(data)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, ErrInvalidImage
+	}
+	return config.Width, config.Height, nil
+}
+
+// The standard library has no WebP decoder, so the size is read from the first chunk (VP8X, VP8, or VP8L)
+func webpDimensions(data []byte) (int, int, error) {
+	switch string(data[12:16]) {
+	case "VP8X":
+		width := int(data[24]) | int(data[25])<<8 | int(data[26])<<16
+		height := int(data[27]) | int(data[28])<<8 | int(data[29])<<16
+		return width + 1, height + 1, nil
+	case "VP8 ":
+		if data[23] != 0x9D || data[24] != 0x01 || data[25] != 0x2A {
+			return 0, 0, ErrInvalidImage
+		}
+		return int(binary.LittleEndian.Uint16(data[26:]) & 0x3FFF), int(binary.LittleEndian.Uint16(data[28:]) & 0x3FFF), nil
+	case "VP8L":
+		if data[20] != 0x2F {
+			return 0, 0, ErrInvalidImage
+		}
+		bits := binary.LittleEndian.Uint32(data[21:])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, nil
+	}
+	return 0, 0, ErrInvalidImage
+}
+
+// Returns the mime type for the file extension of an image
+func MimeType(extension string) string {
+	switch strings.ToLower(extension) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".svg":
+		return "image/svg+xml"
+	}
+	return "application/octet-stream"
+}
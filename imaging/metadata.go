@@ -0,0 +1,203 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// Removes the EXIF, XMP, IPTC, and comment segments from a JPEG image. ICC profiles (APP2) and the JFIF (APP0) and
+// Adobe (APP14) headers are kept since they affect how the image is displayed. Also returns the EXIF orientation (1 if there is none).
+func stripJpeg(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, ErrInvalidImage
+	}
+	output := make([]byte, 0, len(data))
+	output = append(output, 0xFF, 0xD8)
+	orientation := 1
+	position := 2
+	for position+1 < len(data) {
+		if data[position] != 0xFF {
+			return nil, 0, ErrInvalidImage
+		}
+		marker := data[position+1]
+		switch {
+		case marker == 0xFF: // Fill byte
+			position++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8): // Markers without a length
+			output = append(output, data[position:position+2]...)
+			position += 2
+			continue
+		case marker == 0xD9 || marker == 0xDA: // End of image or start of scan: the rest is image data
+			return append(output, data[position:]...), orientation, nil
+		}
+		if position+4 > len(data) {
+			return nil, 0, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint16(data[position+2:]))
+		end := position + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, 0, ErrInvalidImage
+		}
+		segment := data[position:end]
+		if marker == 0xE1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			orientation = exifOrientation(segment[10:])
+		}
+		if !isJpegMetadata(marker) {
+			output = append(output, segment...)
+		}
+		position = end
+	}
+	return nil, 0, ErrInvalidImage
+}
+
+// COM and all APPn segments except APP0 (JFIF), APP2 (ICC profile), and APP14 (Adobe color transform)
+func isJpegMetadata(marker byte) bool {
+	return marker == 0xFE || (marker >= 0xE1 && marker <= 0xEF && marker != 0xE2 && marker != 0xEE)
+}
+
+// Reads the orientation tag (0x0112) from the first IFD of EXIF data
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// Removes the text, time, and EXIF chunks from a PNG image
+func stripPng(data []byte) ([]byte, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], []byte("\x89PNG\r\n\x1a\n")) {
+		return nil, ErrInvalidImage
+	}
+	output := make([]byte, 0, len(data))
+	output = append(output, data[:8]...)
+	position := 8
+	for position+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[position:]))
+		end := position + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+		chunkType := string(data[position+4 : position+8])
+		switch chunkType {
+		case "tEXt", "zTXt", "iTXt", "eXIf", "tIME":
+		default:
+			output = append(output, data[position:end]...)
+		}
+		position = end
+		if chunkType == "IEND" {
+			return output, nil
+		}
+	}
+	return nil, ErrInvalidImage
+}
+
+// Removes the EXIF and XMP chunks from a WebP image
+func stripWebp(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+	output := make([]byte, 0, len(data))
+	output = append(output, data[:12]...)
+	position := 12
+	for position+8 <= len(data) {
+		length := int(binary.LittleEndian.Uint32(data[position+4:]))
+		end := position + 8 + length + length%2 // Chunks are padded to an even size
+		if length < 0 || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+		switch string(data[position : position+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[position:end]...)
+			if len(chunk) > 8 {
+				// Clear the EXIF and XMP flags
+				chunk[8] &^= 0x08 | 0x04
+			}
+			output = append(output, chunk...)
+		default:
+			output = append(output, data[position:end]...)
+		}
+		position = end
+	}
+	binary.LittleEndian.PutUint32(output[4:], uint32(len(output)-8))
+	return output, nil
+}
+
+// Rotates and flips an image as described by an EXIF orientation (2-8)
+func applyOrientation(source image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return source
+	}
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	destinationWidth, destinationHeight := width, height
+	if orientation >= 5 {
+		destinationWidth, destinationHeight = height, width
+	}
+	rgba := toRGBA(source)
+	destination := image.NewRGBA(image.Rect(0, 0, destinationWidth, destinationHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // Rotated by 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated by 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // Transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // Rotated by 90° counterclockwise
+				dx, dy = y, width-1-x
+			}
+			copy(destination.Pix[destination.PixOffset(dx, dy):destination.PixOffset(dx, dy)+4], rgba.Pix[rgba.PixOffset(x, y):rgba.PixOffset(x, y)+4])
+		}
+	}
+	return destination
+}
+
+// Returns the image as RGBA with its origin at 0, 0
+func toRGBA(source image.Image) *image.RGBA {
+	bounds := source.Bounds()
+	if rgba, ok := source.(*image.RGBA); ok && bounds.Min == (image.Point{}) {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), source, bounds.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+type contribution struct {
+	index  int
+	weight float64
+}
+
+// Scales an image down to the given width (keeping the aspect ratio). Every pixel of the result is the
+// area-weighted average of the pixels it covers, which avoids the aliasing of nearest neighbor scaling.
+func Resize(source image.Image, width int) image.Image {
+	bounds := source.Bounds()
+	if width <= 0 || width >= bounds.Dx() {
+		return source
+	}
+	height := int(math.Floor(float64(bounds.Dy())*float64(width)/float64(bounds.Dx()) + 0.5))
+	if height < 1 {
+		height = 1
+	}
+	rgba := toRGBA(source)
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	// Horizontal pass
+	columns := contributions(sourceWidth, width)
+	intermediate := make([]float64, width*sourceHeight*4)
+	for y := 0; y < sourceHeight; y++ {
+		for x, contributors := range columns {
+			var pixel [4]float64
+			for _, c := range contributors {
+				offset := rgba.PixOffset(c.index, y)
+				for channel := 0; channel < 4; channel++ {
+					pixel[channel] += float64(rgba.Pix[offset+channel]) * c.weight
+				}
+			}
+			copy(intermediate[(y*width+x)*4:], pixel[:])
+		}
+	}
+	// Vertical pass
+	rows := contributions(sourceHeight, height)
+	destination := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, contributors := range rows {
+		for x := 0; x < width; x++ {
+			var pixel [4]float64
+			for _, c := range contributors {
+				offset := (c.index*width + x) * 4
+				for channel := 0; channel < 4; channel++ {
+					pixel[channel] += intermediate[offset+channel] * c.weight
+				}
+			}
+			offset := destination.PixOffset(x, y)
+			for channel := 0; channel < 4; channel++ {
+				destination.Pix[offset+channel] = clampUint8(pixel[channel])
+			}
+		}
+	}
+	return destination
+}
+
+// Returns the source pixels (and how much of them) each destination pixel covers
+func contributions(sourceSize int, destinationSize int) [][]contribution {
+	scale := float64(sourceSize) / float64(destinationSize)
+	result := make([][]contribution, destinationSize)
+	for i := 0; i < destinationSize; i++ {
+		start := float64(i) * scale
+		end := start + scale
+		for j := int(start); j < sourceSize && float64(j) < end; j++ {
+			overlap := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if overlap > 0 {
+				result[i] = append(result[i], contribution{index: j, weight: overlap / scale})
+			}
+		}
+	}
+	return result
+}
+
+func clampUint8(value float64) uint8 {
+	value = math.Floor(value + 0.5)
+	if value < 0 {
+		return 0
+	} else if value > 255 {
+		return 255
+	}
+	return uint8(value)
+}
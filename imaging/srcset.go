@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+var imgTagPattern = regexp.MustCompile(`(?i)<img\s[^>]*>`)
+var imgSrcPattern = regexp.MustCompile(`(?i)\ssrc=["']([^"']+)["']`)
+var imgSrcsetPattern = regexp.MustCompile(`(?i)\ssrcset=`)
+
+// Adds a srcset with the resized variants to every uploaded image in html. If there are WebP variants, the
+// image is wrapped in a picture element with a WebP source. exists reports if the variant with the given url has been created.
+func AddSrcset(html []byte, sizes []Size, exists func(string) bool) []byte {
+	if len(sizes) == 0 {
+		return html
+	}
+	return imgTagPattern.ReplaceAllFunc(html, func(tag []byte) []byte {
+		if imgSrcsetPattern.Match(tag) {
+			return tag
+		}
+		match := imgSrcPattern.FindSubmatch(tag)
+		if match == nil {
+			return tag
+		}
+		srcset, largest := srcsetFor(string(match[1]), sizes, "", exists)
+		if srcset == "" {
+			return tag
+		}
+		attributes := " srcset=\"" + srcset + "\" sizes=\"(max-width: " + strconv.Itoa(largest) + "px) 100vw, " + strconv.Itoa(largest) + "px\""
+		var buffer bytes.Buffer
+		webpSrcset, webpLargest := srcsetFor(string(match[1]), sizes, ".webp", exists)
+		if webpSrcset != "" {
+			buffer.WriteString("<picture><source type=\"image/webp\" srcset=\"" + webpSrcset + "\" sizes=\"(max-width: " + strconv.Itoa(webpLargest) + "px) 100vw, " + strconv.Itoa(webpLargest) + "px\">")
+		}
+		// Insert the attributes before the end of the tag (> or />)
+		end := len(tag) - 1
+		if end > 0 && tag[end-1] == '/' {
+			end--
+		}
+		buffer.Write(bytes.TrimRight(tag[:end], " "))
+		buffer.WriteString(attributes)
+		buffer.Write(tag[end:])
+		if webpSrcset != "" {
+			buffer.WriteString("</picture>")
+		}
+		return buffer.Bytes()
+	})
+}
+
+// Returns the srcset value for the variants of an image that exist and the largest width among them
+func srcsetFor(imagePath string, sizes []Size, extension string, exists func(string) bool) (string, int) {
+	var buffer bytes.Buffer
+	largest := 0
+	for _, width := range widths(sizes) {
+		variantPath := VariantPath(imagePath, width, extension)
+		if variantPath == "" || !exists(variantPath) {
+			continue
+		}
+		if buffer.Len() != 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(variantPath + " " + strconv.Itoa(width) + "w")
+		largest = width
+	}
+	return buffer.String(), largest
+}
@@ -0,0 +1,43 @@
+package imaging
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Returns the path of the cwebp tool or an empty string if it isn't available
+func webpEncoder(cwebpPath string) string {
+	if cwebpPath == "" {
+		return ""
+	}
+	path, err := exec.LookPath(cwebpPath)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// Converts a PNG image to WebP with cwebp. Metadata is never copied.
+func encodeWebp(cwebpPath string, pngData []byte, quality int) ([]byte, error) {
+	directory, err := ioutil.TempDir("", "journey-webp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(directory)
+	input := filepath.Join(directory, "input.png")
+	output := filepath.Join(directory, "output.webp")
+	err = ioutil.WriteFile(input, pngData, 0600)
+	if err != nil {
+		return nil, err
+	}
+	message, err := exec.Command(cwebpPath, "-quiet", "-metadata", "none", "-q", strconv.Itoa(quality), input, "-o", output).CombinedOutput()
+	if err != nil {
+		return nil, errors.New("Couldn't create WebP variant: " + strings.TrimSpace(err.Error()+" "+string(message)))
+	}
+	return ioutil.ReadFile(output)
+}
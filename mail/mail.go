@@ -0,0 +1,162 @@
+// Package mail sends plain text mails (e.g. password reset links) through a configurable transport.
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transports
+const (
+	TransportNone   = "none"
+	TransportSmtp   = "smtp"
+	TransportFile   = "file"
+	TransportStdout = "stdout"
+)
+
+// Settings: the mail section of config.json. Transport is "smtp", "file" (writes every mail to content/mail),
+// "stdout" (writes every mail to the standard output), or "none". The file and stdout transports are meant for testing.
+type Settings struct {
+	Transport    string
+	From         string
+	SmtpHost     string
+	SmtpPort     int
+	SmtpUsername string
+	SmtpPassword string
+}
+
+// Message: a plain text mail
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Transport: delivers a formatted mail (headers and body) to the recipient
+type Transport interface {
+	Send(from string, to string, data []byte) error
+}
+
+var ErrMailNotConfigured = errors.New("Mail is not configured. Set a mail transport in config.json.")
+
+var current = struct {
+	sync.RWMutex
+	transport Transport
+	from      string
+}{}
+
+// Function to set up the transport from the settings. Files of the file transport are written to directory.
+// The from address defaults to noreply@host.
+func Initialize(settings Settings, directory string, host string) error {
+	var transport Transport
+	switch settings.Transport {
+	case TransportNone, "":
+		transport = nil
+	case TransportSmtp:
+		if settings.SmtpHost == "" || settings.SmtpPort == 0 {
+			return errors.New("The smtp mail transport needs a host and a port.")
+		}
+		transport = &SmtpTransport{Host: settings.SmtpHost, Port: settings.SmtpPort, Username: settings.SmtpUsername, Password: settings.SmtpPassword}
+	case TransportFile:
+		transport = &FileTransport{Directory: directory}
+	case TransportStdout:
+		transport = &WriterTransport{Writer: os.Stdout}
+	default:
+		return errors.New("Unknown mail transport: " + settings.Transport)
+	}
+	from := settings.From
+	if from == "" {
+		from = "noreply@" + host
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return errors.New("Invalid mail sender address " + from + ": " + err.Error())
+	}
+	current.Lock()
+	current.transport = transport
+	current.from = from
+	current.Unlock()
+	return nil
+}
+
+// Returns false if mail is turned off
+func IsAvailable() bool {
+	current.RLock()
+	defer current.RUnlock()
+	return current.transport != nil
+}
+
+// Function to send a mail through the configured transport.
+func Send(message *Message) error {
+	current.RLock()
+	transport := current.transport
+	from := current.from
+	current.RUnlock()
+	if transport == nil {
+		return ErrMailNotConfigured
+	}
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return err
+	}
+	data, err := Format(from, message, time.Now())
+	if err != nil {
+		return err
+	}
+	return transport.Send(from, to.Address, data)
+}
+
+// Function to format a message as an RFC 5322 mail (utf-8, quoted-printable).
+func Format(from string, message *Message, date time.Time) ([]byte, error) {
+	// Header values must not contain line breaks
+	for _, value := range []string{from, message.To, message.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("Mail headers must not contain line breaks.")
+		}
+	}
+	messageId, err := generateMessageId(from)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("From: " + from + "\r\n")
+	buffer.WriteString("To: " + message.To + "\r\n")
+	buffer.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	buffer.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	buffer.WriteString("Message-Id: " + messageId + "\r\n")
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buffer.WriteString("\r\n")
+	writer := quotedprintable.NewWriter(&buffer)
+	_, err = writer.Write([]byte(strings.Replace(message.Body, "\n", "\r\n", -1)))
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func generateMessageId(from string) (string, error) {
+	random := make([]byte, 16)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if index := strings.LastIndex(from, "@"); index != -1 {
+		domain = strings.TrimRight(from[index+1:], ">")
+	}
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	message := &Message{To: "john@example.com", Subject: "Passwort zurücksetzen", Body: "Hallo John,\nbitte klicke hier: https://example.com/admin/login/reset/?token=abc"}
+	data, err := Format("blog@example.com", message, time.Date(2017, 3, 10, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != message.Subject {
+		t.Errorf("Subject => %q (%v), want %q", subject, err, message.Subject)
+	}
+	if from := parsed.Header.Get("From"); from != "blog@example.com" {
+		t.Errorf("From => %q", from)
+	}
+	if date := parsed.Header.Get("Date"); date != "Fri, 10 Mar 2017 12:00:00 +0000" {
+		t.Errorf("Date => %q", date)
+	}
+	if !strings.HasSuffix(parsed.Header.Get("Message-Id"), "@example.com>") {
+		t.Errorf("Message-Id => %q", parsed.Header.Get("Message-Id"))
+	}
+}
+
+func TestFormatRejectsLineBreaks(t *testing.T) {
+	message := &Message{To: "john@example.com\r\nBcc: everyone@example.com", Subject: "Hello", Body: ""}
+	if _, err := Format("blog@example.com", message, time.Now()); err == nil {
+		t.Errorf("Format() with a line break in a header: expected an error")
+	}
+}
+
+func TestSend(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journey-mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Initialize(Settings{Transport: TransportFile}, directory, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Send(&Message{To: "John <john@example.com>", Subject: "Hello", Body: "Hello John"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(directory, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Send() wrote %d files, want 1", len(files))
+	}
+	data, _ := ioutil.ReadFile(files[0])
+	if !bytes.Contains(data, []byte("From: noreply@example.com\r\n")) {
+		t.Errorf("Send() => mail without default sender:\n%s", data)
+	}
+	// Turned off
+	err = Initialize(Settings{Transport: TransportNone}, directory, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Send(&Message{To: "john@example.com"}); err != ErrMailNotConfigured {
+		t.Errorf("Send() without transport => %v, want ErrMailNotConfigured", err)
+	}
+}
@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"io"
+	"io/ioutil"
+	"net/smtp"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SmtpTransport: sends mails through an smtp server. STARTTLS is used if the server supports it.
+type SmtpTransport struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+func (t *SmtpTransport) Send(from string, to string, data []byte) error {
+	var auth smtp.Auth
+	if t.Username != "" {
+		auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	}
+	return smtp.SendMail(t.Host+":"+strconv.Itoa(t.Port), auth, from, []string{to}, data)
+}
+
+// FileTransport: writes every mail to its own .eml file in Directory
+type FileTransport struct {
+	Directory string
+}
+
+func (t *FileTransport) Send(from string, to string, data []byte) error {
+	err := os.MkdirAll(t.Directory, 0776)
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(t.Directory, time.Now().UTC().Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriterTransport: writes every mail to Writer (e.g. the standard output)
+type WriterTransport struct {
+	Writer io.Writer
+	mutex  sync.Mutex
+}
+
+func (t *WriterTransport) Send(from string, to string, data []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, err := io.WriteString(t.Writer, "----- Mail from "+from+" to "+to+" -----\r\n")
+	if err != nil {
+		return err
+	}
+	_, err = t.Writer.Write(data)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(t.Writer, "\r\n----- End of mail -----\r\n")
+	return err
+}
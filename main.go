@@ -3,9 +3,12 @@ package main
 import (
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dimfeld/httptreemux"
 	"github.com/kabukky/journey/configuration"
@@ -13,8 +16,11 @@ import (
 	"github.com/kabukky/journey/filenames"
 	"github.com/kabukky/journey/flags"
 	"github.com/kabukky/journey/https"
+	"github.com/kabukky/journey/mail"
 	"github.com/kabukky/journey/plugins"
+	"github.com/kabukky/journey/scheduler"
 	"github.com/kabukky/journey/server"
+	"github.com/kabukky/journey/storage"
 	"github.com/kabukky/journey/structure/methods"
 	"github.com/kabukky/journey/templates"
 )
@@ -43,18 +49,87 @@ func main() {
 
 	// Configuration is read from config.json by loading the configuration package
 
+	// Database schema migrations (without starting the blog)
+	if flags.MigrateStatus || flags.MigrateDown {
+		if err = database.Open(); err != nil {
+			log.Fatal("Error: Couldn't open database:", err)
+			return
+		}
+		if flags.MigrateDown {
+			if _, err = database.MigrateDown(); err != nil {
+				log.Fatal("Error: Couldn't revert migration:", err)
+				return
+			}
+		}
+		statuses, err := database.MigrationStatus()
+		if err != nil {
+			log.Fatal("Error: Couldn't retrieve migration status:", err)
+			return
+		}
+		for _, status := range statuses {
+			if status.AppliedAt != nil {
+				log.Println("Migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + "): applied at " + status.AppliedAt.Format(time.RFC3339))
+			} else {
+				log.Println("Migration " + strconv.FormatInt(status.Version, 10) + " (" + status.Name + "): pending")
+			}
+		}
+		return
+	}
+
 	// Database
 	if err = database.Initialize(); err != nil {
 		log.Fatal("Error: Couldn't initialize database:", err)
 		return
 	}
 
+	// Copy an existing SQLite database into the configured database backend
+	if flags.CopyFromSqlite != "" {
+		if err = database.CopyFromSqlite(flags.CopyFromSqlite); err != nil {
+			log.Fatal("Error: Couldn't copy database:", err)
+			return
+		}
+		log.Println("Copied " + flags.CopyFromSqlite + " into the " + configuration.Config.DatabaseDialect + " database.")
+		return
+	}
+
+	// Storage of uploaded files
+	if err = storage.Initialize(configuration.Config.Storage, filenames.ImagesFilepath); err != nil {
+		log.Fatal("Error: Couldn't initialize storage:", err)
+		return
+	}
+
+	// Add the images that were uploaded before the media library existed
+	if flags.ImportMedia {
+		if _, err = methods.ImportMedia(); err != nil {
+			log.Fatal("Error: Couldn't import media:", err)
+			return
+		}
+		return
+	}
+
+	// Search index
+	if err = methods.InitializeSearchIndex(); err != nil {
+		log.Fatal("Error: Couldn't initialize search index:", err)
+		return
+	}
+
 	// Global blog data
 	if err = methods.GenerateBlog(); err != nil {
 		log.Fatal("Error: Couldn't generate blog data:", err)
 		return
 	}
 
+	// Mail (the default sender address uses the host of the blog url)
+	blogUrl, err := url.Parse(configuration.Config.Url)
+	if err != nil {
+		log.Fatal("Error: Couldn't parse blog url:", err)
+		return
+	}
+	if err = mail.Initialize(configuration.Config.Mail, filenames.MailFilepath, blogUrl.Hostname()); err != nil {
+		log.Fatal("Error: Couldn't initialize mail:", err)
+		return
+	}
+
 	// Templates
 	if err = templates.Generate(); err != nil {
 		log.Fatal("Error: Couldn't compile templates:", err)
@@ -68,6 +143,22 @@ func main() {
 		log.Println("Plugins loaded.")
 	}
 
+	// Static export (without starting the server)
+	if flags.Export != "" {
+		if err = server.Export(flags.Export, flags.ExportUrl); err != nil {
+			log.Fatal("Error: Couldn't export blog:", err)
+			return
+		}
+		return
+	}
+
+	// Webhooks (started after the one-off commands like -export, which exit before deliveries are sent, and before the
+	// scheduler, so that posts it publishes are announced)
+	methods.StartWebhooks()
+
+	// Scheduled posts
+	scheduler.Start()
+
 	// HTTP(S) Server
 	httpPort := configuration.Config.HttpHostAndPort
 	httpsPort := configuration.Config.HttpsHostAndPort
@@ -0,0 +1,335 @@
+// Package nql implements the subset of Ghost's query language (NQL) that is used in filter parameters,
+// e.g. "featured:true+tag:[news,updates]" or "published_at:>'2017-01-01'+author:-john".
+package nql
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Comparison operators
+const (
+	OperatorEqual          = ""
+	OperatorNot            = "-"
+	OperatorGreater        = ">"
+	OperatorGreaterOrEqual = ">="
+	OperatorLess           = "<"
+	OperatorLessOrEqual    = "<="
+	OperatorContains       = "~"
+	OperatorStartsWith     = "~^"
+	OperatorEndsWith       = "~$"
+)
+
+// Record: something that can be filtered (e.g. a post). A field can have multiple values (e.g. the slugs of all tags of a post). A field without values is null.
+type Record interface {
+	Values(field string) []string
+}
+
+type Expression interface {
+	Match(record Record) bool
+}
+
+// And: all expressions must match
+type And []Expression
+
+// Or: one of the expressions must match
+type Or []Expression
+
+// Comparison: a single field:value comparison. Multiple values (field:[a,b]) match if one of them matches.
+type Comparison struct {
+	Field    string
+	Operator string
+	Values   []string
+}
+
+func (a And) Match(record Record) bool {
+	for _, expression := range a {
+		if !expression.Match(record) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o Or) Match(record Record) bool {
+	for _, expression := range o {
+		if expression.Match(record) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Comparison) Match(record Record) bool {
+	recordValues := record.Values(c.Field)
+	if c.Operator == OperatorNot {
+		for _, value := range c.Values {
+			if matchesAny(recordValues, OperatorEqual, value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range c.Values {
+		if matchesAny(recordValues, c.Operator, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(recordValues []string, operator string, value string) bool {
+	// null matches fields without values
+	if value == "null" && len(recordValues) == 0 {
+		return operator == OperatorEqual
+	}
+	for _, recordValue := range recordValues {
+		if matches(recordValue, operator, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(recordValue string, operator string, value string) bool {
+	switch operator {
+	case OperatorEqual:
+		return strings.ToLower(recordValue) == strings.ToLower(value)
+	case OperatorContains:
+		return strings.Contains(strings.ToLower(recordValue), strings.ToLower(value))
+	case OperatorStartsWith:
+		return strings.HasPrefix(strings.ToLower(recordValue), strings.ToLower(value))
+	case OperatorEndsWith:
+		return strings.HasSuffix(strings.ToLower(recordValue), strings.ToLower(value))
+	}
+	result := compare(recordValue, value)
+	switch operator {
+	case OperatorGreater:
+		return result > 0
+	case OperatorGreaterOrEqual:
+		return result >= 0
+	case OperatorLess:
+		return result < 0
+	case OperatorLessOrEqual:
+		return result <= 0
+	}
+	return false
+}
+
+// Compares numbers and dates by their value, everything else as strings.
+func compare(a string, b string) int {
+	numberA, errA := strconv.ParseFloat(a, 64)
+	numberB, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		if numberA < numberB {
+			return -1
+		} else if numberA > numberB {
+			return 1
+		}
+		return 0
+	}
+	dateA, okA := ParseDate(a)
+	dateB, okB := ParseDate(b)
+	if okA && okB {
+		if dateA.Before(dateB) {
+			return -1
+		} else if dateA.After(dateB) {
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+var dateFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// Function to parse the date formats that filters accept (RFC 3339, "2006-01-02 15:04:05", and "2006-01-02")
+func ParseDate(value string) (time.Time, bool) {
+	for _, format := range dateFormats {
+		date, err := time.Parse(format, value)
+		if err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Function to parse a filter. An empty filter matches everything.
+func Parse(filter string) (Expression, error) {
+	p := parser{input: filter}
+	p.skipWhitespace()
+	if p.done() {
+		return And{}, nil
+	}
+	expression, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if !p.done() {
+		return nil, p.error("unexpected '" + string(p.input[p.position]) + "'")
+	}
+	return expression, nil
+}
+
+type parser struct {
+	input    string
+	position int
+}
+
+func (p *parser) parseOr() (Expression, error) {
+	expressions := make(Or, 0)
+	for {
+		expression, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+		p.skipWhitespace()
+		if !p.accept(",") {
+			break
+		}
+	}
+	if len(expressions) == 1 {
+		return expressions[0], nil
+	}
+	return expressions, nil
+}
+
+func (p *parser) parseAnd() (Expression, error) {
+	expressions := make(And, 0)
+	for {
+		expression, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+		p.skipWhitespace()
+		if !p.accept("+") {
+			break
+		}
+	}
+	if len(expressions) == 1 {
+		return expressions[0], nil
+	}
+	return expressions, nil
+}
+
+func (p *parser) parseTerm() (Expression, error) {
+	p.skipWhitespace()
+	if p.accept("(") {
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWhitespace()
+		if !p.accept(")") {
+			return nil, p.error("missing ')'")
+		}
+		return expression, nil
+	}
+	// Field
+	start := p.position
+	for !p.done() && isFieldCharacter(p.input[p.position]) {
+		p.position++
+	}
+	if start == p.position {
+		return nil, p.error("expected a field name")
+	}
+	comparison := Comparison{Field: strings.ToLower(p.input[start:p.position])}
+	p.skipWhitespace()
+	if !p.accept(":") {
+		return nil, p.error("expected ':' after '" + comparison.Field + "'")
+	}
+	p.skipWhitespace()
+	// Operator (longest first)
+	for _, operator := range []string{OperatorGreaterOrEqual, OperatorLessOrEqual, OperatorStartsWith, OperatorEndsWith, OperatorGreater, OperatorLess, OperatorContains, OperatorNot} {
+		if p.accept(operator) {
+			comparison.Operator = operator
+			break
+		}
+	}
+	p.skipWhitespace()
+	// Value or list of values
+	if p.accept("[") {
+		for {
+			p.skipWhitespace()
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			comparison.Values = append(comparison.Values, value)
+			p.skipWhitespace()
+			if p.accept("]") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, p.error("missing ']'")
+			}
+		}
+	} else {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = []string{value}
+	}
+	return comparison, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	if p.done() {
+		return "", p.error("expected a value")
+	}
+	// Quoted value
+	quote := p.input[p.position]
+	if quote == '\'' || quote == '"' {
+		p.position++
+		start := p.position
+		for !p.done() && p.input[p.position] != quote {
+			p.position++
+		}
+		if p.done() {
+			return "", p.error("missing closing quote")
+		}
+		value := p.input[start:p.position]
+		p.position++
+		return value, nil
+	}
+	start := p.position
+	for !p.done() && !strings.ContainsRune("+,()[] \t\n", rune(p.input[p.position])) {
+		p.position++
+	}
+	if start == p.position {
+		return "", p.error("expected a value")
+	}
+	return p.input[start:p.position], nil
+}
+
+func (p *parser) accept(token string) bool {
+	if strings.HasPrefix(p.input[p.position:], token) {
+		p.position += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipWhitespace() {
+	for !p.done() && strings.ContainsRune(" \t\n", rune(p.input[p.position])) {
+		p.position++
+	}
+}
+
+func (p *parser) done() bool {
+	return p.position >= len(p.input)
+}
+
+func (p *parser) error(message string) error {
+	return errors.New("Invalid filter at position " + strconv.Itoa(p.position) + ": " + message)
+}
+
+func isFieldCharacter(character byte) bool {
+	return (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') || (character >= '0' && character <= '9') || character == '_' || character == '.'
+}
@@ -0,0 +1,77 @@
+package nql
+
+import "testing"
+
+type testRecord map[string][]string
+
+func (r testRecord) Values(field string) []string {
+	return r[field]
+}
+
+var record = testRecord{
+	"slug":         {"welcome"},
+	"featured":     {"true"},
+	"tag":          {"news", "getting-started"},
+	"author":       {"john"},
+	"reading_time": {"7"},
+	"published_at": {"2017-03-10T12:00:00Z"},
+}
+
+var matchTests = []struct {
+	filter string
+	out    bool
+}{
+	{"", true},
+	{"featured:true", true},
+	{"featured:false", false},
+	{"tag:news", true},
+	{"tag:NEWS", true},
+	{"tag:-news", false},
+	{"tag:-sports", true},
+	{"tag:[sports,news]", true},
+	{"tag:-[sports,news]", false},
+	{"featured:true+tag:sports", false},
+	{"featured:true,tag:sports", true},
+	{"author:john+(tag:sports,tag:getting-started)", true},
+	{"reading_time:>5", true},
+	{"reading_time:<=5", false},
+	{"reading_time:>10", false},
+	{"published_at:>'2017-01-01'", true},
+	{"published_at:<'2017-01-01'", false},
+	{"slug:~'come'", true},
+	{"slug:~^'wel'", true},
+	{"slug:~$'wel'", false},
+	{"image:null", true},
+	{"slug:null", false},
+}
+
+func TestMatch(t *testing.T) {
+	for _, test := range matchTests {
+		expression, err := Parse(test.filter)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error %v", test.filter, err)
+			continue
+		}
+		if out := expression.Match(record); out != test.out {
+			t.Errorf("Parse(%q).Match() => %v, want %v", test.filter, out, test.out)
+		}
+	}
+}
+
+var errorTests = []string{
+	"featured",
+	"featured:",
+	"tag:[news",
+	"(tag:news",
+	"tag:'news",
+	"tag:news)",
+	":news",
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, filter := range errorTests {
+		if _, err := Parse(filter); err == nil {
+			t.Errorf("Parse(%q): expected an error", filter)
+		}
+	}
+}
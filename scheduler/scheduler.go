@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"github.com/kabukky/journey/structure/methods"
+)
+
+// How often the scheduler checks for posts that are due to be published
+const interval = time.Minute
+
+// Function to start the background scheduler. Scheduled posts become visible on the blog as soon as their publication date has passed.
+func Start() {
+	// Publish posts that became due while Journey wasn't running
+	publish()
+	go func() {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			publish()
+		}
+	}()
+}
+
+func publish() {
+	_, err := methods.PublishScheduledPosts()
+	if err != nil {
+		log.Println("Error while publishing scheduled posts:", err)
+	}
+}
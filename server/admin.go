@@ -3,9 +3,10 @@ package server
 import (
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -18,6 +19,7 @@ import (
 	"github.com/kabukky/journey/database"
 	"github.com/kabukky/journey/date"
 	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/imaging"
 	"github.com/kabukky/journey/slug"
 	"github.com/kabukky/journey/structure"
 	"github.com/kabukky/journey/structure/methods"
@@ -26,30 +28,36 @@ import (
 )
 
 type JsonPost struct {
-	Id              int64
-	Title           string
-	Slug            string
-	Markdown        string
-	Html            string
-	IsFeatured      bool
-	IsPage          bool
-	IsPublished     bool
-	Image           string
-	MetaDescription string
-	Date            *time.Time
-	Tags            string
+	Id                int64
+	Title             string
+	Slug              string
+	Markdown          string
+	Html              string
+	IsFeatured        bool
+	IsPage            bool
+	IsPublished       bool
+	IsScheduled       bool
+	Image             string
+	MetaDescription   string
+	CodeinjectionHead string
+	CodeinjectionFoot string
+	Date              *time.Time
+	ScheduledAt       *time.Time
+	Tags              string
 }
 
 type JsonBlog struct {
-	Url             string
-	Title           string
-	Description     string
-	Logo            string
-	Cover           string
-	Themes          []string
-	ActiveTheme     string
-	PostsPerPage    int64
-	NavigationItems []structure.Navigation
+	Url               string
+	Title             string
+	Description       string
+	Logo              string
+	Cover             string
+	Themes            []string
+	ActiveTheme       string
+	PostsPerPage      int64
+	NavigationItems   []structure.Navigation
+	CodeinjectionHead string
+	CodeinjectionFoot string
 }
 
 type JsonUser struct {
@@ -64,6 +72,8 @@ type JsonUser struct {
 	Location         string
 	Password         string
 	PasswordRepeated string
+	Role             int
+	Status           string
 }
 
 type JsonUserId struct {
@@ -89,9 +99,30 @@ func postLoginHandler(w http.ResponseWriter, r *http.Request, _ map[string]strin
 	name := r.FormValue("name")
 	password := r.FormValue("password")
 	if name != "" && password != "" {
+		// Slow down password guessing
+		if wait := authentication.LoginWaitTime(name, r); wait > 0 {
+			seconds := int(wait/time.Second) + 1
+			log.Println("Blocked login attempt for user " + name + " (too many failed attempts)")
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, "Too many failed login attempts. Please try again in "+strconv.Itoa(seconds)+" seconds.", http.StatusTooManyRequests)
+			return
+		}
 		if authentication.LoginIsCorrect(name, password) {
-			logInUser(name, w)
+			// Users with two-factor authentication have to enter a code before they get a session
+			userId, err := getUserId(name)
+			if err == nil && authentication.TwoFactorIsEnabled(userId) {
+				err = authentication.SetPendingLogin(userId, w, r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				http.Redirect(w, r, "/admin/login/2fa/", 302)
+				return
+			}
+			authentication.RecordLoginAttempt(name, r, true)
+			logInUser(name, w, r)
 		} else {
+			authentication.RecordLoginAttempt(name, r, false)
 			log.Println("Failed login attempt for user " + name)
 		}
 	}
@@ -105,13 +136,18 @@ func getRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[string
 		http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "registration.html"))
 		return
 	}
+	// Users that have been invited may register as well
+	if _, err := retrieveValidInvite(r.FormValue("token")); err == nil {
+		http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "registration.html"))
+		return
+	}
 	http.Redirect(w, r, "/admin/", 302)
 	return
 }
 
 // Function to recieve a registration form.
 func postRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	if database.RetrieveUsersCount() == 0 { // TODO: Or check if authenticated user is admin when adding users from inside the admin area
+	if database.RetrieveUsersCount() == 0 { // The first user is the owner of the blog
 		name := r.FormValue("name")
 		email := r.FormValue("email")
 		password := r.FormValue("password")
@@ -121,7 +157,7 @@ func postRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[strin
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			user := structure.User{Name: []byte(name), Slug: slug.Generate(name, "users"), Email: []byte(email), Image: []byte(filenames.DefaultUserImageFilename), Cover: []byte(filenames.DefaultUserCoverFilename), Role: 4}
+			user := structure.User{Name: []byte(name), Slug: slug.Generate(name, "users"), Email: []byte(email), Image: []byte(filenames.DefaultUserImageFilename), Cover: []byte(filenames.DefaultUserCoverFilename), Role: structure.RoleOwner}
 			err = methods.SaveUser(&user, hashedPassword, 1)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -133,15 +169,45 @@ func postRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[strin
 		http.Redirect(w, r, "/admin/", 302)
 		return
 	} else {
-		// TODO: Handle creation of other users (not just the first one)
-		http.Error(w, "Not implemented yet.", http.StatusInternalServerError)
+		// All other users need a valid invite to register
+		invite, err := retrieveValidInvite(r.FormValue("token"))
+		if err != nil {
+			http.Error(w, "The invitation is invalid or has expired.", http.StatusForbidden)
+			return
+		}
+		name := r.FormValue("name")
+		password := r.FormValue("password")
+		if name != "" && password != "" {
+			// Make sure the user name is not taken yet
+			if _, err := database.RetrieveUserByName([]byte(name)); err == nil {
+				http.Error(w, "This user name is already taken.", http.StatusBadRequest)
+				return
+			}
+			hashedPassword, err := authentication.EncryptPassword(password)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			user := structure.User{Name: []byte(name), Slug: slug.Generate(name, "users"), Email: invite.Email, Image: []byte(filenames.DefaultUserImageFilename), Cover: []byte(filenames.DefaultUserCoverFilename), Role: invite.Role}
+			err = methods.SaveUser(&user, hashedPassword, invite.CreatedBy)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// Invites can only be used once
+			err = database.DeleteInviteById(invite.Id)
+			if err != nil {
+				log.Println("Couldn't delete used invite:", err)
+			}
+		}
+		http.Redirect(w, r, "/admin/", 302)
 		return
 	}
 }
 
-// Function to log out the user. Not used at the moment.
+// Function to log out the user.
 func logoutHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	authentication.ClearSession(w)
+	authentication.ClearSession(w, r)
 	http.Redirect(w, r, "/admin/login/", 302)
 	return
 }
@@ -152,8 +218,8 @@ func adminHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 		http.Redirect(w, r, "/admin/register/", 302)
 		return
 	} else {
-		userName := authentication.GetUserName(r)
-		if userName != "" {
+		user := getAuthenticatedUser(r)
+		if user != nil {
 			http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "admin.html"))
 			return
 		} else {
@@ -165,8 +231,8 @@ func adminHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 
 // Function to serve files belonging to the admin interface.
 func adminFileHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		// Get arguments (files)
 		http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, params["filepath"]))
 		return
@@ -178,8 +244,8 @@ func adminFileHandler(w http.ResponseWriter, r *http.Request, params map[string]
 
 // API function to get all posts by pages
 func apiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		number := params["number"]
 		page, err := strconv.Atoi(number)
 		if err != nil || page < 1 {
@@ -187,7 +253,25 @@ func apiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]s
 			return
 		}
 		postsPerPage := int64(15)
-		posts, err := database.RetrievePostsForApi(postsPerPage, ((int64(page) - 1) * postsPerPage))
+		// Filter the posts by a search query if one was submitted (e.g. /admin/api/posts/1?q=journey)
+		query := r.FormValue("q")
+		var posts []structure.Post
+		if query != "" && !database.SearchIsAvailable() {
+			http.Error(w, database.ErrSearchNotAvailable.Error(), http.StatusServiceUnavailable)
+			return
+		} else if query != "" {
+			if isEditor(user) {
+				posts, err = database.RetrievePostsForApiBySearch(query, postsPerPage, ((int64(page) - 1) * postsPerPage))
+			} else {
+				// Authors only get to see their own posts
+				posts, err = database.RetrievePostsForApiBySearchAndUser(query, user.Id, postsPerPage, ((int64(page) - 1) * postsPerPage))
+			}
+		} else if isEditor(user) {
+			posts, err = database.RetrievePostsForApi(postsPerPage, ((int64(page) - 1) * postsPerPage))
+		} else {
+			// Authors only get to see their own posts
+			posts, err = database.RetrievePostsForApiByUser(user.Id, postsPerPage, ((int64(page) - 1) * postsPerPage))
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -208,8 +292,8 @@ func apiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]s
 
 // API function to get a post by id
 func getApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		id := params["id"]
 		// Get post
 		postId, err := strconv.ParseInt(id, 10, 64)
@@ -222,6 +306,11 @@ func getApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// Authors may only access their own posts
+		if !canEditPost(user, post) {
+			http.Error(w, "You don't have permission to access this post.", http.StatusForbidden)
+			return
+		}
 		json, err := json.Marshal(postToJson(post))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -238,17 +327,12 @@ func getApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string
 
 // API function to create a post
 func postApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
-		userId, err := getUserId(userName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		// Create post
 		decoder := json.NewDecoder(r.Body)
 		var json JsonPost
-		err = decoder.Decode(&json)
+		err := decoder.Decode(&json)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -260,7 +344,8 @@ func postApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]str
 			postSlug = slug.Generate(json.Title, "posts")
 		}
 		currentTime := date.GetCurrentTime()
-		post := structure.Post{Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), Date: &currentTime, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: userId}}
+		post := structure.Post{Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), CodeinjectionHead: []byte(json.CodeinjectionHead), CodeinjectionFoot: []byte(json.CodeinjectionFoot), Date: &currentTime, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: user.Id}}
+		applySchedule(&post, &json)
 		err = methods.SavePost(&post)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -277,17 +362,12 @@ func postApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]str
 
 // API function to update a post.
 func patchApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
-		userId, err := getUserId(userName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		// Update post
 		decoder := json.NewDecoder(r.Body)
 		var json JsonPost
-		err = decoder.Decode(&json)
+		err := decoder.Decode(&json)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -299,13 +379,19 @@ func patchApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// Authors may only edit their own posts
+		if !canEditPost(user, post) {
+			http.Error(w, "You don't have permission to edit this post.", http.StatusForbidden)
+			return
+		}
 		if json.Slug != post.Slug { // Check if user has submitted a custom slug
 			postSlug = slug.Generate(json.Slug, "posts")
 		} else {
 			postSlug = post.Slug
 		}
 		currentTime := date.GetCurrentTime()
-		*post = structure.Post{Id: json.Id, Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), Date: &currentTime, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: userId}}
+		*post = structure.Post{Id: json.Id, Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), CodeinjectionHead: []byte(json.CodeinjectionHead), CodeinjectionFoot: []byte(json.CodeinjectionFoot), Date: &currentTime, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: user.Id}}
+		applySchedule(post, &json)
 		err = methods.UpdatePost(post)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -322,8 +408,8 @@ func patchApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 
 // API function to delete a post by id.
 func deleteApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		id := params["id"]
 		// Delete post
 		postId, err := strconv.ParseInt(id, 10, 64)
@@ -331,6 +417,16 @@ func deleteApiPostHandler(w http.ResponseWriter, r *http.Request, params map[str
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		post, err := database.RetrievePostById(postId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Authors may only delete their own posts
+		if !canEditPost(user, post) {
+			http.Error(w, "You don't have permission to delete this post.", http.StatusForbidden)
+			return
+		}
 		err = methods.DeletePost(postId)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -347,8 +443,8 @@ func deleteApiPostHandler(w http.ResponseWriter, r *http.Request, params map[str
 
 // API function to upload images
 func apiUploadHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		// Create multipart reader
 		reader, err := r.MultipartReader()
 		if err != nil {
@@ -367,27 +463,37 @@ func apiUploadHandler(w http.ResponseWriter, r *http.Request, _ map[string]strin
 			if part.FileName() == "" {
 				continue
 			}
-			// Folder structure: year/month/randomname
-			currentDate := date.GetCurrentTime()
-			filePath := filepath.Join(filenames.ImagesFilepath, currentDate.Format("2006"), currentDate.Format("01"))
-			if os.MkdirAll(filePath, 0777) != nil {
+			// Check and process the image (the type is detected by the content, not by the file name)
+			maxSize := configuration.Config.Images.MaxUploadMegabytes << 20
+			data, err := ioutil.ReadAll(io.LimitReader(part, maxSize+1))
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			dst, err := os.Create(filepath.Join(filePath, strconv.FormatInt(currentDate.Unix(), 10)+"_"+uuid.NewV4().String()+filepath.Ext(part.FileName())))
-			defer dst.Close()
+			if int64(len(data)) > maxSize {
+				http.Error(w, imaging.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			image, err := imaging.Process(data, configuration.Config.Images)
+			if err != nil {
+				http.Error(w, err.Error(), imageErrorStatus(err))
+				return
+			}
+			// Folder structure: year/month/randomname
+			currentDate := date.GetCurrentTime()
+			relativePath := path.Join(currentDate.Format("2006"), currentDate.Format("01"), strconv.FormatInt(currentDate.Unix(), 10)+"_"+uuid.NewV4().String()+image.Extension)
+			err = saveImage(relativePath, image)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			if _, err := io.Copy(dst, part); err != nil {
+			filePath := "/images/" + relativePath
+			// Add the image to the media library
+			_, err = database.InsertMedia(filePath, imaging.MimeType(image.Extension), int64(len(image.Data)), image.Width, image.Height, user.Id, currentDate)
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			// Rewrite to file path on server
-			filePath = strings.Replace(dst.Name(), filenames.ImagesFilepath, "/images", 1)
-			// Make sure to always use "/" as path separator (to make a valid url that we can use on the blog)
-			filePath = filepath.ToSlash(filePath)
 			allFilePaths = append(allFilePaths, filePath)
 		}
 		json, err := json.Marshal(allFilePaths)
@@ -406,46 +512,25 @@ func apiUploadHandler(w http.ResponseWriter, r *http.Request, _ map[string]strin
 
 // API function to get all images by pages
 func apiImagesHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		number := params["number"]
 		page, err := strconv.Atoi(number)
 		if err != nil || page < 1 {
 			http.Error(w, "Not a valid api function!", http.StatusInternalServerError)
 			return
 		}
-		images := make([]string, 0)
-		// Walk all files in images folder
-		err = filepath.Walk(filenames.ImagesFilepath, func(filePath string, info os.FileInfo, err error) error {
-			if !info.IsDir() && (strings.EqualFold(filepath.Ext(filePath), ".jpg") || strings.EqualFold(filepath.Ext(filePath), ".jpeg") || strings.EqualFold(filepath.Ext(filePath), ".gif") || strings.EqualFold(filepath.Ext(filePath), ".png") || strings.EqualFold(filepath.Ext(filePath), ".svg")) {
-				// Rewrite to file path on server
-				filePath = strings.Replace(filePath, filenames.ImagesFilepath, "/images", 1)
-				// Make sure to always use "/" as path separator (to make a valid url that we can use on the blog)
-				filePath = filepath.ToSlash(filePath)
-				// Prepend file to slice (thus reversing the order)
-				images = append([]string{filePath}, images...)
-			}
-			return nil
-		})
-		if len(images) == 0 {
-			// Write empty json array
-			w.Header().Set("Content-Type", "application/json")
-			w.Write([]byte("[]"))
-			return
-		}
-		imagesPerPage := 15
-		start := (page * imagesPerPage) - imagesPerPage
-		end := page * imagesPerPage
-		if start > (len(images) - 1) {
-			// Write empty json array
-			w.Header().Set("Content-Type", "application/json")
-			w.Write([]byte("[]"))
+		imagesPerPage := int64(15)
+		media, err := database.RetrieveMedia(false, imagesPerPage, ((int64(page) - 1) * imagesPerPage))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		if end > len(images) {
-			end = len(images)
+		images := make([]string, len(media))
+		for index, _ := range media {
+			images[index] = media[index].Path
 		}
-		json, err := json.Marshal(images[start:end])
+		json, err := json.Marshal(images)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -459,10 +544,15 @@ func apiImagesHandler(w http.ResponseWriter, r *http.Request, params map[string]
 	}
 }
 
-// API function to delete an image by its filename.
+// API function to delete an image by its url (e.g. /images/2016/01/image.jpg).
 func deleteApiImageHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" { // TODO: Check if the user has permissions to delete the image
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		// Only editors and administrators may delete images (they could be used in the posts of other users)
+		if !isEditor(user) {
+			http.Error(w, "You don't have permission to delete images.", http.StatusForbidden)
+			return
+		}
 		// Get the file name from the json data
 		decoder := json.NewDecoder(r.Body)
 		var json JsonImage
@@ -471,15 +561,13 @@ func deleteApiImageHandler(w http.ResponseWriter, r *http.Request, _ map[string]
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		err = filepath.Walk(filenames.ImagesFilepath, func(filePath string, info os.FileInfo, err error) error {
-			if !info.IsDir() && filepath.Base(filePath) == filepath.Base(json.Filename) {
-				err := os.Remove(filePath)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
-		})
+		// Only the file with exactly this path is deleted
+		medium, err := database.RetrieveMediaByPath(mediaPath(json.Filename))
+		if err != nil {
+			http.Error(w, "Image not found.", http.StatusNotFound)
+			return
+		}
+		err = deleteMedia(medium)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -495,8 +583,8 @@ func deleteApiImageHandler(w http.ResponseWriter, r *http.Request, _ map[string]
 
 // API function to get blog settings
 func getApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		// Read lock the global blog
 		methods.Blog.RLock()
 		defer methods.Blog.RUnlock()
@@ -517,16 +605,16 @@ func getApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]stri
 
 // API function to update blog settings
 func patchApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
-		userId, err := getUserId(userName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		// Only administrators may change the blog settings
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to change the blog settings.", http.StatusForbidden)
 			return
 		}
 		decoder := json.NewDecoder(r.Body)
 		var json JsonBlog
-		err = decoder.Decode(&json)
+		err := decoder.Decode(&json)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -551,8 +639,8 @@ func patchApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		tempBlog := structure.Blog{Url: []byte(configuration.Config.Url), Title: []byte(json.Title), Description: []byte(json.Description), Logo: []byte(json.Logo), Cover: []byte(json.Cover), AssetPath: []byte("/assets/"), PostCount: blog.PostCount, PostsPerPage: json.PostsPerPage, ActiveTheme: json.ActiveTheme, NavigationItems: json.NavigationItems}
-		err = methods.UpdateBlog(&tempBlog, userId)
+		tempBlog := structure.Blog{Url: []byte(configuration.Config.Url), Title: []byte(json.Title), Description: []byte(json.Description), Logo: []byte(json.Logo), Cover: []byte(json.Cover), AssetPath: []byte("/assets/"), PostCount: blog.PostCount, PostsPerPage: json.PostsPerPage, ActiveTheme: json.ActiveTheme, NavigationItems: json.NavigationItems, CodeinjectionHead: []byte(json.CodeinjectionHead), CodeinjectionFoot: []byte(json.CodeinjectionFoot)}
+		err = methods.UpdateBlog(&tempBlog, user.Id)
 		// Check if active theme setting has been changed, if so, generate templates from new theme
 		if tempBlog.ActiveTheme != blog.ActiveTheme {
 			err = templates.Generate()
@@ -578,28 +666,23 @@ func patchApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 
 // API function to get user settings
 func getApiUserHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
-		userId, err := getUserId(userName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		id := params["id"]
 		userIdToGet, err := strconv.ParseInt(id, 10, 64)
 		if err != nil || userIdToGet < 1 {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
-		} else if userIdToGet != userId { // Make sure the authenticated user is only accessing his/her own data. TODO: Make sure the user is admin when multiple users have been introduced
+		} else if userIdToGet != user.Id && !isAdministrator(user) { // Make sure the authenticated user is only accessing his/her own data (unless he/she is an administrator)
 			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
 			return
 		}
-		user, err := database.RetrieveUser(userIdToGet)
+		userToGet, err := database.RetrieveUserWithRole(userIdToGet)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		userJson := userToJson(user)
+		userJson := userToJson(userToGet)
 		json, err := json.Marshal(userJson)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -616,16 +699,11 @@ func getApiUserHandler(w http.ResponseWriter, r *http.Request, params map[string
 
 // API function to patch user settings
 func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
-		userId, err := getUserId(userName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	user := getAuthenticatedUser(r)
+	if user != nil {
 		decoder := json.NewDecoder(r.Body)
 		var json JsonUser
-		err = decoder.Decode(&json)
+		err := decoder.Decode(&json)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -634,16 +712,18 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 		if json.Id < 1 {
 			http.Error(w, "Wrong user id.", http.StatusInternalServerError)
 			return
-		} else if userId != json.Id { // Make sure the authenticated user is only changing his/her own data. TODO: Make sure the user is admin when multiple users have been introduced
-			http.Error(w, "You don't have permission to change this data.", http.StatusInternalServerError)
-			return
 		}
 		// Get old user data to compare
-		tempUser, err := database.RetrieveUser(json.Id)
+		tempUser, err := database.RetrieveUserWithRole(json.Id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// Make sure the authenticated user is only changing his/her own data (unless he/she is allowed to manage the user)
+		if user.Id != json.Id && !canManageUser(user, tempUser) {
+			http.Error(w, "You don't have permission to change this data.", http.StatusForbidden)
+			return
+		}
 		// Make sure user email is provided
 		if json.Email == "" {
 			json.Email = string(tempUser.Email)
@@ -674,8 +754,8 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 				json.Slug = tempUser.Slug
 			}
 		}
-		user := structure.User{Id: json.Id, Name: []byte(json.Name), Slug: json.Slug, Email: []byte(json.Email), Image: []byte(json.Image), Cover: []byte(json.Cover), Bio: []byte(json.Bio), Website: []byte(json.Website), Location: []byte(json.Location)}
-		err = methods.UpdateUser(&user, userId)
+		updatedUser := structure.User{Id: json.Id, Name: []byte(json.Name), Slug: json.Slug, Email: []byte(json.Email), Image: []byte(json.Image), Cover: []byte(json.Cover), Bio: []byte(json.Bio), Website: []byte(json.Website), Location: []byte(json.Location)}
+		err = methods.UpdateUser(&updatedUser, user.Id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -686,15 +766,16 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			err = database.UpdateUserPassword(user.Id, encryptedPassword, date.GetCurrentTime(), json.Id)
+			err = database.UpdateUserPassword(updatedUser.Id, encryptedPassword, date.GetCurrentTime(), user.Id)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-		}
-		// Check if the user name was changed. If so, update the session cookie to the new user name.
-		if json.Name != string(tempUser.Name) {
-			logInUser(json.Name, w)
+			// A new password ends all other sessions of the user
+			err = database.DeleteOtherSessionsForUser(updatedUser.Id, currentSessionId(r))
+			if err != nil {
+				log.Println("Couldn't delete sessions of a user:", err)
+			}
 		}
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("User settings updated!"))
@@ -707,14 +788,9 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 
 // API function to get the id of the currently authenticated user
 func getApiUserIdHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	userName := authentication.GetUserName(r)
-	if userName != "" {
-		userId, err := getUserId(userName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		jsonUserId := JsonUserId{Id: userId}
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		jsonUserId := JsonUserId{Id: user.Id}
 		json, err := json.Marshal(jsonUserId)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -729,6 +805,46 @@ func getApiUserIdHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 	}
 }
 
+// Function to get the authenticated user (including role and status) of a request. Returns nil if nobody is logged in or the user has been suspended.
+// Requests with an api token (Authorization: Bearer <token>) are authenticated as the user who owns the token.
+func getAuthenticatedUser(r *http.Request) *structure.User {
+	if token, ok := getBearerToken(r); ok {
+		return getApiTokenUser(token)
+	}
+	session := authentication.GetSession(r)
+	if session == nil {
+		return nil
+	}
+	user, err := database.RetrieveUserWithRole(session.UserId)
+	if err != nil || user.Status == "inactive" {
+		return nil
+	}
+	return user
+}
+
+// Owners and administrators
+func isAdministrator(user *structure.User) bool {
+	return user.Role == structure.RoleOwner || user.Role == structure.RoleAdministrator
+}
+
+// Owners, administrators, and editors
+func isEditor(user *structure.User) bool {
+	return isAdministrator(user) || user.Role == structure.RoleEditor
+}
+
+// Authors may only edit their own posts, everyone else may edit all posts
+func canEditPost(user *structure.User, post *structure.Post) bool {
+	return isEditor(user) || (post.Author != nil && post.Author.Id == user.Id)
+}
+
+// Administrators may manage all users except the owner. Only the owner may manage himself/herself.
+func canManageUser(user *structure.User, userToManage *structure.User) bool {
+	if userToManage.Role == structure.RoleOwner {
+		return user.Id == userToManage.Id
+	}
+	return isAdministrator(user)
+}
+
 func getUserId(userName string) (int64, error) {
 	user, err := database.RetrieveUserByName([]byte(userName))
 	if err != nil {
@@ -737,11 +853,16 @@ func getUserId(userName string) (int64, error) {
 	return user.Id, nil
 }
 
-func logInUser(name string, w http.ResponseWriter) {
-	authentication.SetSession(name, w)
+func logInUser(name string, w http.ResponseWriter, r *http.Request) {
 	userId, err := getUserId(name)
 	if err != nil {
 		log.Println("Couldn't get id of logged in user:", err)
+		return
+	}
+	err = authentication.SetSession(userId, w, r)
+	if err != nil {
+		log.Println("Couldn't create session:", err)
+		return
 	}
 	err = database.UpdateLastLogin(date.GetCurrentTime(), userId)
 	if err != nil {
@@ -749,6 +870,16 @@ func logInUser(name string, w http.ResponseWriter) {
 	}
 }
 
+// Posts that are published with a publication date in the future are scheduled instead
+func applySchedule(post *structure.Post, jsonPost *JsonPost) {
+	if (jsonPost.IsPublished || jsonPost.IsScheduled) && jsonPost.ScheduledAt != nil && jsonPost.ScheduledAt.After(date.GetCurrentTime()) {
+		scheduledAt := jsonPost.ScheduledAt.UTC()
+		post.IsPublished = false
+		post.IsScheduled = true
+		post.Date = &scheduledAt
+	}
+}
+
 func postsToJson(posts []structure.Post) *[]JsonPost {
 	jsonPosts := make([]JsonPost, len(posts))
 	for index, _ := range posts {
@@ -767,8 +898,14 @@ func postToJson(post *structure.Post) *JsonPost {
 	jsonPost.IsFeatured = post.IsFeatured
 	jsonPost.IsPage = post.IsPage
 	jsonPost.IsPublished = post.IsPublished
+	jsonPost.IsScheduled = post.IsScheduled
+	if post.IsScheduled {
+		jsonPost.ScheduledAt = post.Date
+	}
 	jsonPost.MetaDescription = string(post.MetaDescription)
 	jsonPost.Image = string(post.Image)
+	jsonPost.CodeinjectionHead = string(post.CodeinjectionHead)
+	jsonPost.CodeinjectionFoot = string(post.CodeinjectionFoot)
 	jsonPost.Date = post.Date
 	tags := make([]string, len(post.Tags))
 	for index, _ := range post.Tags {
@@ -789,6 +926,8 @@ func blogToJson(blog *structure.Blog) *JsonBlog {
 	jsonBlog.Themes = templates.GetAllThemes()
 	jsonBlog.ActiveTheme = blog.ActiveTheme
 	jsonBlog.NavigationItems = blog.NavigationItems
+	jsonBlog.CodeinjectionHead = string(blog.CodeinjectionHead)
+	jsonBlog.CodeinjectionFoot = string(blog.CodeinjectionFoot)
 	return &jsonBlog
 }
 
@@ -803,6 +942,8 @@ func userToJson(user *structure.User) *JsonUser {
 	jsonUser.Bio = string(user.Bio)
 	jsonUser.Website = string(user.Website)
 	jsonUser.Location = string(user.Location)
+	jsonUser.Role = user.Role
+	jsonUser.Status = user.Status
 	return &jsonUser
 }
 
@@ -811,6 +952,12 @@ func InitializeAdmin(router *httptreemux.TreeMux) {
 	router.GET("/admin/", adminHandler)
 	router.GET("/admin/login/", getLoginHandler)
 	router.POST("/admin/login/", postLoginHandler)
+	router.GET("/admin/login/2fa/", getLoginTwoFactorHandler)
+	router.GET("/admin/login/forgot/", getForgotPasswordHandler)
+	router.POST("/admin/login/forgot/", postForgotPasswordHandler)
+	router.GET("/admin/login/reset/", getResetPasswordHandler)
+	router.POST("/admin/login/reset/", postResetPasswordHandler)
+	router.POST("/admin/login/2fa/", postLoginTwoFactorHandler)
 	router.GET("/admin/register/", getRegistrationHandler)
 	router.POST("/admin/register/", postRegistrationHandler)
 	router.GET("/admin/logout/", logoutHandler)
@@ -824,11 +971,25 @@ func InitializeAdmin(router *httptreemux.TreeMux) {
 	router.POST("/admin/api/post", postApiPostHandler)
 	router.PATCH("/admin/api/post", patchApiPostHandler)
 	router.DELETE("/admin/api/post/:id", deleteApiPostHandler)
+	// Post revisions
+	router.GET("/admin/api/post/:id/revisions", getApiPostRevisionsHandler)
+	router.GET("/admin/api/post/:id/revisions/diff", getApiPostRevisionsDiffHandler)
+	router.GET("/admin/api/post/:id/revisions/:revision", getApiPostRevisionHandler)
+	router.POST("/admin/api/post/:id/revisions/:revision/restore", postApiPostRevisionRestoreHandler)
 	// Upload
 	router.POST("/admin/api/upload", apiUploadHandler)
 	// Images
 	router.GET("/admin/api/images/:number", apiImagesHandler)
 	router.DELETE("/admin/api/image", deleteApiImageHandler)
+	// Comments
+	router.GET("/admin/api/comments/:number", getApiCommentsHandler)
+	router.PATCH("/admin/api/comment/:id", patchApiCommentHandler)
+	router.DELETE("/admin/api/comment/:id", deleteApiCommentHandler)
+	// Media library
+	router.GET("/admin/api/media/:number", getApiMediaHandler)
+	router.GET("/admin/api/image/:id", getApiImageHandler)
+	router.PATCH("/admin/api/image/:id", patchApiImageHandler)
+	router.DELETE("/admin/api/image/:id", deleteApiImageByIdHandler)
 	// Blog
 	router.GET("/admin/api/blog", getApiBlogHandler)
 	router.PATCH("/admin/api/blog", patchApiBlogHandler)
@@ -837,4 +998,35 @@ func InitializeAdmin(router *httptreemux.TreeMux) {
 	router.PATCH("/admin/api/user", patchApiUserHandler)
 	// User id
 	router.GET("/admin/api/userid", getApiUserIdHandler)
+	// Users
+	router.GET("/admin/api/users", getApiUsersHandler)
+	router.POST("/admin/api/users", postApiUsersHandler)
+	router.POST("/admin/api/users/invite", postApiUsersInviteHandler)
+	router.PATCH("/admin/api/users/:id", patchApiUsersHandler)
+	router.DELETE("/admin/api/users/:id", deleteApiUsersHandler)
+	// Content api keys
+	router.GET("/admin/api/contentkeys", getApiContentKeysHandler)
+	router.POST("/admin/api/contentkeys", postApiContentKeysHandler)
+	router.DELETE("/admin/api/contentkeys/:id", deleteApiContentKeysHandler)
+	// Webhooks
+	router.GET("/admin/api/webhooks", getApiWebhooksHandler)
+	router.POST("/admin/api/webhooks", postApiWebhooksHandler)
+	router.DELETE("/admin/api/webhooks/:id", deleteApiWebhooksHandler)
+	router.GET("/admin/api/webhooks/:id/deliveries/:number", getApiWebhookDeliveriesHandler)
+	// Login audit log
+	router.GET("/admin/api/loginattempts/:number", getApiLoginAttemptsHandler)
+	// Two-factor authentication
+	router.GET("/admin/api/2fa", getApiTwoFactorHandler)
+	router.POST("/admin/api/2fa/setup", postApiTwoFactorSetupHandler)
+	router.POST("/admin/api/2fa/confirm", postApiTwoFactorConfirmHandler)
+	router.POST("/admin/api/2fa/recoverycodes", postApiRecoveryCodesHandler)
+	router.DELETE("/admin/api/2fa", deleteApiTwoFactorHandler)
+	router.DELETE("/admin/api/users/:id/2fa", deleteApiUsersTwoFactorHandler)
+	// Sessions
+	router.GET("/admin/api/sessions", getApiSessionsHandler)
+	router.DELETE("/admin/api/sessions", deleteApiSessionsHandler)
+	// Admin api tokens
+	router.GET("/admin/api/tokens", getApiTokensHandler)
+	router.POST("/admin/api/tokens", postApiTokensHandler)
+	router.DELETE("/admin/api/tokens/:id", deleteApiTokensHandler)
 }
@@ -3,12 +3,15 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strconv"
 
 	"github.com/dimfeld/httptreemux"
 	"github.com/kabukky/journey/database"
 	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/storage"
 	"github.com/kabukky/journey/structure/methods"
 	"github.com/kabukky/journey/templates"
 )
@@ -50,9 +53,9 @@ func authorHandler(w http.ResponseWriter, r *http.Request, params map[string]str
 			return
 		}
 		return
-	} else if function == "rss" {
-		// Render author rss feed
-		err := templates.ShowAuthorRss(w, slug)
+	} else if function == templates.FeedRss || function == templates.FeedAtom {
+		// Render author rss or atom feed
+		err := templates.ShowAuthorFeed(w, r, slug, function)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -85,9 +88,9 @@ func tagHandler(w http.ResponseWriter, r *http.Request, params map[string]string
 			return
 		}
 		return
-	} else if function == "rss" {
-		// Render tag rss feed
-		err := templates.ShowTagRss(w, slug)
+	} else if function == templates.FeedRss || function == templates.FeedAtom {
+		// Render tag rss or atom feed
+		err := templates.ShowTagFeed(w, r, slug, function)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -108,14 +111,44 @@ func tagHandler(w http.ResponseWriter, r *http.Request, params map[string]string
 	return
 }
 
+func searchHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !database.SearchIsAvailable() {
+		http.Error(w, database.ErrSearchNotAvailable.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	query := r.FormValue("q")
+	number := params["number"]
+	if number == "" {
+		// Render search template (first page)
+		err := templates.ShowSearchTemplate(w, r, query, 1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+	page, err := strconv.Atoi(number)
+	if err != nil || page <= 1 {
+		http.Redirect(w, r, "/search/?q="+url.QueryEscape(query), http.StatusFound)
+		return
+	}
+	// Render search template
+	err = templates.ShowSearchTemplate(w, r, query, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	return
+}
+
 func postHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	slug := params["slug"]
 	if slug == "" {
 		http.Redirect(w, r, "/", http.StatusFound)
 		return
-	} else if slug == "rss" {
-		// Render index rss feed
-		err := templates.ShowIndexRss(w)
+	} else if slug == templates.FeedRss || slug == templates.FeedAtom {
+		// Render index rss or atom feed
+		err := templates.ShowIndexFeed(w, r, slug)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -132,6 +165,33 @@ func postHandler(w http.ResponseWriter, r *http.Request, params map[string]strin
 	return
 }
 
+func indexJsonFeedHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	err := templates.ShowIndexFeed(w, r, templates.FeedJson)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	return
+}
+
+func authorJsonFeedHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	err := templates.ShowAuthorFeed(w, r, params["slug"], templates.FeedJson)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	return
+}
+
+func tagJsonFeedHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	err := templates.ShowTagFeed(w, r, params["slug"], templates.FeedJson)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	return
+}
+
 func postEditHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	slug := params["slug"]
 
@@ -158,8 +218,23 @@ func assetsHandler(w http.ResponseWriter, r *http.Request, params map[string]str
 	return
 }
 
+// Serves uploaded files from the storage backend or redirects to the CDN if one is configured
 func imagesHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	http.ServeFile(w, r, filepath.Join(filenames.ImagesFilepath, params["filepath"]))
+	name := params["filepath"]
+	if cdnUrl := storage.CdnUrl(name); cdnUrl != "" {
+		http.Redirect(w, r, cdnUrl, http.StatusFound)
+		return
+	}
+	reader, file, err := storage.Open(name)
+	if err == storage.ErrNotFound || err == storage.ErrInvalidPath {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+	http.ServeContent(w, r, path.Base(name), file.ModTime, reader)
 	return
 }
 
@@ -173,15 +248,39 @@ func InitializeBlog(router *httptreemux.TreeMux) {
 	router.GET("/", indexHandler)
 	router.GET("/:slug/edit", postEditHandler)
 	router.GET("/:slug/", postHandler)
+	router.POST("/:slug/comments/", postCommentHandler)
 	router.GET("/page/:number/", indexHandler)
+	router.GET("/feed.json", indexJsonFeedHandler)
 	// For author
 	router.GET("/author/:slug/", authorHandler)
 	router.GET("/author/:slug/:function/", authorHandler)
 	router.GET("/author/:slug/:function/:number/", authorHandler)
+	router.GET("/author/:slug/feed.json", authorJsonFeedHandler)
 	// For tag
 	router.GET("/tag/:slug/", tagHandler)
 	router.GET("/tag/:slug/:function/", tagHandler)
 	router.GET("/tag/:slug/:function/:number/", tagHandler)
+	router.GET("/tag/:slug/feed.json", tagJsonFeedHandler)
+	// For search
+	router.GET("/search/", searchHandler)
+	router.GET("/search/page/:number/", searchHandler)
+	// For sitemaps
+	router.GET("/sitemap.xml", sitemapIndexHandler)
+	router.GET("/sitemap-posts.xml", sitemapPostsHandler)
+	router.GET("/sitemap-pages.xml", sitemapPagesHandler)
+	router.GET("/sitemap-tags.xml", sitemapTagsHandler)
+	router.GET("/sitemap-authors.xml", sitemapAuthorsHandler)
+	// For the content api
+	for _, resource := range []struct {
+		name    string
+		handler httptreemux.HandlerFunc
+	}{{"posts", contentApiPostsHandler}, {"pages", contentApiPagesHandler}, {"tags", contentApiTagsHandler}, {"authors", contentApiAuthorsHandler}} {
+		router.GET(contentApiPath+"/"+resource.name+"/", resource.handler)
+		router.GET(contentApiPath+"/"+resource.name+"/:id/", resource.handler)
+		router.GET(contentApiPath+"/"+resource.name+"/slug/:slug/", resource.handler)
+	}
+	router.GET(contentApiPath+"/settings/", contentApiSettingsHandler)
+	router.OPTIONS(contentApiPath+"/*path", contentApiOptionsHandler)
 	// For serving asset files
 	router.GET("/assets/*filepath", assetsHandler)
 	router.GET("/images/*filepath", imagesHandler)
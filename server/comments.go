@@ -0,0 +1,292 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kabukky/journey/authentication"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	journeyMail "github.com/kabukky/journey/mail"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/structure/methods"
+)
+
+// Hidden field of the comment form. People can't see it, so a comment with a value in it has been written by a bot.
+const commentHoneypotField = "phone"
+
+// Number of comments that can be written from one ip address in commentRateInterval
+const (
+	commentRateLimit    = 5
+	commentRateInterval = 10 * time.Minute
+)
+
+// Maximum length (in characters) of the fields of a comment
+const (
+	maxCommentNameLength    = 150
+	maxCommentEmailLength   = 254
+	maxCommentUrlLength     = 2000
+	maxCommentContentLength = 10000
+)
+
+type JsonComment struct {
+	Id          int64
+	PostId      int64
+	ParentId    int64
+	AuthorName  string
+	AuthorEmail string
+	AuthorUrl   string
+	Content     string
+	Status      string
+	Ip          string
+	UserAgent   string
+	CreatedAt   *time.Time
+}
+
+type JsonCommentStatus struct {
+	Status string
+}
+
+// Function to receive the comment form of a post (fields: name, email, url, content, and parent_id for replies).
+// New comments wait for approval in the moderation queue.
+func postCommentHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	post, err := database.RetrievePostBySlug(params["slug"])
+	if err != nil || !post.IsPublished {
+		http.NotFound(w, r)
+		return
+	}
+	// Pretend that the comment has been saved, so that the bot doesn't try again
+	if r.FormValue(commentHoneypotField) != "" {
+		log.Println("Discarded spam comment on post " + post.Slug + " (honeypot)")
+		commentSaved(w, r, post, 0)
+		return
+	}
+	ip := []byte(authentication.RemoteIp(r))
+	currentTime := date.GetCurrentTime()
+	count, err := database.RetrieveNumberOfCommentsByIp(ip, currentTime.Add(-commentRateInterval))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if count >= commentRateLimit {
+		http.Error(w, "Too many comments. Please try again in a few minutes.", http.StatusTooManyRequests)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	email := strings.TrimSpace(r.FormValue("email"))
+	website := strings.TrimSpace(r.FormValue("url"))
+	content := strings.TrimSpace(r.FormValue("content"))
+	if name == "" || utf8.RuneCountInString(name) > maxCommentNameLength {
+		http.Error(w, "Please enter your name.", http.StatusBadRequest)
+		return
+	}
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > maxCommentEmailLength {
+		http.Error(w, "Please enter a valid email address.", http.StatusBadRequest)
+		return
+	}
+	if website != "" && ((!strings.HasPrefix(website, "http://") && !strings.HasPrefix(website, "https://")) || len(website) > maxCommentUrlLength) {
+		http.Error(w, "The website must be an http or https url.", http.StatusBadRequest)
+		return
+	}
+	if content == "" || utf8.RuneCountInString(content) > maxCommentContentLength {
+		http.Error(w, "Please enter a comment (up to "+strconv.Itoa(maxCommentContentLength)+" characters).", http.StatusBadRequest)
+		return
+	}
+	// Replies are only possible to approved comments of the same post
+	parentId := int64(0)
+	if value := r.FormValue("parent_id"); value != "" && value != "0" {
+		parentId, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			http.Error(w, "Not a valid comment to reply to.", http.StatusBadRequest)
+			return
+		}
+		parent, err := database.RetrieveCommentById(parentId)
+		if err != nil || parent.PostId != post.Id || parent.Status != structure.CommentApproved {
+			http.Error(w, "Not a valid comment to reply to.", http.StatusBadRequest)
+			return
+		}
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > 254 {
+		userAgent = userAgent[:254]
+	}
+	commentId, err := database.InsertComment(post.Id, parentId, []byte(name), []byte(email), []byte(website), []byte(content), structure.CommentPending, ip, []byte(userAgent), currentTime)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	go notifyAboutComment(post, name, content)
+	commentSaved(w, r, post, commentId)
+	return
+}
+
+// Answers with the new comment as json if the form has been sent by a script and redirects back to the post otherwise
+func commentSaved(w http.ResponseWriter, r *http.Request, post *structure.Post, commentId int64) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		json, err := json.Marshal(JsonComment{Id: commentId, PostId: post.Id, Status: structure.CommentPending})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(json)
+		return
+	}
+	http.Redirect(w, r, "/"+post.Slug+"/#comments", http.StatusSeeOther)
+}
+
+// Sends a mail about a new comment to the author of the post
+func notifyAboutComment(post *structure.Post, name string, content string) {
+	if !journeyMail.IsAvailable() || post.Author == nil || len(post.Author.Email) == 0 {
+		return
+	}
+	methods.Blog.RLock()
+	title := string(methods.Blog.Title)
+	methods.Blog.RUnlock()
+	body := "Hello " + string(post.Author.Name) + ",\n\n" +
+		name + " wrote a comment on \"" + string(post.Title) + "\":\n\n" +
+		content + "\n\n" +
+		"The comment is waiting for approval. You can moderate it in the admin area: " + adminUrl() + "/admin/\n"
+	err := journeyMail.Send(&journeyMail.Message{To: string(post.Author.Email), Subject: "New comment on " + string(post.Title) + " (" + title + ")", Body: body})
+	if err != nil {
+		log.Println("Couldn't send comment notification:", err)
+	}
+}
+
+// API function to get the comments of all posts by pages (newest first). Use ?status=pending (or approved, or spam) to
+// only get the comments with that status. Authors only get the comments on their own posts.
+func getApiCommentsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		page, err := strconv.Atoi(params["number"])
+		if err != nil || page < 1 {
+			http.Error(w, "Wrong page number.", http.StatusInternalServerError)
+			return
+		}
+		status := r.FormValue("status")
+		if status != "" && !isCommentStatus(status) {
+			http.Error(w, "Not a valid comment status.", http.StatusBadRequest)
+			return
+		}
+		commentsPerPage := int64(50)
+		var comments []structure.Comment
+		if isEditor(user) {
+			comments, err = database.RetrieveComments(status, commentsPerPage, ((int64(page) - 1) * commentsPerPage))
+		} else {
+			comments, err = database.RetrieveCommentsForAuthor(user.Id, status, commentsPerPage, ((int64(page) - 1) * commentsPerPage))
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonComments := make([]JsonComment, len(comments))
+		for index, _ := range comments {
+			jsonComments[index] = commentToJson(&comments[index])
+		}
+		json, err := json.Marshal(jsonComments)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to approve a comment, mark it as spam, or put it back into the moderation queue
+func patchApiCommentHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		comment, ok := retrieveModeratedComment(w, user, params)
+		if !ok {
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var json JsonCommentStatus
+		err := decoder.Decode(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !isCommentStatus(json.Status) {
+			http.Error(w, "Not a valid comment status.", http.StatusBadRequest)
+			return
+		}
+		err = database.UpdateCommentStatus(comment.Id, json.Status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Comment updated!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a comment. Its replies are kept.
+func deleteApiCommentHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		comment, ok := retrieveModeratedComment(w, user, params)
+		if !ok {
+			return
+		}
+		err := database.DeleteCommentById(comment.Id, comment.ParentId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Comment deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Retrieves the comment with the id in params if the user may moderate the comments of its post
+func retrieveModeratedComment(w http.ResponseWriter, user *structure.User, params map[string]string) (*structure.Comment, bool) {
+	id, err := strconv.ParseInt(params["id"], 10, 64)
+	if err != nil || id < 1 {
+		http.Error(w, "Not a valid id.", http.StatusInternalServerError)
+		return nil, false
+	}
+	comment, err := database.RetrieveCommentById(id)
+	if err != nil {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return nil, false
+	}
+	post, err := database.RetrievePostById(comment.PostId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !canEditPost(user, post) {
+		http.Error(w, "You don't have permission to moderate the comments of this post.", http.StatusForbidden)
+		return nil, false
+	}
+	return comment, true
+}
+
+func isCommentStatus(status string) bool {
+	return status == structure.CommentPending || status == structure.CommentApproved || status == structure.CommentSpam
+}
+
+func commentToJson(comment *structure.Comment) JsonComment {
+	return JsonComment{Id: comment.Id, PostId: comment.PostId, ParentId: comment.ParentId, AuthorName: string(comment.AuthorName), AuthorEmail: string(comment.AuthorEmail), AuthorUrl: string(comment.AuthorUrl), Content: string(comment.Content), Status: comment.Status, Ip: string(comment.Ip), UserAgent: string(comment.UserAgent), CreatedAt: comment.CreatedAt}
+}
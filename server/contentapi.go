@@ -0,0 +1,483 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kabukky/journey/conversion"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/nql"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/structure/methods"
+)
+
+// Read-only api that is compatible with the Ghost Content API (v3). Requests are authenticated with a content key (?key=).
+const contentApiPath = "/ghost/api/v3/content"
+
+const contentApiDefaultLimit = 15
+
+// Ghost computes the reading time with 275 words per minute
+const wordsPerMinute = 275
+
+// Length of the excerpt in characters
+const excerptLength = 500
+
+// Parameters that are shared by all browse requests
+type contentApiQuery struct {
+	filter  nql.Expression
+	include map[string]bool
+	fields  []string
+	formats map[string]bool
+	limit   int // 0 = all
+	page    int
+}
+
+func contentApiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	contentApiPostsOrPages(w, r, params, false)
+}
+
+func contentApiPagesHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	contentApiPostsOrPages(w, r, params, true)
+}
+
+func contentApiPostsOrPages(w http.ResponseWriter, r *http.Request, params map[string]string, pages bool) {
+	if !checkContentKey(w, r) {
+		return
+	}
+	name := "posts"
+	if pages {
+		name = "pages"
+	}
+	query, err := parseContentApiQuery(r)
+	if err != nil {
+		writeContentApiError(w, http.StatusBadRequest, "BadRequestError", err.Error())
+		return
+	}
+	filter, limit, offset := contentApiSelection(query, params)
+	posts, err := database.RetrievePostsByFilter(filter, "", pages, limit, offset)
+	if err != nil {
+		writeContentApiRetrievalError(w, err, params)
+		return
+	}
+	total := int64(len(posts))
+	if !isContentApiSingle(params) {
+		total, err = database.CountPostsByFilter(filter, pages)
+		if err != nil {
+			writeContentApiRetrievalError(w, err, params)
+			return
+		}
+	}
+	// Read lock the global blog
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	resources := make([]map[string]interface{}, len(posts))
+	for index, _ := range posts {
+		resources[index] = postToContentApi(&posts[index], query)
+	}
+	writeContentApiResources(w, name, resources, total, query, params)
+}
+
+func contentApiTagsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !checkContentKey(w, r) {
+		return
+	}
+	query, err := parseContentApiQuery(r)
+	if err != nil {
+		writeContentApiError(w, http.StatusBadRequest, "BadRequestError", err.Error())
+		return
+	}
+	filter, limit, offset := contentApiSelection(query, params)
+	tags, err := database.RetrieveTagsByFilter(filter, "", limit, offset)
+	if err != nil {
+		writeContentApiRetrievalError(w, err, params)
+		return
+	}
+	total := int64(len(tags))
+	if !isContentApiSingle(params) {
+		total, err = database.CountTagsByFilter(filter)
+		if err != nil {
+			writeContentApiRetrievalError(w, err, params)
+			return
+		}
+	}
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	resources := make([]map[string]interface{}, len(tags))
+	for index, _ := range tags {
+		resources[index] = tagToContentApi(&tags[index])
+		if query.include["count.posts"] {
+			resources[index]["count"] = map[string]int64{"posts": tags[index].PostCount}
+		}
+	}
+	writeContentApiResources(w, "tags", resources, total, query, params)
+}
+
+func contentApiAuthorsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !checkContentKey(w, r) {
+		return
+	}
+	query, err := parseContentApiQuery(r)
+	if err != nil {
+		writeContentApiError(w, http.StatusBadRequest, "BadRequestError", err.Error())
+		return
+	}
+	// Suspended users are not public (see database.RetrieveAuthorsByFilter)
+	filter, limit, offset := contentApiSelection(query, params)
+	users, err := database.RetrieveAuthorsByFilter(filter, "", limit, offset)
+	if err != nil {
+		writeContentApiRetrievalError(w, err, params)
+		return
+	}
+	total := int64(len(users))
+	if !isContentApiSingle(params) {
+		total, err = database.CountAuthorsByFilter(filter)
+		if err != nil {
+			writeContentApiRetrievalError(w, err, params)
+			return
+		}
+	}
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	resources := make([]map[string]interface{}, len(users))
+	for index, _ := range users {
+		resources[index] = authorToContentApi(&users[index])
+		if query.include["count.posts"] {
+			resources[index]["count"] = map[string]int64{"posts": users[index].PostCount}
+		}
+	}
+	writeContentApiResources(w, "authors", resources, total, query, params)
+}
+
+func contentApiSettingsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if !checkContentKey(w, r) {
+		return
+	}
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	navigation := make([]structure.Navigation, len(methods.Blog.NavigationItems))
+	copy(navigation, methods.Blog.NavigationItems)
+	settings := map[string]interface{}{
+		"title":                string(methods.Blog.Title),
+		"description":          string(methods.Blog.Description),
+		"logo":                 absoluteContentApiUrl(methods.Blog.Logo),
+		"icon":                 nil,
+		"cover_image":          absoluteContentApiUrl(methods.Blog.Cover),
+		"facebook":             nil,
+		"twitter":              nil,
+		"lang":                 "en",
+		"timezone":             "Etc/UTC",
+		"navigation":           navigation,
+		"secondary_navigation": []structure.Navigation{},
+		"meta_title":           nil,
+		"meta_description":     nil,
+		"codeinjection_head":   nullableString(methods.Blog.CodeinjectionHead),
+		"codeinjection_foot":   nullableString(methods.Blog.CodeinjectionFoot),
+		"url":                  string(methods.Blog.Url) + "/",
+	}
+	writeContentApiJson(w, map[string]interface{}{"settings": settings, "meta": map[string]interface{}{}})
+}
+
+// Answers CORS preflight requests. Headless frontends call the api from other origins.
+func contentApiOptionsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Accept-Version, Content-Type")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func checkContentKey(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	secret := r.FormValue("key")
+	if secret == "" {
+		writeContentApiError(w, http.StatusUnauthorized, "UnauthorizedError", "Authorization failed: no content api key provided.")
+		return false
+	}
+	if _, err := database.RetrieveContentKeyBySecret(secret); err != nil {
+		writeContentApiError(w, http.StatusUnauthorized, "UnauthorizedError", "Unknown Content API Key.")
+		return false
+	}
+	return true
+}
+
+func parseContentApiQuery(r *http.Request) (*contentApiQuery, error) {
+	var err error
+	query := contentApiQuery{include: make(map[string]bool), formats: map[string]bool{"html": true}, limit: contentApiDefaultLimit, page: 1}
+	query.filter, err = nql.Parse(r.FormValue("filter"))
+	if err != nil {
+		return nil, err
+	}
+	for _, include := range splitContentApiList(r.FormValue("include")) {
+		query.include[include] = true
+	}
+	query.fields = splitContentApiList(r.FormValue("fields"))
+	if formats := splitContentApiList(r.FormValue("formats")); len(formats) != 0 {
+		query.formats = make(map[string]bool)
+		for _, format := range formats {
+			query.formats[format] = true
+		}
+	}
+	if limit := r.FormValue("limit"); limit == "all" {
+		query.limit = 0
+	} else if limit != "" {
+		query.limit, err = strconv.Atoi(limit)
+		if err != nil || query.limit < 1 {
+			return nil, &contentApiParameterError{"limit"}
+		}
+	}
+	if page := r.FormValue("page"); page != "" {
+		query.page, err = strconv.Atoi(page)
+		if err != nil || query.page < 1 {
+			return nil, &contentApiParameterError{"page"}
+		}
+	}
+	return &query, nil
+}
+
+type contentApiParameterError struct {
+	parameter string
+}
+
+func (e *contentApiParameterError) Error() string {
+	return "Validation error, cannot read " + e.parameter + "."
+}
+
+func splitContentApiList(input string) []string {
+	output := make([]string, 0)
+	for _, item := range strings.Split(input, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			output = append(output, item)
+		}
+	}
+	return output
+}
+
+// Returns the filter, limit, and offset of a request. Single resources (e.g. /posts/slug/:slug/) are looked up by their id or slug.
+func contentApiSelection(query *contentApiQuery, params map[string]string) (nql.Expression, int64, int64) {
+	if params["id"] != "" {
+		return nql.Comparison{Field: "id", Operator: nql.OperatorEqual, Values: []string{params["id"]}}, 1, 0
+	} else if params["slug"] != "" {
+		return nql.Comparison{Field: "slug", Operator: nql.OperatorEqual, Values: []string{params["slug"]}}, 1, 0
+	}
+	return query.filter, int64(query.limit), int64((query.page - 1) * query.limit)
+}
+
+func isContentApiSingle(params map[string]string) bool {
+	return params["id"] != "" || params["slug"] != ""
+}
+
+// Filters that can't be translated (e.g. unknown fields) are bad requests. A single resource with an invalid id doesn't exist.
+func writeContentApiRetrievalError(w http.ResponseWriter, err error, params map[string]string) {
+	if _, ok := err.(*database.FilterError); !ok {
+		writeContentApiError(w, http.StatusInternalServerError, "InternalServerError", err.Error())
+	} else if isContentApiSingle(params) {
+		writeContentApiError(w, http.StatusNotFound, "NotFoundError", "Resource not found.")
+	} else {
+		writeContentApiError(w, http.StatusBadRequest, "BadRequestError", err.Error())
+	}
+}
+
+func writeContentApiResources(w http.ResponseWriter, name string, resources []map[string]interface{}, total int64, query *contentApiQuery, params map[string]string) {
+	if isContentApiSingle(params) {
+		writeContentApiSingle(w, name, resources, query)
+		return
+	}
+	writeContentApiList(w, name, resources, total, query)
+}
+
+// The resources are the requested page already, total is the number of all resources that match the filter
+func writeContentApiList(w http.ResponseWriter, name string, resources []map[string]interface{}, total int64, query *contentApiQuery) {
+	pages := 1
+	if query.limit > 0 && total > 0 {
+		pages = int((total + int64(query.limit) - 1) / int64(query.limit))
+	}
+	output := make([]map[string]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		output = append(output, selectContentApiFields(resource, query.fields))
+	}
+	pagination := map[string]interface{}{"page": query.page, "limit": query.limit, "pages": pages, "total": total, "next": nil, "prev": nil}
+	if query.limit == 0 {
+		pagination["limit"] = "all"
+	}
+	if query.page < pages {
+		pagination["next"] = query.page + 1
+	}
+	if query.page > 1 {
+		pagination["prev"] = query.page - 1
+	}
+	writeContentApiJson(w, map[string]interface{}{name: output, "meta": map[string]interface{}{"pagination": pagination}})
+}
+
+// Single resources are returned in an array as well (like Ghost does)
+func writeContentApiSingle(w http.ResponseWriter, name string, resources []map[string]interface{}, query *contentApiQuery) {
+	if len(resources) == 0 {
+		writeContentApiError(w, http.StatusNotFound, "NotFoundError", "Resource not found.")
+		return
+	}
+	writeContentApiJson(w, map[string]interface{}{name: []map[string]interface{}{selectContentApiFields(resources[0], query.fields)}})
+}
+
+func writeContentApiJson(w http.ResponseWriter, output interface{}) {
+	json, err := json.Marshal(output)
+	if err != nil {
+		writeContentApiError(w, http.StatusInternalServerError, "InternalServerError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(json)
+}
+
+func writeContentApiError(w http.ResponseWriter, status int, errorType string, message string) {
+	output := map[string]interface{}{"errors": []map[string]string{map[string]string{"message": message, "type": errorType}}}
+	json, _ := json.Marshal(output)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(json)
+}
+
+func selectContentApiFields(resource map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return resource
+	}
+	output := make(map[string]interface{})
+	for _, field := range fields {
+		if value, ok := resource[field]; ok {
+			output[field] = value
+		}
+	}
+	return output
+}
+
+func postToContentApi(post *structure.Post, query *contentApiQuery) map[string]interface{} {
+	id := strconv.FormatInt(post.Id, 10)
+	plaintext := string(conversion.StripTagsFromHtml(post.Html))
+	output := map[string]interface{}{
+		"id":                 id,
+		"uuid":               string(post.Uuid),
+		"title":              string(post.Title),
+		"slug":               post.Slug,
+		"comment_id":         id,
+		"feature_image":      absoluteContentApiUrl(post.Image),
+		"featured":           post.IsFeatured,
+		"visibility":         "public",
+		"created_at":         formatContentApiDate(post.Date),
+		"updated_at":         formatContentApiDate(post.UpdatedAt),
+		"published_at":       formatContentApiDate(post.Date),
+		"custom_excerpt":     nil,
+		"codeinjection_head": nullableString(post.CodeinjectionHead),
+		"codeinjection_foot": nullableString(post.CodeinjectionFoot),
+		"custom_template":    nil,
+		"canonical_url":      nil,
+		"url":                string(methods.Blog.Url) + "/" + post.Slug + "/",
+		"excerpt":            excerpt(plaintext),
+		"reading_time":       readingTime(plaintext),
+		"meta_title":         nil,
+		"meta_description":   nullableString(post.MetaDescription),
+	}
+	if query.formats["html"] {
+		output["html"] = string(post.Html)
+	}
+	if query.formats["plaintext"] {
+		output["plaintext"] = plaintext
+	}
+	// Tags
+	if query.include["tags"] {
+		tags := make([]map[string]interface{}, len(post.Tags))
+		for index, _ := range post.Tags {
+			tags[index] = tagToContentApi(&post.Tags[index])
+		}
+		output["tags"] = tags
+		if len(tags) != 0 {
+			output["primary_tag"] = tags[0]
+		} else {
+			output["primary_tag"] = nil
+		}
+	}
+	// Authors (a post has exactly one author in Journey)
+	if post.Author != nil && query.include["authors"] {
+		author := authorToContentApi(post.Author)
+		output["authors"] = []map[string]interface{}{author}
+		output["primary_author"] = author
+	}
+	return output
+}
+
+func tagToContentApi(tag *structure.Tag) map[string]interface{} {
+	id := strconv.FormatInt(tag.Id, 10)
+	output := map[string]interface{}{
+		"id":               id,
+		"name":             string(tag.Name),
+		"slug":             tag.Slug,
+		"description":      nil,
+		"feature_image":    nil,
+		"visibility":       "public",
+		"meta_title":       nil,
+		"meta_description": nil,
+		"url":              string(methods.Blog.Url) + "/tag/" + tag.Slug + "/",
+	}
+	return output
+}
+
+func authorToContentApi(user *structure.User) map[string]interface{} {
+	id := strconv.FormatInt(user.Id, 10)
+	output := map[string]interface{}{
+		"id":               id,
+		"name":             string(user.Name),
+		"slug":             user.Slug,
+		"profile_image":    absoluteContentApiUrl(user.Image),
+		"cover_image":      absoluteContentApiUrl(user.Cover),
+		"bio":              nullableString(user.Bio),
+		"website":          nullableString(user.Website),
+		"location":         nullableString(user.Location),
+		"facebook":         nil,
+		"twitter":          nil,
+		"meta_title":       nil,
+		"meta_description": nil,
+		"url":              string(methods.Blog.Url) + "/author/" + user.Slug + "/",
+	}
+	return output
+}
+
+// Images are saved as paths (e.g. /images/2015/01/image.jpg). The api returns absolute urls.
+func absoluteContentApiUrl(path []byte) interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(string(path), "/") {
+		return string(methods.Blog.Url) + string(path)
+	}
+	return string(path)
+}
+
+func formatContentApiDate(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+	return date.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+func nullableString(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
+
+func excerpt(plaintext string) string {
+	if utf8.RuneCountInString(plaintext) <= excerptLength {
+		return strings.TrimSpace(plaintext)
+	}
+	return strings.TrimSpace(string([]rune(plaintext)[:excerptLength]))
+}
+
+func readingTime(plaintext string) int {
+	minutes := len(strings.Fields(plaintext)) / wordsPerMinute
+	if minutes == 0 {
+		return 1
+	}
+	return minutes
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/structure"
+)
+
+type JsonContentKey struct {
+	Id        int64
+	Name      string
+	Secret    string
+	CreatedAt *time.Time
+	CreatedBy int64
+}
+
+// API function to get all content api keys
+func getApiContentKeysHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
+			return
+		}
+		keys, err := database.RetrieveContentKeys()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonKeys := make([]JsonContentKey, len(keys))
+		for index, _ := range keys {
+			jsonKeys[index] = *contentKeyToJson(&keys[index])
+		}
+		json, err := json.Marshal(jsonKeys)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a content api key (one key per integration)
+func postApiContentKeysHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to create content api keys.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var jsonKey JsonContentKey
+		err := decoder.Decode(&jsonKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if jsonKey.Name == "" {
+			http.Error(w, "A name for the integration must be provided.", http.StatusBadRequest)
+			return
+		}
+		secret, err := generateContentKeySecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		currentTime := date.GetCurrentTime()
+		keyId, err := database.InsertContentKey([]byte(jsonKey.Name), secret, currentTime, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		key := structure.ContentKey{Id: keyId, Name: []byte(jsonKey.Name), Secret: secret, CreatedAt: &currentTime, CreatedBy: user.Id}
+		json, err := json.Marshal(contentKeyToJson(&key))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a content api key. Integrations that use the key lose access immediately.
+func deleteApiContentKeysHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to delete content api keys.", http.StatusForbidden)
+			return
+		}
+		keyId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || keyId < 1 {
+			http.Error(w, "Wrong key id.", http.StatusInternalServerError)
+			return
+		}
+		err = database.DeleteContentKeyById(keyId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Content api key deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func contentKeyToJson(key *structure.ContentKey) *JsonContentKey {
+	return &JsonContentKey{Id: key.Id, Name: string(key.Name), Secret: key.Secret, CreatedAt: key.CreatedAt, CreatedBy: key.CreatedBy}
+}
+
+// Ghost content api keys are 26 hex characters long
+func generateContentKeySecret() (string, error) {
+	secret := make([]byte, 13)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kabukky/journey/configuration"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/export"
+	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/storage"
+	"github.com/kabukky/journey/structure/methods"
+	"github.com/kabukky/journey/templates"
+)
+
+// Collects the output of a template function instead of sending it to a client
+type exportWriter struct {
+	header http.Header
+	status int
+	buffer bytes.Buffer
+}
+
+func (w *exportWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *exportWriter) Write(data []byte) (int, error) {
+	return w.buffer.Write(data)
+}
+
+func (w *exportWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Function to render the blog as static files into directory: index, posts, pages, tag and author archives (with all of their pages),
+// feeds, theme assets, images, and public files. Urls of the blog are rewritten to baseUrl (the config url if it is empty).
+func Export(directory string, baseUrl string) error {
+	if baseUrl == "" {
+		baseUrl = configuration.Config.Url
+	}
+	parsedUrl, err := url.Parse(baseUrl)
+	if err != nil || parsedUrl.Scheme == "" || parsedUrl.Host == "" {
+		return errors.New("The export url must be an absolute url like https://cdn.example.com/blog")
+	}
+	site, err := export.New(directory, baseUrl, configuration.Config.Url, configuration.Config.HttpsUrl)
+	if err != nil {
+		return err
+	}
+	methods.Blog.RLock()
+	postsPerPage := methods.Blog.PostsPerPage
+	postCount := methods.Blog.PostCount
+	activeTheme := methods.Blog.ActiveTheme
+	methods.Blog.RUnlock()
+
+	// Index
+	err = exportArchive(site, "", postCount, postsPerPage, templates.ShowIndexTemplate)
+	if err != nil {
+		return err
+	}
+	err = exportFeeds(site, "", func(w http.ResponseWriter, r *http.Request, format string) error {
+		return templates.ShowIndexFeed(w, r, format)
+	})
+	if err != nil {
+		return err
+	}
+	// Posts and pages
+	posts, err := database.RetrievePublishedPostsAndPages()
+	if err != nil {
+		return err
+	}
+	for index, _ := range posts {
+		slug := posts[index].Slug
+		err = exportPage(site, "/"+slug+"/", func(w http.ResponseWriter, r *http.Request) error {
+			return templates.ShowPostTemplate(w, r, slug)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// Tags with published posts
+	tags, err := database.RetrieveAllTags()
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		count, err := database.RetrieveNumberOfPostsByTag(tag.Id)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		slug := tag.Slug
+		err = exportArchive(site, "/tag/"+slug, count, postsPerPage, func(w http.ResponseWriter, r *http.Request, page int) error {
+			return templates.ShowTagTemplate(w, r, slug, page)
+		})
+		if err != nil {
+			return err
+		}
+		err = exportFeeds(site, "/tag/"+slug, func(w http.ResponseWriter, r *http.Request, format string) error {
+			return templates.ShowTagFeed(w, r, slug, format)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// Authors with published posts
+	users, err := database.RetrieveUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		count, err := database.RetrieveNumberOfPostsByUser(user.Id)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		slug := user.Slug
+		err = exportArchive(site, "/author/"+slug, count, postsPerPage, func(w http.ResponseWriter, r *http.Request, page int) error {
+			return templates.ShowAuthorTemplate(w, r, slug, page)
+		})
+		if err != nil {
+			return err
+		}
+		err = exportFeeds(site, "/author/"+slug, func(w http.ResponseWriter, r *http.Request, format string) error {
+			return templates.ShowAuthorFeed(w, r, slug, format)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// Static files
+	err = site.CopyDirectory(filepath.Join(filenames.ThemesFilepath, activeTheme, "assets"), "/assets/")
+	if err != nil {
+		return err
+	}
+	err = exportImages(site)
+	if err != nil {
+		return err
+	}
+	err = site.CopyDirectory(filenames.PublicFilepath, "/public/")
+	if err != nil {
+		return err
+	}
+	err = site.Finish()
+	if err != nil {
+		return err
+	}
+	log.Println("Exported the blog to " + directory + " (" + strconv.Itoa(site.Written) + " files written, " + strconv.Itoa(site.Unchanged) + " unchanged).")
+	return nil
+}
+
+// Exports all pages of an archive (e.g. /tag/news/ and /tag/news/page/2/)
+func exportArchive(site *export.Site, pathPrefix string, count int64, postsPerPage int64, show func(http.ResponseWriter, *http.Request, int) error) error {
+	pages := int64(1)
+	if postsPerPage > 0 && count > postsPerPage {
+		pages = (count + postsPerPage - 1) / postsPerPage
+	}
+	for page := int64(1); page <= pages; page++ {
+		urlPath := pathPrefix + "/"
+		if page > 1 {
+			urlPath = pathPrefix + "/page/" + strconv.FormatInt(page, 10) + "/"
+		}
+		pageNumber := int(page)
+		err := exportPage(site, urlPath, func(w http.ResponseWriter, r *http.Request) error {
+			return show(w, r, pageNumber)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportFeeds(site *export.Site, pathPrefix string, show func(http.ResponseWriter, *http.Request, string) error) error {
+	for _, format := range []string{templates.FeedRss, templates.FeedAtom, templates.FeedJson} {
+		feedFormat := format
+		err := exportPage(site, templates.FeedPath(pathPrefix, feedFormat), func(w http.ResponseWriter, r *http.Request) error {
+			return show(w, r, feedFormat)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportPage(site *export.Site, urlPath string, show func(http.ResponseWriter, *http.Request) error) error {
+	request, err := http.NewRequest("GET", urlPath, nil)
+	if err != nil {
+		return err
+	}
+	writer := &exportWriter{header: make(http.Header)}
+	err = show(writer, request)
+	if err != nil {
+		return errors.New("Couldn't render " + urlPath + ": " + err.Error())
+	}
+	if writer.status != 0 && writer.status != http.StatusOK {
+		return errors.New("Couldn't render " + urlPath + ": status " + strconv.Itoa(writer.status))
+	}
+	return site.WritePage(urlPath, writer.buffer.Bytes())
+}
+
+// Copies the uploaded files from the storage backend
+func exportImages(site *export.Site) error {
+	files, err := storage.List("")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Path
+		open := func() (io.ReadCloser, error) {
+			reader, _, err := storage.Open(name)
+			return reader, err
+		}
+		err = site.CopyFile("/images/"+name, file.Size, file.ModTime, open)
+		if err != nil {
+			return err
+		}
+		// Images are also served at /content/images/ to keep compatibility with Ghost
+		err = site.CopyFile("/content/images/"+name, file.Size, file.ModTime, open)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
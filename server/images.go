@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kabukky/journey/configuration"
+	"github.com/kabukky/journey/imaging"
+	"github.com/kabukky/journey/storage"
+)
+
+// Saves a processed image as relativePath (e.g. 2016/01/image.jpg) and its variants as size/w<width>/relativePath
+func saveImage(relativePath string, image *imaging.Image) error {
+	err := storage.Save(relativePath, image.Data, imaging.MimeType(image.Extension))
+	if err != nil {
+		return err
+	}
+	for _, variant := range image.Variants {
+		variantPath := strings.TrimSuffix(relativePath, path.Ext(relativePath)) + variant.Extension
+		err = storage.Save(path.Join("size", "w"+strconv.Itoa(variant.Width), variantPath), variant.Data, imaging.MimeType(variant.Extension))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deletes an uploaded image (relativePath in the storage) and all of its resized variants in the configured sizes
+func deleteImageFiles(relativePath string) error {
+	err := storage.Delete(relativePath)
+	if err != nil {
+		return err
+	}
+	for _, size := range configuration.Config.Images.Sizes {
+		// The variants may have a different file extension (WebP)
+		variants, err := storage.List(path.Join("size", "w"+strconv.Itoa(size.Width), strings.TrimSuffix(relativePath, path.Ext(relativePath))) + ".")
+		if err != nil {
+			return err
+		}
+		for _, variant := range variants {
+			err = storage.Delete(variant.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func imageErrorStatus(err error) int {
+	switch err {
+	case imaging.ErrTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case imaging.ErrUnsupportedType:
+		return http.StatusUnsupportedMediaType
+	case imaging.ErrInvalidImage:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
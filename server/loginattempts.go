@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/database"
+)
+
+type JsonLoginAttempt struct {
+	Id         int64
+	Name       string
+	Ip         string
+	UserAgent  string
+	Successful bool
+	CreatedAt  *time.Time
+}
+
+// API function to get the login audit log (newest first). Use ?failed=true to only get the failed attempts.
+func getApiLoginAttemptsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
+			return
+		}
+		page, err := strconv.Atoi(params["number"])
+		if err != nil || page < 1 {
+			http.Error(w, "Wrong page number.", http.StatusInternalServerError)
+			return
+		}
+		attemptsPerPage := int64(50)
+		attempts, err := database.RetrieveLoginAttempts(r.FormValue("failed") == "true", attemptsPerPage, ((int64(page) - 1) * attemptsPerPage))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonAttempts := make([]JsonLoginAttempt, len(attempts))
+		for index, attempt := range attempts {
+			jsonAttempts[index] = JsonLoginAttempt{Id: attempt.Id, Name: string(attempt.Name), Ip: string(attempt.Ip), UserAgent: string(attempt.UserAgent), Successful: attempt.Successful, CreatedAt: attempt.CreatedAt}
+		}
+		json, err := json.Marshal(jsonAttempts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
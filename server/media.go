@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/structure"
+)
+
+type JsonMedia struct {
+	Id         int64
+	Url        string
+	MimeType   string
+	Size       int64
+	Width      int
+	Height     int
+	Alt        string
+	Caption    string
+	UploadedBy int64
+	CreatedAt  *time.Time
+	PostIds    []int64
+}
+
+type JsonMediaDetails struct {
+	Alt     string
+	Caption string
+}
+
+// API function to get the media library by pages (newest first). Use ?unused=true to only get the media that isn't used anywhere
+// (in posts, as the blog logo or cover, or as a user image or cover).
+func getApiMediaHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		page, err := strconv.Atoi(params["number"])
+		if err != nil || page < 1 {
+			http.Error(w, "Wrong page number.", http.StatusInternalServerError)
+			return
+		}
+		mediaPerPage := int64(15)
+		media, err := database.RetrieveMedia(r.FormValue("unused") == "true", mediaPerPage, ((int64(page) - 1) * mediaPerPage))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonMedia := make([]JsonMedia, len(media))
+		for index, _ := range media {
+			medium, err := mediaToJson(&media[index])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			jsonMedia[index] = *medium
+		}
+		json, err := json.Marshal(jsonMedia)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get an image of the media library by its id
+func getApiImageHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		medium, ok := retrieveMediaFromParams(w, params)
+		if !ok {
+			return
+		}
+		jsonMedium, err := mediaToJson(medium)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(jsonMedium)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to change the alt text and caption of an image
+func patchApiImageHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		medium, ok := retrieveMediaFromParams(w, params)
+		if !ok {
+			return
+		}
+		if !isEditor(user) && medium.UploadedBy != user.Id {
+			http.Error(w, "You don't have permission to change this image.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var jsonDetails JsonMediaDetails
+		err := decoder.Decode(&jsonDetails)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = database.UpdateMediaDetails(medium.Id, []byte(jsonDetails.Alt), []byte(jsonDetails.Caption))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Image updated!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete an image of the media library (and its resized variants) by its id
+func deleteApiImageByIdHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		// Only editors and administrators may delete images (they could be used in the posts of other users)
+		if !isEditor(user) {
+			http.Error(w, "You don't have permission to delete images.", http.StatusForbidden)
+			return
+		}
+		medium, ok := retrieveMediaFromParams(w, params)
+		if !ok {
+			return
+		}
+		err := deleteMedia(medium)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Image deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func retrieveMediaFromParams(w http.ResponseWriter, params map[string]string) (*structure.Media, bool) {
+	id, err := strconv.ParseInt(params["id"], 10, 64)
+	if err != nil || id < 1 {
+		http.Error(w, "Not a valid id.", http.StatusInternalServerError)
+		return nil, false
+	}
+	medium, err := database.RetrieveMediaById(id)
+	if err != nil {
+		http.Error(w, "Image not found.", http.StatusNotFound)
+		return nil, false
+	}
+	return medium, true
+}
+
+func mediaToJson(medium *structure.Media) (*JsonMedia, error) {
+	postIds, err := database.RetrievePostIdsForMedia(medium.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &JsonMedia{Id: medium.Id, Url: medium.Path, MimeType: medium.MimeType, Size: medium.Size, Width: medium.Width, Height: medium.Height, Alt: string(medium.Alt), Caption: string(medium.Caption), UploadedBy: medium.UploadedBy, CreatedAt: medium.CreatedAt, PostIds: postIds}, nil
+}
+
+// Deletes the files of an image and removes it from the media library
+func deleteMedia(medium *structure.Media) error {
+	err := deleteImageFiles(strings.TrimPrefix(medium.Path, "/images/"))
+	if err != nil {
+		return err
+	}
+	return database.DeleteMediaById(medium.Id)
+}
+
+// Returns the path an image is stored with in the media library (/images/...) for one of its urls
+// (e.g. http://127.0.0.1:8084/content/images/2016/01/image.jpg)
+func mediaPath(imageUrl string) string {
+	parsedUrl, err := url.Parse(imageUrl)
+	if err != nil {
+		return imageUrl
+	}
+	return strings.TrimPrefix(parsedUrl.Path, "/content")
+}
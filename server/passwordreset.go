@@ -0,0 +1,138 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kabukky/journey/authentication"
+	"github.com/kabukky/journey/configuration"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/mail"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/structure/methods"
+)
+
+// Only one reset mail is sent per user in this period (so that the form can't be used to flood a mailbox)
+const passwordResetMailInterval = 5 * time.Minute
+
+var passwordResetMails = struct {
+	sync.Mutex
+	sent map[int64]time.Time
+}{sent: make(map[int64]time.Time)}
+
+// Function to serve the "forgot password" form
+func getForgotPasswordHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "forgot.html"))
+	return
+}
+
+// Function to receive the "forgot password" form (user name or email address). The response is the same whether the user exists or not.
+func postForgotPasswordHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if !mail.IsAvailable() {
+		http.Error(w, "Password reset is not available. Please ask an administrator to configure mail delivery.", http.StatusServiceUnavailable)
+		return
+	}
+	input := strings.TrimSpace(r.FormValue("name"))
+	if input != "" {
+		user, err := database.RetrieveUserWithRoleByName([]byte(input))
+		if err != nil {
+			user, err = database.RetrieveUserWithRoleByEmail([]byte(input))
+		}
+		if err == nil && user.Status != "inactive" && len(user.Email) != 0 {
+			if passwordResetMailAllowed(user.Id) {
+				err = sendPasswordResetMail(user)
+				if err != nil {
+					log.Println("Couldn't send password reset mail:", err)
+				} else {
+					log.Println("Sent password reset mail to user " + string(user.Name))
+				}
+			}
+		} else {
+			log.Println("Password reset requested for unknown user " + input)
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("If the account exists, a mail with a link to reset the password has been sent to its address."))
+	return
+}
+
+// Function to serve the form for the new password
+func getResetPasswordHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if _, err := authentication.ValidatePasswordResetToken(r.FormValue("token")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "reset.html"))
+	return
+}
+
+// Function to receive the new password. Ends all sessions of the user.
+func postResetPasswordHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user, err := authentication.ValidatePasswordResetToken(r.FormValue("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	password := r.FormValue("password")
+	if password == "" || password != r.FormValue("passwordRepeated") {
+		http.Error(w, "The passwords don't match.", http.StatusBadRequest)
+		return
+	}
+	hashedPassword, err := authentication.EncryptPassword(password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = database.UpdateUserPassword(user.Id, hashedPassword, date.GetCurrentTime(), user.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logOutUser(user.Id)
+	log.Println("Password of user " + string(user.Name) + " was reset")
+	http.Redirect(w, r, "/admin/login/", 302)
+	return
+}
+
+func passwordResetMailAllowed(userId int64) bool {
+	passwordResetMails.Lock()
+	defer passwordResetMails.Unlock()
+	currentTime := date.GetCurrentTime()
+	if sent, ok := passwordResetMails.sent[userId]; ok && currentTime.Sub(sent) < passwordResetMailInterval {
+		return false
+	}
+	passwordResetMails.sent[userId] = currentTime
+	return true
+}
+
+func sendPasswordResetMail(user *structure.User) error {
+	token, err := authentication.GeneratePasswordResetToken(user)
+	if err != nil {
+		return err
+	}
+	methods.Blog.RLock()
+	title := string(methods.Blog.Title)
+	methods.Blog.RUnlock()
+	link := adminUrl() + "/admin/login/reset/?token=" + url.QueryEscape(token)
+	body := "Hello " + string(user.Name) + ",\n\n" +
+		"someone (hopefully you) asked to reset your password for " + title + ".\n" +
+		"Open this link within the next hour to choose a new password:\n\n" +
+		link + "\n\n" +
+		"If you didn't ask for this, you can ignore this mail. Your password stays the same.\n"
+	return mail.Send(&mail.Message{To: string(user.Email), Subject: "Reset your password for " + title, Body: body})
+}
+
+// Returns the url the admin area is served at
+func adminUrl() string {
+	if configuration.Config.HttpsUsage == "AdminOnly" || configuration.Config.HttpsUsage == "All" {
+		return configuration.Config.HttpsUrl
+	}
+	return configuration.Config.Url
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/diff"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/structure/methods"
+)
+
+type JsonPostRevision struct {
+	Id        int64
+	PostId    int64
+	Title     string
+	Markdown  string
+	CreatedAt *time.Time
+	CreatedBy int64
+}
+
+type JsonPostRevisionDiff struct {
+	From      int64
+	To        int64
+	FromTitle string
+	ToTitle   string
+	Lines     []diff.Line
+}
+
+// API function to get all revisions of a post (newest first)
+func getApiPostRevisionsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		post, ok := retrieveEditablePost(w, user, params["id"])
+		if !ok {
+			return
+		}
+		revisions, err := database.RetrievePostRevisions(post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonRevisions := make([]JsonPostRevision, len(revisions))
+		for index, _ := range revisions {
+			jsonRevisions[index] = *postRevisionToJson(&revisions[index])
+		}
+		json, err := json.Marshal(jsonRevisions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get a single revision of a post
+func getApiPostRevisionHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		post, ok := retrieveEditablePost(w, user, params["id"])
+		if !ok {
+			return
+		}
+		revisionId, err := strconv.ParseInt(params["revision"], 10, 64)
+		if err != nil || revisionId < 1 {
+			http.Error(w, "Wrong revision id.", http.StatusInternalServerError)
+			return
+		}
+		revision, err := database.RetrievePostRevision(revisionId, post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(postRevisionToJson(revision))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get the line diff of the markdown of two revisions (e.g. /admin/api/post/1/revisions/diff?from=3&to=5)
+func getApiPostRevisionsDiffHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		post, ok := retrieveEditablePost(w, user, params["id"])
+		if !ok {
+			return
+		}
+		fromId, err := strconv.ParseInt(r.FormValue("from"), 10, 64)
+		if err != nil || fromId < 1 {
+			http.Error(w, "Wrong revision id in 'from'.", http.StatusBadRequest)
+			return
+		}
+		toId, err := strconv.ParseInt(r.FormValue("to"), 10, 64)
+		if err != nil || toId < 1 {
+			http.Error(w, "Wrong revision id in 'to'.", http.StatusBadRequest)
+			return
+		}
+		from, err := database.RetrievePostRevision(fromId, post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		to, err := database.RetrievePostRevision(toId, post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		revisionDiff := JsonPostRevisionDiff{From: from.Id, To: to.Id, FromTitle: string(from.Title), ToTitle: string(to.Title), Lines: diff.Lines(from.Markdown, to.Markdown)}
+		json, err := json.Marshal(revisionDiff)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to restore a post to one of its revisions
+func postApiPostRevisionRestoreHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		post, ok := retrieveEditablePost(w, user, params["id"])
+		if !ok {
+			return
+		}
+		revisionId, err := strconv.ParseInt(params["revision"], 10, 64)
+		if err != nil || revisionId < 1 {
+			http.Error(w, "Wrong revision id.", http.StatusInternalServerError)
+			return
+		}
+		err = methods.RestorePostRevision(post.Id, revisionId, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Post restored!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Retrieves the post with the given id and writes an error to w if the post doesn't exist or the user may not edit it.
+func retrieveEditablePost(w http.ResponseWriter, user *structure.User, id string) (*structure.Post, bool) {
+	postId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || postId < 1 {
+		http.Error(w, "Wrong post id.", http.StatusInternalServerError)
+		return nil, false
+	}
+	post, err := database.RetrievePostById(postId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	// Authors may only access the revisions of their own posts
+	if !canEditPost(user, post) {
+		http.Error(w, "You don't have permission to access this post.", http.StatusForbidden)
+		return nil, false
+	}
+	return post, true
+}
+
+func postRevisionToJson(revision *structure.PostRevision) *JsonPostRevision {
+	var jsonRevision JsonPostRevision
+	jsonRevision.Id = revision.Id
+	jsonRevision.PostId = revision.PostId
+	jsonRevision.Title = string(revision.Title)
+	jsonRevision.Markdown = string(revision.Markdown)
+	jsonRevision.CreatedAt = revision.CreatedAt
+	jsonRevision.CreatedBy = revision.CreatedBy
+	return &jsonRevision
+}
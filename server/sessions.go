@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kabukky/journey/authentication"
+	"github.com/kabukky/journey/database"
+)
+
+type JsonSession struct {
+	Id         int64
+	UserAgent  string
+	Ip         string
+	CreatedAt  *time.Time
+	LastSeenAt *time.Time
+	ExpiresAt  *time.Time
+	IsCurrent  bool
+}
+
+// API function to get all sessions of the logged in user
+func getApiSessionsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		sessions, err := database.RetrieveSessionsForUser(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		currentId := currentSessionId(r)
+		jsonSessions := make([]JsonSession, len(sessions))
+		for index, session := range sessions {
+			jsonSessions[index] = JsonSession{Id: session.Id, UserAgent: string(session.UserAgent), Ip: string(session.Ip), CreatedAt: session.CreatedAt, LastSeenAt: session.LastSeenAt, ExpiresAt: session.ExpiresAt, IsCurrent: session.Id == currentId}
+		}
+		json, err := json.Marshal(jsonSessions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to log out all other sessions of the logged in user (e.g. after losing a device). The current session stays logged in.
+func deleteApiSessionsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		err := database.DeleteOtherSessionsForUser(user.Id, currentSessionId(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Other sessions logged out!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Returns the id of the session of a request or 0 (e.g. if the request was authenticated with an api token)
+func currentSessionId(r *http.Request) int64 {
+	session := authentication.GetSession(r)
+	if session == nil {
+		return 0
+	}
+	return session.Id
+}
+
+// Function to end all sessions of a user (e.g. when the user is suspended)
+func logOutUser(userId int64) {
+	err := database.DeleteOtherSessionsForUser(userId, 0)
+	if err != nil {
+		log.Println("Couldn't delete sessions of a user:", err)
+	}
+}
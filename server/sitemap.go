@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/sitemap"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/structure/methods"
+)
+
+// The sitemaps that are listed in the sitemap index (/sitemap.xml)
+var sitemaps = []struct {
+	name    string
+	entries func([]structure.Post) []sitemap.Entry
+}{
+	{"sitemap-pages.xml", pageSitemapEntries},
+	{"sitemap-posts.xml", postSitemapEntries},
+	{"sitemap-authors.xml", authorSitemapEntries},
+	{"sitemap-tags.xml", tagSitemapEntries},
+}
+
+func sitemapIndexHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	serveSitemap(w, r, "sitemap.xml", func() ([]byte, error) {
+		posts, err := database.RetrievePublishedPostsAndPages()
+		if err != nil {
+			return nil, err
+		}
+		blogUrl := sitemapBlogUrl()
+		index := make([]sitemap.Entry, len(sitemaps))
+		for i, s := range sitemaps {
+			index[i] = sitemap.Entry{Location: blogUrl + "/" + s.name, LastModified: sitemap.LastModified(s.entries(posts))}
+		}
+		var buffer bytes.Buffer
+		err = sitemap.WriteIndex(&buffer, index)
+		return buffer.Bytes(), err
+	})
+}
+
+func sitemapPostsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	serveUrlSet(w, r, "sitemap-posts.xml", postSitemapEntries)
+}
+
+func sitemapPagesHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	serveUrlSet(w, r, "sitemap-pages.xml", pageSitemapEntries)
+}
+
+func sitemapTagsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	serveUrlSet(w, r, "sitemap-tags.xml", tagSitemapEntries)
+}
+
+func sitemapAuthorsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	serveUrlSet(w, r, "sitemap-authors.xml", authorSitemapEntries)
+}
+
+func serveUrlSet(w http.ResponseWriter, r *http.Request, name string, entries func([]structure.Post) []sitemap.Entry) {
+	serveSitemap(w, r, name, func() ([]byte, error) {
+		posts, err := database.RetrievePublishedPostsAndPages()
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		err = sitemap.WriteUrlSet(&buffer, entries(posts))
+		return buffer.Bytes(), err
+	})
+}
+
+// Sitemaps are generated on the first request and cached until the content of the blog changes (see methods.GenerateBlog)
+func serveSitemap(w http.ResponseWriter, r *http.Request, name string, generate func() ([]byte, error)) {
+	data, err := sitemap.Get(name, generate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(data)
+	return
+}
+
+func postSitemapEntries(posts []structure.Post) []sitemap.Entry {
+	blogUrl := sitemapBlogUrl()
+	entries := make([]sitemap.Entry, 0)
+	for index, _ := range posts {
+		if posts[index].IsPage {
+			continue
+		}
+		entries = append(entries, postSitemapEntry(blogUrl, &posts[index]))
+	}
+	return entries
+}
+
+// The index page is part of the page sitemap
+func pageSitemapEntries(posts []structure.Post) []sitemap.Entry {
+	blogUrl := sitemapBlogUrl()
+	entries := []sitemap.Entry{sitemap.Entry{Location: blogUrl + "/"}}
+	for index, _ := range posts {
+		if !posts[index].IsPage {
+			entries[0].LastModified = latest(entries[0].LastModified, postLastModified(&posts[index]))
+			continue
+		}
+		entries = append(entries, postSitemapEntry(blogUrl, &posts[index]))
+	}
+	return entries
+}
+
+// Only tags with published posts are listed. A tag was last modified when its most recent post was.
+func tagSitemapEntries(posts []structure.Post) []sitemap.Entry {
+	blogUrl := sitemapBlogUrl()
+	entries := make([]sitemap.Entry, 0)
+	positions := make(map[int64]int)
+	for index, _ := range posts {
+		if posts[index].IsPage {
+			continue
+		}
+		for _, tag := range posts[index].Tags {
+			position, ok := positions[tag.Id]
+			if !ok {
+				position = len(entries)
+				positions[tag.Id] = position
+				entries = append(entries, sitemap.Entry{Location: blogUrl + "/tag/" + tag.Slug + "/"})
+			}
+			entries[position].LastModified = latest(entries[position].LastModified, postLastModified(&posts[index]))
+		}
+	}
+	return entries
+}
+
+// Only authors with published posts are listed
+func authorSitemapEntries(posts []structure.Post) []sitemap.Entry {
+	blogUrl := sitemapBlogUrl()
+	entries := make([]sitemap.Entry, 0)
+	positions := make(map[int64]int)
+	for index, _ := range posts {
+		author := posts[index].Author
+		if posts[index].IsPage || author == nil {
+			continue
+		}
+		position, ok := positions[author.Id]
+		if !ok {
+			position = len(entries)
+			positions[author.Id] = position
+			entry := sitemap.Entry{Location: blogUrl + "/author/" + author.Slug + "/"}
+			if len(author.Image) != 0 {
+				entry.Images = []string{absoluteSitemapUrl(blogUrl, string(author.Image))}
+			}
+			entries = append(entries, entry)
+		}
+		entries[position].LastModified = latest(entries[position].LastModified, postLastModified(&posts[index]))
+	}
+	return entries
+}
+
+func postSitemapEntry(blogUrl string, post *structure.Post) sitemap.Entry {
+	entry := sitemap.Entry{Location: blogUrl + "/" + post.Slug + "/", LastModified: postLastModified(post)}
+	if len(post.Image) != 0 {
+		entry.Images = []string{absoluteSitemapUrl(blogUrl, string(post.Image))}
+	}
+	return entry
+}
+
+// Posts that haven't been edited since they were published don't have an update date
+func postLastModified(post *structure.Post) *time.Time {
+	if post.UpdatedAt != nil {
+		return post.UpdatedAt
+	}
+	return post.Date
+}
+
+func latest(a *time.Time, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
+
+func absoluteSitemapUrl(blogUrl string, path string) string {
+	if strings.HasPrefix(path, "/") {
+		return blogUrl + path
+	}
+	return path
+}
+
+func sitemapBlogUrl() string {
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	return string(methods.Blog.Url)
+}
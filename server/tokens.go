@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kabukky/journey/authentication"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/structure"
+)
+
+// The last used time of a token is only written if it is older than this (so that scripts don't cause a write on every request)
+const apiTokenLastUsedPrecision = time.Minute
+
+type JsonApiToken struct {
+	Id         int64
+	Name       string
+	Token      string `json:",omitempty"` // Only set when the token is created
+	UserId     int64
+	CreatedAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// API function to get the api tokens of the logged in user
+func getApiTokensHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		tokens, err := database.RetrieveApiTokensForUser(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonTokens := make([]JsonApiToken, len(tokens))
+		for index, _ := range tokens {
+			jsonTokens[index] = *apiTokenToJson(&tokens[index])
+		}
+		json, err := json.Marshal(jsonTokens)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create an api token for the logged in user. The token is only returned once, the database only stores its hash.
+func postApiTokensHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		decoder := json.NewDecoder(r.Body)
+		var jsonToken JsonApiToken
+		err := decoder.Decode(&jsonToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if jsonToken.Name == "" {
+			http.Error(w, "A name for the token must be provided.", http.StatusBadRequest)
+			return
+		}
+		token, err := authentication.GenerateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		currentTime := date.GetCurrentTime()
+		tokenId, err := database.InsertApiToken([]byte(jsonToken.Name), authentication.HashToken(token), user.Id, currentTime)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		output := apiTokenToJson(&structure.ApiToken{Id: tokenId, Name: []byte(jsonToken.Name), UserId: user.Id, CreatedAt: &currentTime})
+		output.Token = token
+		json, err := json.Marshal(output)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke an api token. Users may revoke their own tokens, administrators may revoke the tokens of the users they manage.
+func deleteApiTokensHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		tokenId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || tokenId < 1 {
+			http.Error(w, "Wrong token id.", http.StatusInternalServerError)
+			return
+		}
+		token, err := database.RetrieveApiToken(tokenId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if token.UserId != user.Id {
+			owner, err := database.RetrieveUserWithRole(token.UserId)
+			if err != nil || !canManageUser(user, owner) {
+				http.Error(w, "You don't have permission to revoke this token.", http.StatusForbidden)
+				return
+			}
+		}
+		err = database.DeleteApiTokenById(token.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Token revoked!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Returns the token of an "Authorization: Bearer <token>" header
+func getBearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[7:]), true
+}
+
+// Function to get the user of an api token. The token has the role of its user. Returns nil if the token is unknown or the user has been suspended.
+func getApiTokenUser(token string) *structure.User {
+	if token == "" {
+		return nil
+	}
+	apiToken, err := database.RetrieveApiTokenByHash(authentication.HashToken(token))
+	if err != nil {
+		return nil
+	}
+	user, err := database.RetrieveUserWithRole(apiToken.UserId)
+	if err != nil || user.Status == "inactive" {
+		return nil
+	}
+	currentTime := date.GetCurrentTime()
+	if apiToken.LastUsedAt == nil || currentTime.Sub(*apiToken.LastUsedAt) >= apiTokenLastUsedPrecision {
+		// Not being able to record the time shouldn't fail the request
+		database.UpdateApiTokenLastUsed(apiToken.Id, currentTime)
+	}
+	return user
+}
+
+func apiTokenToJson(token *structure.ApiToken) *JsonApiToken {
+	return &JsonApiToken{Id: token.Id, Name: string(token.Name), UserId: token.UserId, CreatedAt: token.CreatedAt, LastUsedAt: token.LastUsedAt}
+}
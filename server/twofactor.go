@@ -0,0 +1,285 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/authentication"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/structure/methods"
+	"github.com/kabukky/journey/totp"
+)
+
+type JsonTwoFactor struct {
+	Enabled           bool
+	RecoveryCodesLeft int64
+}
+
+type JsonTwoFactorSetup struct {
+	Secret          string
+	ProvisioningUri string
+}
+
+type JsonTwoFactorCode struct {
+	Code string
+}
+
+type JsonRecoveryCodes struct {
+	RecoveryCodes []string
+}
+
+// Function to serve the form for the second factor
+func getLoginTwoFactorHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if authentication.GetPendingLogin(r) == 0 {
+		http.Redirect(w, r, "/admin/login/", 302)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "login-2fa.html"))
+	return
+}
+
+// Function to receive the second factor. The session is only created if the code is correct.
+func postLoginTwoFactorHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userId := authentication.GetPendingLogin(r)
+	if userId == 0 {
+		http.Redirect(w, r, "/admin/login/", 302)
+		return
+	}
+	user, err := database.RetrieveUserWithRole(userId)
+	if err != nil {
+		http.Redirect(w, r, "/admin/login/", 302)
+		return
+	}
+	name := string(user.Name)
+	// Codes are throttled like passwords
+	if wait := authentication.LoginWaitTime(name, r); wait > 0 {
+		seconds := int(wait/time.Second) + 1
+		log.Println("Blocked two-factor login attempt for user " + name + " (too many failed attempts)")
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, "Too many failed login attempts. Please try again in "+strconv.Itoa(seconds)+" seconds.", http.StatusTooManyRequests)
+		return
+	}
+	if !authentication.SecondFactorIsCorrect(userId, r.FormValue("code")) {
+		authentication.RecordLoginAttempt(name, r, false)
+		log.Println("Failed two-factor login attempt for user " + name)
+		http.Redirect(w, r, "/admin/login/2fa/", 302)
+		return
+	}
+	authentication.ClearPendingLogin(w, r)
+	authentication.RecordLoginAttempt(name, r, true)
+	logInUser(name, w, r)
+	http.Redirect(w, r, "/admin/", 302)
+	return
+}
+
+// API function to get the two-factor authentication status of the logged in user
+func getApiTwoFactorHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		status := JsonTwoFactor{Enabled: authentication.TwoFactorIsEnabled(user.Id)}
+		if status.Enabled {
+			count, err := database.RetrieveNumberOfRecoveryCodes(user.Id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			status.RecoveryCodesLeft = count
+		}
+		json, err := json.Marshal(status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to start the enrolment. Returns a new secret and the uri for the QR code. Two-factor authentication is enabled once a code has been confirmed.
+func postApiTwoFactorSetupHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if authentication.TwoFactorIsEnabled(user.Id) {
+			http.Error(w, "Two-factor authentication is already enabled. Disable it first to set up a new authenticator.", http.StatusBadRequest)
+			return
+		}
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = database.InsertTwoFactor(user.Id, secret, date.GetCurrentTime())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		methods.Blog.RLock()
+		issuer := string(methods.Blog.Title)
+		methods.Blog.RUnlock()
+		if issuer == "" {
+			issuer = "Journey"
+		}
+		json, err := json.Marshal(JsonTwoFactorSetup{Secret: secret, ProvisioningUri: totp.ProvisioningUri(issuer, string(user.Name), secret)})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to finish the enrolment with a code from the authenticator app. Returns the recovery codes (they are only shown once).
+func postApiTwoFactorConfirmHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		jsonCode, err := decodeTwoFactorCode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		twoFactor, err := database.RetrieveTwoFactor(user.Id)
+		if err != nil || twoFactor.Confirmed {
+			http.Error(w, "No pending two-factor authentication setup.", http.StatusBadRequest)
+			return
+		}
+		step, ok := totp.Validate(twoFactor.Secret, jsonCode.Code, date.GetCurrentTime())
+		if !ok {
+			http.Error(w, "Wrong code.", http.StatusBadRequest)
+			return
+		}
+		err = database.UpdateTwoFactorConfirmed(user.Id, step)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeRecoveryCodes(w, user.Id)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to replace the recovery codes of the logged in user. Needs a valid code.
+func postApiRecoveryCodesHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		jsonCode, err := decodeTwoFactorCode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.SecondFactorIsCorrect(user.Id, jsonCode.Code) {
+			http.Error(w, "Wrong code.", http.StatusBadRequest)
+			return
+		}
+		writeRecoveryCodes(w, user.Id)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to disable two-factor authentication for the logged in user. Needs a valid code.
+func deleteApiTwoFactorHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		jsonCode, err := decodeTwoFactorCode(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// A pending (unconfirmed) setup can be cancelled without a code
+		if authentication.TwoFactorIsEnabled(user.Id) && !authentication.SecondFactorIsCorrect(user.Id, jsonCode.Code) {
+			http.Error(w, "Wrong code.", http.StatusBadRequest)
+			return
+		}
+		err = database.DeleteTwoFactorForUser(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Two-factor authentication disabled!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to reset the two-factor authentication of another user (e.g. if the user lost the authenticator and the recovery codes)
+func deleteApiUsersTwoFactorHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		userIdToReset, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || userIdToReset < 1 {
+			http.Error(w, "Wrong user id.", http.StatusInternalServerError)
+			return
+		}
+		userToReset, err := database.RetrieveUserWithRole(userIdToReset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Users disable their own two-factor authentication with a code
+		if userToReset.Id == user.Id || !canManageUser(user, userToReset) {
+			http.Error(w, "You don't have permission to reset the two-factor authentication of this user.", http.StatusForbidden)
+			return
+		}
+		err = database.DeleteTwoFactorForUser(userToReset.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Println("Two-factor authentication of user " + string(userToReset.Name) + " was reset by " + string(user.Name))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Two-factor authentication reset!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func decodeTwoFactorCode(r *http.Request) (*JsonTwoFactorCode, error) {
+	decoder := json.NewDecoder(r.Body)
+	var jsonCode JsonTwoFactorCode
+	err := decoder.Decode(&jsonCode)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &jsonCode, nil
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, userId int64) {
+	codes, err := authentication.GenerateRecoveryCodes(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json, err := json.Marshal(JsonRecoveryCodes{RecoveryCodes: codes})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(json)
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/authentication"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/filenames"
+	"github.com/kabukky/journey/slug"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/structure/methods"
+)
+
+// Invites are valid for one week
+const inviteValidity = 7 * 24 * time.Hour
+
+type JsonInvite struct {
+	Email string
+	Role  int
+}
+
+type JsonInviteToken struct {
+	Token     string
+	Url       string
+	ExpiresAt time.Time
+}
+
+// API function to get all users
+func getApiUsersHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
+			return
+		}
+		users, err := database.RetrieveUsers()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonUsers := make([]JsonUser, len(users))
+		for index, _ := range users {
+			jsonUsers[index] = *userToJson(&users[index])
+		}
+		json, err := json.Marshal(jsonUsers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a user
+func postApiUsersHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to create users.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var json JsonUser
+		err := decoder.Decode(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if json.Name == "" || json.Password == "" {
+			http.Error(w, "User name and password must be provided.", http.StatusBadRequest)
+			return
+		}
+		if !isAssignableRole(json.Role) {
+			http.Error(w, "Invalid role.", http.StatusBadRequest)
+			return
+		}
+		// Make sure the user name is not taken yet
+		if _, err := database.RetrieveUserByName([]byte(json.Name)); err == nil {
+			http.Error(w, "This user name is already taken.", http.StatusBadRequest)
+			return
+		}
+		hashedPassword, err := authentication.EncryptPassword(json.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		newUser := structure.User{Name: []byte(json.Name), Slug: slug.Generate(json.Name, "users"), Email: []byte(json.Email), Image: []byte(filenames.DefaultUserImageFilename), Cover: []byte(filenames.DefaultUserCoverFilename), Role: json.Role}
+		err = methods.SaveUser(&newUser, hashedPassword, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User created!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to invite a user. Returns the registration url for the invited user.
+func postApiUsersInviteHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to invite users.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var jsonInvite JsonInvite
+		err := decoder.Decode(&jsonInvite)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !isAssignableRole(jsonInvite.Role) {
+			http.Error(w, "Invalid role.", http.StatusBadRequest)
+			return
+		}
+		// Clean up invites that were never used
+		currentTime := date.GetCurrentTime()
+		err = database.DeleteExpiredInvites(currentTime)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		token, err := authentication.GenerateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		expiresAt := currentTime.Add(inviteValidity)
+		// Only the hash of the token is saved in the database
+		_, err = database.InsertInvite(authentication.HashToken(token), []byte(jsonInvite.Email), jsonInvite.Role, expiresAt, currentTime, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		inviteJson := JsonInviteToken{Token: token, Url: adminUrl() + "/admin/register/?token=" + token, ExpiresAt: expiresAt}
+		json, err := json.Marshal(inviteJson)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to change the role or status (active/inactive) of a user
+func patchApiUsersHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		userIdToChange, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || userIdToChange < 1 {
+			http.Error(w, "Wrong user id.", http.StatusInternalServerError)
+			return
+		}
+		userToChange, err := database.RetrieveUserWithRole(userIdToChange)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Nobody may change his/her own role or suspend himself/herself
+		if userToChange.Id == user.Id || !canManageUser(user, userToChange) {
+			http.Error(w, "You don't have permission to change this user.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var json JsonUser
+		err = decoder.Decode(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if json.Role != 0 && json.Role != userToChange.Role {
+			if !isAssignableRole(json.Role) {
+				http.Error(w, "Invalid role.", http.StatusBadRequest)
+				return
+			}
+			err = database.UpdateRoleUser(json.Role, userToChange.Id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if json.Status != "" && json.Status != userToChange.Status {
+			if json.Status != "active" && json.Status != "inactive" {
+				http.Error(w, "Invalid status.", http.StatusBadRequest)
+				return
+			}
+			err = database.UpdateUserStatus(userToChange.Id, json.Status, date.GetCurrentTime(), user.Id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// Suspended users are logged out everywhere
+			if json.Status == "inactive" {
+				logOutUser(userToChange.Id)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User updated!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a user. The posts of the deleted user are handed over to the user who deleted him/her.
+func deleteApiUsersHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		userIdToDelete, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || userIdToDelete < 1 {
+			http.Error(w, "Wrong user id.", http.StatusInternalServerError)
+			return
+		}
+		userToDelete, err := database.RetrieveUserWithRole(userIdToDelete)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// The owner can't be deleted and nobody may delete himself/herself
+		if userToDelete.Id == user.Id || userToDelete.Role == structure.RoleOwner || !canManageUser(user, userToDelete) {
+			http.Error(w, "You don't have permission to delete this user.", http.StatusForbidden)
+			return
+		}
+		err = methods.DeleteUser(userToDelete.Id, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// The owner role can't be assigned. There is only one owner (the user who registered first).
+func isAssignableRole(role int) bool {
+	return role == structure.RoleAdministrator || role == structure.RoleEditor || role == structure.RoleAuthor
+}
+
+func retrieveValidInvite(token string) (*structure.Invite, error) {
+	if token == "" {
+		return nil, errors.New("No invite token provided.")
+	}
+	invite, err := database.RetrieveInviteByToken(authentication.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invite.ExpiresAt == nil || invite.ExpiresAt.Before(date.GetCurrentTime()) {
+		return nil, errors.New("Invite has expired.")
+	}
+	return invite, nil
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/webhooks"
+)
+
+type JsonWebhook struct {
+	Id        int64
+	Event     string
+	TargetUrl string
+	Secret    string
+	CreatedAt *time.Time
+	CreatedBy int64
+}
+
+type JsonWebhookDelivery struct {
+	Id         int64
+	Event      string
+	TargetUrl  string
+	Payload    json.RawMessage
+	Attempt    int
+	Status     string
+	StatusCode int
+	Error      string
+	DurationMs int64
+	CreatedAt  *time.Time
+}
+
+// API function to get all webhooks
+func getApiWebhooksHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
+			return
+		}
+		hooks, err := database.RetrieveWebhooks("")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonWebhooks := make([]JsonWebhook, len(hooks))
+		for index, _ := range hooks {
+			jsonWebhooks[index] = *webhookToJson(&hooks[index])
+		}
+		json, err := json.Marshal(jsonWebhooks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to register a webhook for an event. A secret for the signatures is generated if none is given.
+func postApiWebhooksHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to create webhooks.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var jsonWebhook JsonWebhook
+		err := decoder.Decode(&jsonWebhook)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !webhooks.IsEvent(jsonWebhook.Event) {
+			http.Error(w, "Not a valid event.", http.StatusBadRequest)
+			return
+		}
+		targetUrl, err := url.Parse(jsonWebhook.TargetUrl)
+		if err != nil || (targetUrl.Scheme != "http" && targetUrl.Scheme != "https") || targetUrl.Host == "" || len(jsonWebhook.TargetUrl) > 2000 {
+			http.Error(w, "The target url must be an http or https url.", http.StatusBadRequest)
+			return
+		}
+		if len(jsonWebhook.Secret) > 64 {
+			http.Error(w, "The secret can't be longer than 64 characters.", http.StatusBadRequest)
+			return
+		}
+		if jsonWebhook.Secret == "" {
+			jsonWebhook.Secret, err = generateWebhookSecret()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		currentTime := date.GetCurrentTime()
+		webhookId, err := database.InsertWebhook(jsonWebhook.Event, jsonWebhook.TargetUrl, jsonWebhook.Secret, currentTime, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		webhook := structure.Webhook{Id: webhookId, Event: jsonWebhook.Event, TargetUrl: jsonWebhook.TargetUrl, Secret: jsonWebhook.Secret, CreatedAt: &currentTime, CreatedBy: user.Id}
+		json, err := json.Marshal(webhookToJson(&webhook))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a webhook and its delivery log. Retries that are still pending are sent anyway.
+func deleteApiWebhooksHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to delete webhooks.", http.StatusForbidden)
+			return
+		}
+		webhookId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || webhookId < 1 {
+			http.Error(w, "Wrong webhook id.", http.StatusInternalServerError)
+			return
+		}
+		err = database.DeleteWebhookById(webhookId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Webhook deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get the delivery log of a webhook by pages (newest first). Every attempt is an entry.
+func getApiWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	user := getAuthenticatedUser(r)
+	if user != nil {
+		if !isAdministrator(user) {
+			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
+			return
+		}
+		webhookId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || webhookId < 1 {
+			http.Error(w, "Wrong webhook id.", http.StatusInternalServerError)
+			return
+		}
+		page, err := strconv.Atoi(params["number"])
+		if err != nil || page < 1 {
+			http.Error(w, "Wrong page number.", http.StatusInternalServerError)
+			return
+		}
+		if _, err = database.RetrieveWebhookById(webhookId); err != nil {
+			http.Error(w, "Webhook not found.", http.StatusNotFound)
+			return
+		}
+		deliveriesPerPage := int64(25)
+		deliveries, err := database.RetrieveWebhookDeliveries(webhookId, deliveriesPerPage, ((int64(page) - 1) * deliveriesPerPage))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonDeliveries := make([]JsonWebhookDelivery, len(deliveries))
+		for index, delivery := range deliveries {
+			jsonDeliveries[index] = JsonWebhookDelivery{Id: delivery.Id, Event: delivery.Event, TargetUrl: delivery.TargetUrl, Payload: json.RawMessage(delivery.Payload), Attempt: delivery.Attempt, Status: delivery.Status, StatusCode: delivery.StatusCode, Error: delivery.Error, DurationMs: delivery.DurationMs, CreatedAt: delivery.CreatedAt}
+		}
+		json, err := json.Marshal(jsonDeliveries)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func webhookToJson(webhook *structure.Webhook) *JsonWebhook {
+	return &JsonWebhook{Id: webhook.Id, Event: webhook.Event, TargetUrl: webhook.TargetUrl, Secret: webhook.Secret, CreatedAt: webhook.CreatedAt, CreatedBy: webhook.CreatedBy}
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
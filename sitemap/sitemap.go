@@ -0,0 +1,133 @@
+// Package sitemap writes sitemaps (https://www.sitemaps.org) and caches them until the content of the blog changes.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"sync"
+	"time"
+)
+
+// Entry: a url in a sitemap (or a sitemap in the sitemap index)
+type Entry struct {
+	Location     string
+	LastModified *time.Time
+	Images       []string
+}
+
+type urlSet struct {
+	XMLName    xml.Name `xml:"urlset"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	XmlnsImage string   `xml:"xmlns:image,attr"`
+	Urls       []url    `xml:"url"`
+}
+
+type url struct {
+	Location     string  `xml:"loc"`
+	LastModified string  `xml:"lastmod,omitempty"`
+	Images       []image `xml:"image:image"`
+}
+
+type image struct {
+	Location string `xml:"image:loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name  `xml:"sitemapindex"`
+	Xmlns    string    `xml:"xmlns,attr"`
+	Sitemaps []sitemap `xml:"sitemap"`
+}
+
+type sitemap struct {
+	Location     string `xml:"loc"`
+	LastModified string `xml:"lastmod,omitempty"`
+}
+
+const xmlnsSitemap = "http://www.sitemaps.org/schemas/sitemap/0.9"
+const xmlnsImage = "http://www.google.com/schemas/sitemap-image/1.1"
+
+// Function to write a sitemap with the given urls.
+func WriteUrlSet(w io.Writer, entries []Entry) error {
+	set := urlSet{Xmlns: xmlnsSitemap, XmlnsImage: xmlnsImage, Urls: make([]url, len(entries))}
+	for index, entry := range entries {
+		set.Urls[index] = url{Location: entry.Location, LastModified: formatDate(entry.LastModified)}
+		for _, location := range entry.Images {
+			set.Urls[index].Images = append(set.Urls[index].Images, image{Location: location})
+		}
+	}
+	return write(w, set)
+}
+
+// Function to write a sitemap index that lists the given sitemaps.
+func WriteIndex(w io.Writer, entries []Entry) error {
+	index := sitemapIndex{Xmlns: xmlnsSitemap, Sitemaps: make([]sitemap, len(entries))}
+	for i, entry := range entries {
+		index.Sitemaps[i] = sitemap{Location: entry.Location, LastModified: formatDate(entry.LastModified)}
+	}
+	return write(w, index)
+}
+
+// Returns the most recent modification time of the entries (nil if none of them has one)
+func LastModified(entries []Entry) *time.Time {
+	var last *time.Time
+	for _, entry := range entries {
+		if entry.LastModified != nil && (last == nil || entry.LastModified.After(*last)) {
+			last = entry.LastModified
+		}
+	}
+	return last
+}
+
+func write(w io.Writer, value interface{}) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(value)
+}
+
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.UTC().Format(time.RFC3339)
+}
+
+// The generated sitemaps (by name, e.g. "sitemap-posts.xml"). The generation counts the invalidations.
+var cache = struct {
+	sync.RWMutex
+	files      map[string][]byte
+	generation int
+}{files: make(map[string][]byte)}
+
+// Function to get a sitemap from the cache. Calls generate if the sitemap isn't cached (yet).
+func Get(name string, generate func() ([]byte, error)) ([]byte, error) {
+	cache.RLock()
+	data, ok := cache.files[name]
+	generation := cache.generation
+	cache.RUnlock()
+	if ok {
+		return data, nil
+	}
+	data, err := generate()
+	if err != nil {
+		return nil, err
+	}
+	cache.Lock()
+	// Don't cache a sitemap that was generated from content that changed in the meantime
+	if cache.generation == generation {
+		cache.files[name] = data
+	}
+	cache.Unlock()
+	return data, nil
+}
+
+// Function to empty the cache. Called whenever the content of the blog changes.
+func Invalidate() {
+	cache.Lock()
+	cache.files = make(map[string][]byte)
+	cache.generation++
+	cache.Unlock()
+}
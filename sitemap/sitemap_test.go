@@ -0,0 +1,73 @@
+package sitemap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteUrlSet(t *testing.T) {
+	date := time.Date(2017, 3, 10, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	entries := []Entry{
+		Entry{Location: "http://example.com/welcome/", LastModified: &date, Images: []string{"http://example.com/images/a.jpg"}},
+		Entry{Location: "http://example.com/about/?a=1&b=2"},
+	}
+	var buffer bytes.Buffer
+	err := WriteUrlSet(&buffer, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>http://example.com/welcome/</loc>
+    <lastmod>2017-03-10T11:00:00Z</lastmod>
+    <image:image>
+      <image:loc>http://example.com/images/a.jpg</image:loc>
+    </image:image>
+  </url>
+  <url>
+    <loc>http://example.com/about/?a=1&amp;b=2</loc>
+  </url>
+</urlset>`
+	if out := buffer.String(); out != want {
+		t.Errorf("WriteUrlSet() =>\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	date := time.Date(2017, 3, 10, 12, 0, 0, 0, time.UTC)
+	var buffer bytes.Buffer
+	err := WriteIndex(&buffer, []Entry{Entry{Location: "http://example.com/sitemap-posts.xml", LastModified: &date}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>http://example.com/sitemap-posts.xml</loc>
+    <lastmod>2017-03-10T12:00:00Z</lastmod>
+  </sitemap>
+</sitemapindex>`
+	if out := buffer.String(); out != want {
+		t.Errorf("WriteIndex() =>\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	calls := 0
+	generate := func() ([]byte, error) {
+		calls++
+		return []byte("sitemap"), nil
+	}
+	Get("test.xml", generate)
+	Get("test.xml", generate)
+	if calls != 1 {
+		t.Errorf("Get() generated the sitemap %d times, want 1", calls)
+	}
+	Invalidate()
+	Get("test.xml", generate)
+	if calls != 2 {
+		t.Errorf("Get() after Invalidate() generated the sitemap %d times, want 2", calls)
+	}
+}
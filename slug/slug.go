@@ -34,7 +34,7 @@ func Generate(input string, table string) string {
 		output = string(runes)
 	}
 	// Don't allow a few specific slugs that are used by the blog
-	if table == "posts" && (output == "rss" || output == "tag" || output == "author" || output == "page" || output == "admin") {
+	if table == "posts" && (output == "rss" || output == "atom" || output == "tag" || output == "author" || output == "page" || output == "admin" || output == "search" || output == "ghost") {
 		output = generateUniqueSlug(output, table, 2)
 	} else if table == "tags" || table == "navigation" { // We want duplicate tag and navigation slugs
 		return output
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStorage: stores the files in a directory on the local disk
+type LocalStorage struct {
+	Directory string
+}
+
+func (s *LocalStorage) filePath(name string) (string, error) {
+	if s.Directory == "" {
+		return "", errors.New("The storage has not been initialized.")
+	}
+	return filepath.Join(s.Directory, filepath.FromSlash(name)), nil
+}
+
+func (s *LocalStorage) Save(name string, data []byte, contentType string) error {
+	filePath, err := s.filePath(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0777)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0666)
+}
+
+func (s *LocalStorage) Open(name string) (Reader, *File, error) {
+	file, err := s.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	filePath, _ := s.filePath(name)
+	reader, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return reader, file, nil
+}
+
+func (s *LocalStorage) Stat(name string) (*File, error) {
+	filePath, err := s.filePath(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	// Directories aren't files of the storage
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	return &File{Path: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *LocalStorage) Delete(name string) error {
+	filePath, err := s.filePath(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(prefix string) ([]File, error) {
+	// Only walk the directory the prefix points into
+	root, err := s.filePath(path.Dir(prefix))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, 0)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return files, nil
+	}
+	err = filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(s.Directory, filePath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relativePath)
+		if strings.HasPrefix(name, prefix) {
+			files = append(files, File{Path: name, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
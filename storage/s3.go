@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Storage: stores the files in a bucket of Amazon S3 or a compatible object storage (e.g. MinIO).
+// Requests are signed with AWS Signature Version 4.
+type S3Storage struct {
+	// Url of the service without a trailing slash, e.g. http://127.0.0.1:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+	// Defaults to a client with a timeout of 30 seconds
+	Client *http.Client
+}
+
+var defaultS3Client = &http.Client{Timeout: 30 * time.Second}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+type bytesReader struct {
+	*bytes.Reader
+}
+
+func (bytesReader) Close() error {
+	return nil
+}
+
+func (s *S3Storage) Save(name string, data []byte, contentType string) error {
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	response, err := s.do("PUT", s.Prefix+name, nil, data, header)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// The content of the file is read into memory (uploads are limited in size)
+func (s *S3Storage) Open(name string) (Reader, *File, error) {
+	response, err := s.do("GET", s.Prefix+name, nil, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	file := &File{Path: name, Size: int64(len(data))}
+	file.ModTime, _ = http.ParseTime(response.Header.Get("Last-Modified"))
+	return bytesReader{bytes.NewReader(data)}, file, nil
+}
+
+func (s *S3Storage) Stat(name string) (*File, error) {
+	response, err := s.do("HEAD", s.Prefix+name, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	file := &File{Path: name}
+	file.Size, _ = strconv.ParseInt(response.Header.Get("Content-Length"), 10, 64)
+	file.ModTime, _ = http.ParseTime(response.Header.Get("Last-Modified"))
+	return file, nil
+}
+
+func (s *S3Storage) Delete(name string) error {
+	response, err := s.do("DELETE", s.Prefix+name, nil, nil, nil)
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) List(prefix string) ([]File, error) {
+	files := make([]File, 0)
+	query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix + prefix}}
+	for {
+		response, err := s.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			files = append(files, File{Path: strings.TrimPrefix(object.Key, s.Prefix), Size: object.Size, ModTime: object.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// Sends a signed request for an object (or for the bucket if key is empty). Responses with an error status are
+// returned as errors (ErrNotFound for 404).
+func (s *S3Storage) do(method string, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	requestUrl, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	requestUrl.Path = strings.TrimSuffix(requestUrl.Path, "/") + "/" + s.Bucket + "/" + key
+	requestUrl.RawPath = escapePath(requestUrl.Path)
+	requestUrl.RawQuery = canonicalQuery(query)
+	request, err := http.NewRequest(method, requestUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	payloadHash := sha256.Sum256(body)
+	signRequest(request, hex.EncodeToString(payloadHash[:]), s.AccessKey, s.SecretKey, s.Region, time.Now())
+	client := s.Client
+	if client == nil {
+		client = defaultS3Client
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var s3Err s3Error
+	// The body of HEAD responses is always empty
+	if xml.NewDecoder(io.LimitReader(response.Body, 1<<16)).Decode(&s3Err) != nil || s3Err.Code == "" {
+		return nil, errors.New("S3 request failed: " + response.Status)
+	}
+	return nil, errors.New("S3 request failed: " + s3Err.Code + ": " + s3Err.Message)
+}
+
+// Adds the AWS Signature Version 4 headers to a request. All headers of the request are signed.
+func signRequest(request *http.Request, payloadHash string, accessKey string, secretKey string, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name, _ := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := request.Method + "\n" + request.URL.EscapedPath() + "\n" + request.URL.RawQuery + "\n" + canonicalHeaders.String() + "\n" + signedHeaders + "\n" + payloadHash
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+	key := hmacSha256([]byte("AWS4"+secretKey), day)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Query with sorted keys, encoded as S3 expects it in the canonical request
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key, _ := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapePath(value string) string {
+	return uriEncode(value, false)
+}
+
+// Percent-encodes everything but the unreserved characters of RFC 3986 (and slashes if encodeSlash is false)
+func uriEncode(value string, encodeSlash bool) string {
+	var buffer bytes.Buffer
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' || (b == '/' && !encodeSlash) {
+			buffer.WriteByte(b)
+		} else {
+			buffer.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+	}
+	return buffer.String()
+}
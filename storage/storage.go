@@ -0,0 +1,204 @@
+// Package storage stores uploaded files on the local disk or in an S3-compatible object storage (e.g. Amazon S3 or MinIO).
+// Files are identified by slash-separated paths relative to the storage root, e.g. 2016/01/image.jpg.
+package storage
+
+import (
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Settings: the storage section of config.json. Backend is "local" (content/images) or "s3". The S3 settings are
+// needed for the s3 backend only; S3Endpoint is the url of the service, e.g. https://s3.eu-central-1.amazonaws.com
+// or http://127.0.0.1:9000 for a local MinIO server. Buckets are always addressed by path.
+type Settings struct {
+	Backend string
+	// Url the files are served from instead of Journey (e.g. a CDN in front of the bucket, https://cdn.example.com/images).
+	// Leave empty to serve the files through Journey.
+	CdnUrl      string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// Prepended to the path of every file in the bucket, e.g. "journey/"
+	S3Prefix string
+}
+
+// File: a stored file
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Reader: the content of a stored file
+type Reader interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Storage: a place to keep uploaded files
+type Storage interface {
+	Save(name string, data []byte, contentType string) error
+	Open(name string) (Reader, *File, error)
+	Stat(name string) (*File, error)
+	// Deleting a file that doesn't exist is not an error
+	Delete(name string) error
+	// Returns all files whose path starts with prefix, sorted by path
+	List(prefix string) ([]File, error)
+}
+
+var ErrNotFound = errors.New("File not found.")
+var ErrInvalidPath = errors.New("Invalid file path.")
+
+var current = struct {
+	sync.RWMutex
+	storage Storage
+	cdnUrl  string
+	// Results of Exists (templates check for resized variants on every request)
+	exists map[string]bool
+}{storage: &LocalStorage{}, exists: make(map[string]bool)}
+
+// Matches the urls of uploaded files in html attributes (also the ones in srcset lists)
+var imageUrlPattern = regexp.MustCompile(`(["']|, )/(?:content/)?images/`)
+
+// Function to set up the storage from the settings. Files of the local backend are stored in directory.
+func Initialize(settings Settings, directory string) error {
+	var storage Storage
+	switch settings.Backend {
+	case BackendLocal, "":
+		storage = &LocalStorage{Directory: directory}
+	case BackendS3:
+		if settings.S3Endpoint == "" || settings.S3Bucket == "" {
+			return errors.New("The s3 storage backend needs an endpoint and a bucket.")
+		}
+		region := settings.S3Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		storage = &S3Storage{Endpoint: strings.TrimSuffix(settings.S3Endpoint, "/"), Region: region, Bucket: settings.S3Bucket, AccessKey: settings.S3AccessKey, SecretKey: settings.S3SecretKey, Prefix: settings.S3Prefix}
+	default:
+		return errors.New("Unknown storage backend: " + settings.Backend)
+	}
+	current.Lock()
+	current.storage = storage
+	current.cdnUrl = strings.TrimSuffix(settings.CdnUrl, "/")
+	current.exists = make(map[string]bool)
+	current.Unlock()
+	return nil
+}
+
+func get() Storage {
+	current.RLock()
+	defer current.RUnlock()
+	return current.storage
+}
+
+func Save(name string, data []byte, contentType string) error {
+	name, err := cleanPath(name)
+	if err != nil {
+		return err
+	}
+	err = get().Save(name, data, contentType)
+	forget(name)
+	return err
+}
+
+func Open(name string) (Reader, *File, error) {
+	name, err := cleanPath(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return get().Open(name)
+}
+
+func Delete(name string) error {
+	name, err := cleanPath(name)
+	if err != nil {
+		return err
+	}
+	err = get().Delete(name)
+	forget(name)
+	return err
+}
+
+func List(prefix string) ([]File, error) {
+	if strings.HasPrefix(prefix, "/") || strings.Contains(prefix, "..") {
+		return nil, ErrInvalidPath
+	}
+	return get().List(prefix)
+}
+
+// Reports if a file exists. The result is cached until the file is saved or deleted.
+func Exists(name string) bool {
+	name, err := cleanPath(name)
+	if err != nil {
+		return false
+	}
+	current.RLock()
+	exists, ok := current.exists[name]
+	current.RUnlock()
+	if ok {
+		return exists
+	}
+	_, err = get().Stat(name)
+	if err != nil && err != ErrNotFound {
+		// Don't cache errors of the backend (e.g. a network error)
+		return false
+	}
+	current.Lock()
+	current.exists[name] = err == nil
+	current.Unlock()
+	return err == nil
+}
+
+func forget(name string) {
+	current.Lock()
+	delete(current.exists, name)
+	current.Unlock()
+}
+
+// Returns the CDN url of a file or an empty string if the files are served through Journey
+func CdnUrl(name string) string {
+	current.RLock()
+	defer current.RUnlock()
+	if current.cdnUrl == "" {
+		return ""
+	}
+	return current.cdnUrl + "/" + strings.TrimPrefix(name, "/")
+}
+
+// Replaces the urls of uploaded files (/images/... and /content/images/...) in html with their CDN urls
+func RewriteUrls(html []byte) []byte {
+	current.RLock()
+	cdnUrl := current.cdnUrl
+	current.RUnlock()
+	if cdnUrl == "" {
+		return html
+	}
+	return imageUrlPattern.ReplaceAll(html, []byte("${1}"+escapeReplacement(cdnUrl)+"/"))
+}
+
+func escapeReplacement(value string) string {
+	return strings.Replace(value, "$", "$$", -1)
+}
+
+// Returns the path without a leading slash. Paths that would leave the storage root are rejected.
+func cleanPath(name string) (string, error) {
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" || cleaned != "/"+strings.TrimPrefix(name, "/") {
+		return "", ErrInvalidPath
+	}
+	return cleaned[1:], nil
+}
@@ -0,0 +1,14 @@
+package structure
+
+import (
+	"time"
+)
+
+// ApiToken: a named token that authenticates requests to the admin api as its user (e.g. from scripts). Only the hash of the token is stored.
+type ApiToken struct {
+	Id         int64
+	Name       []byte
+	UserId     int64
+	CreatedAt  *time.Time
+	LastUsedAt *time.Time
+}
@@ -7,14 +7,16 @@ import (
 // Blog: settings that are used for template execution
 type Blog struct {
 	sync.RWMutex
-	Url             []byte
-	Title           []byte
-	Description     []byte
-	Logo            []byte
-	Cover           []byte
-	AssetPath       []byte
-	PostCount       int64
-	PostsPerPage    int64
-	ActiveTheme     string
-	NavigationItems []Navigation
+	Url               []byte
+	Title             []byte
+	Description       []byte
+	Logo              []byte
+	Cover             []byte
+	AssetPath         []byte
+	PostCount         int64
+	PostsPerPage      int64
+	ActiveTheme       string
+	NavigationItems   []Navigation
+	CodeinjectionHead []byte // inserted by {{ghost_head}} on every page
+	CodeinjectionFoot []byte // inserted by {{ghost_foot}} on every page
 }
@@ -0,0 +1,28 @@
+package structure
+
+import (
+	"time"
+)
+
+// Moderation states of a comment. New comments are pending until they are approved.
+const (
+	CommentPending  = "pending"
+	CommentApproved = "approved"
+	CommentSpam     = "spam"
+)
+
+// Comment: a comment of a reader on a post. Replies have the id of the comment they answer as ParentId (0 for top-level comments).
+type Comment struct {
+	Id          int64
+	PostId      int64
+	ParentId    int64
+	AuthorName  []byte
+	AuthorEmail []byte
+	AuthorUrl   []byte
+	Content     []byte // Plain text as it was written
+	Status      string
+	Ip          []byte
+	UserAgent   []byte
+	CreatedAt   *time.Time
+	Depth       int // Level of the reply in its thread (0 for top-level comments). Not stored in the database.
+}
@@ -0,0 +1,14 @@
+package structure
+
+import (
+	"time"
+)
+
+// ContentKey: a key that gives an integration (e.g. a headless frontend) read access to the content api
+type ContentKey struct {
+	Id        int64
+	Name      []byte
+	Secret    string
+	CreatedAt *time.Time
+	CreatedBy int64
+}
@@ -2,9 +2,11 @@ package structure
 
 // Helpers are created during parsing of the theme (template files). Helpers should never be altered during template execution (Helpers are shared across all requests).
 type Helper struct {
-	Name       string
+	Name       string // hash arguments are named key=value (e.g. limit=5)
+	Key        string // the key of a hash argument (e.g. limit in limit="5"), empty for all other helpers and arguments
 	Arguments  []Helper
 	Unescaped  bool
+	Quoted     bool // the argument was written in quotes (e.g. "post/card" or title="Hello")
 	Position   int
 	Block      []byte
 	Children   []Helper
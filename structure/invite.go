@@ -0,0 +1,14 @@
+package structure
+
+import (
+	"time"
+)
+
+// Invite: an invitation for a new user to register with the given role
+type Invite struct {
+	Id        int64
+	Email     []byte
+	Role      int
+	ExpiresAt *time.Time
+	CreatedBy int64
+}
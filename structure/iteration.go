@@ -0,0 +1,40 @@
+package structure
+
+// Iteration: the position of the current item in a {{#foreach}} loop. Used by @index, @first, @rowStart etc.
+type Iteration struct {
+	Index   int // 0 for the first item that is shown (after from)
+	Length  int // number of items that are shown
+	Columns int // 0 if the loop has no columns argument
+}
+
+// Number: the position starting at 1 (@number)
+func (i *Iteration) Number() int {
+	return i.Index + 1
+}
+
+func (i *Iteration) First() bool {
+	return i.Index == 0
+}
+
+func (i *Iteration) Last() bool {
+	return i.Index == i.Length-1
+}
+
+// The first item (index 0) is odd
+func (i *Iteration) Odd() bool {
+	return i.Index%2 == 0
+}
+
+func (i *Iteration) Even() bool {
+	return i.Index%2 == 1
+}
+
+// RowStart: the item is the first one of a row with the given number of columns
+func (i *Iteration) RowStart() bool {
+	return i.Columns > 0 && i.Index%i.Columns == 0
+}
+
+// RowEnd: the item is the last one of a row (or the last item of the loop)
+func (i *Iteration) RowEnd() bool {
+	return i.Columns > 0 && (i.Index%i.Columns == i.Columns-1 || i.Last())
+}
@@ -0,0 +1,15 @@
+package structure
+
+import (
+	"time"
+)
+
+// LoginAttempt: an entry of the login audit log
+type LoginAttempt struct {
+	Id         int64
+	Name       []byte
+	Ip         []byte
+	UserAgent  []byte
+	Successful bool
+	CreatedAt  *time.Time
+}
@@ -0,0 +1,19 @@
+package structure
+
+import (
+	"time"
+)
+
+// Media: an uploaded file in content/images. Path is the url of the file on the blog (e.g. /images/2016/01/image.jpg).
+type Media struct {
+	Id         int64
+	Path       string
+	MimeType   string
+	Size       int64
+	Width      int
+	Height     int
+	Alt        []byte
+	Caption    []byte
+	UploadedBy int64
+	CreatedAt  *time.Time
+}
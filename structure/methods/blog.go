@@ -5,6 +5,7 @@ import (
 	"github.com/kabukky/journey/configuration"
 	"github.com/kabukky/journey/database"
 	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/sitemap"
 	"github.com/kabukky/journey/slug"
 	"github.com/kabukky/journey/structure"
 	"log"
@@ -21,7 +22,7 @@ func UpdateBlog(b *structure.Blog, userId int64) error {
 	if err != nil {
 		return err
 	}
-	err = database.UpdateSettings(b.Title, b.Description, b.Logo, b.Cover, b.PostsPerPage, b.ActiveTheme, navigation, date.GetCurrentTime(), userId)
+	err = database.UpdateSettings(b.Title, b.Description, b.Logo, b.Cover, b.PostsPerPage, b.ActiveTheme, navigation, b.CodeinjectionHead, b.CodeinjectionFoot, date.GetCurrentTime(), userId)
 	if err != nil {
 		return err
 	}
@@ -30,6 +31,7 @@ func UpdateBlog(b *structure.Blog, userId int64) error {
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	triggerSiteWebhooks()
 	return nil
 }
 
@@ -43,6 +45,7 @@ func UpdateActiveTheme(activeTheme string, userId int64) error {
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	triggerSiteWebhooks()
 	return nil
 }
 
@@ -65,5 +68,7 @@ func GenerateBlog() error {
 		blog.NavigationItems[index].Slug = slug.Generate(blog.NavigationItems[index].Label, "navigation")
 	}
 	Blog = blog
+	// The content may have changed
+	sitemap.Invalidate()
 	return nil
 }
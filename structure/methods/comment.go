@@ -0,0 +1,44 @@
+package methods
+
+import (
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/structure"
+)
+
+// Function to retrieve the approved comments of a post in thread order: every comment is followed by its replies.
+func RetrieveCommentThreads(postId int64) ([]structure.Comment, error) {
+	comments, err := database.RetrieveCommentsForPost(postId, structure.CommentApproved)
+	if err != nil {
+		return nil, err
+	}
+	return ThreadComments(comments), nil
+}
+
+// Function to sort comments (oldest first) into threads and set their depth. Replies to comments that aren't
+// part of the list (e.g. because they haven't been approved) are treated as top-level comments.
+func ThreadComments(comments []structure.Comment) []structure.Comment {
+	ids := make(map[int64]bool)
+	for _, comment := range comments {
+		ids[comment.Id] = true
+	}
+	replies := make(map[int64][]int)
+	for index, comment := range comments {
+		parentId := comment.ParentId
+		if !ids[parentId] || parentId == comment.Id {
+			parentId = 0
+		}
+		replies[parentId] = append(replies[parentId], index)
+	}
+	threaded := make([]structure.Comment, 0, len(comments))
+	var addThread func(parentId int64, depth int)
+	addThread = func(parentId int64, depth int) {
+		for _, index := range replies[parentId] {
+			comment := comments[index]
+			comment.Depth = depth
+			threaded = append(threaded, comment)
+			addThread(comment.Id, depth+1)
+		}
+	}
+	addThread(0, 0)
+	return threaded
+}
@@ -5,15 +5,14 @@ import (
 	"strings"
 )
 
-// Function to put all arguments into a neatly organized map (splitting argument.Name with format "name=argument" into map["name"]"argument")
+// Function to put all arguments into a neatly organized map (hash arguments with format "name=argument" become map["name"]"argument")
 // for easier lookup and use in helper functions.
 func ProcessHelperArguments(arguments []structure.Helper) map[string]string {
 	argumentsMap := make(map[string]string)
 	for index, _ := range arguments {
-		// Separate = arguments and put them in map
-		argumentParts := strings.SplitN(arguments[index].Name, "=", 2)
-		if len(argumentParts) > 1 {
-			argumentsMap[argumentParts[0]] = argumentParts[1]
+		// Separate = arguments and put them in map. Other arguments can contain = as well (e.g. "a=b" in quotes).
+		if arguments[index].Key != "" {
+			argumentsMap[arguments[index].Key] = strings.TrimPrefix(arguments[index].Name, arguments[index].Key+"=")
 		} else {
 			argumentsMap[arguments[index].Name] = ""
 		}
@@ -0,0 +1,84 @@
+package methods
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/imaging"
+	"github.com/kabukky/journey/storage"
+)
+
+// Function to add the stored images that aren't in the media table yet (e.g. files that were uploaded before
+// the media table existed or copied there by hand). The media references of all posts are updated afterwards.
+func ImportMedia() (int, error) {
+	existing, err := database.RetrieveMediaPaths()
+	if err != nil {
+		return 0, err
+	}
+	files, err := storage.List("")
+	if err != nil {
+		return 0, err
+	}
+	imported := 0
+	for _, file := range files {
+		// Resized variants belong to their original
+		if strings.HasPrefix(file.Path, "size/") {
+			continue
+		}
+		path := "/images/" + file.Path
+		if existing[path] {
+			continue
+		}
+		reader, _, err := storage.Open(file.Path)
+		if err != nil {
+			return imported, err
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return imported, err
+		}
+		// SVG images were accepted by earlier versions of the upload
+		extension, err := imaging.Sniff(data)
+		if err != nil && strings.EqualFold(filepath.Ext(file.Path), ".svg") {
+			extension, err = ".svg", nil
+		}
+		if err != nil {
+			log.Println("Skipped " + path + ": not an image.")
+			continue
+		}
+		// The dimensions stay 0 for images that can't be read
+		width, height, _ := imaging.Dimensions(data)
+		// The uploader of these files is unknown (0)
+		_, err = database.InsertMedia(path, imaging.MimeType(extension), file.Size, width, height, 0, file.ModTime)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	log.Println("Imported " + strconv.Itoa(imported) + " files into the media library.")
+	return imported, UpdateAllPostMedia()
+}
+
+// Function to update which media every post references
+func UpdateAllPostMedia() error {
+	count, err := database.RetrieveNumberOfAllPosts()
+	if err != nil {
+		return err
+	}
+	posts, err := database.RetrievePostsForApi(count, 0)
+	if err != nil {
+		return err
+	}
+	for index, _ := range posts {
+		err = database.UpdatePostMedia(posts[index].Id, imaging.ReferencedImages(posts[index].Markdown, posts[index].Html, posts[index].Image))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package methods
 
 import (
+	"github.com/kabukky/journey/configuration"
 	"github.com/kabukky/journey/database"
 	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/imaging"
 	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/webhooks"
 	"log"
+	"time"
 )
 
 func SavePost(p *structure.Post) error {
@@ -19,16 +23,19 @@ func SavePost(p *structure.Post) error {
 			if err != nil {
 				return err
 			}
+			triggerTagWebhooks(tagId, &tag)
 		}
 		if tagId != 0 {
 			tagIds = append(tagIds, tagId)
 		}
 	}
 	// Insert post
-	postId, err := database.InsertPost(p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.MetaDescription, p.Image, *p.Date, p.Author.Id)
+	createdAt, scheduledAt := evaluateSchedule(p)
+	postId, err := database.InsertPost(p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.MetaDescription, p.Image, p.CodeinjectionHead, p.CodeinjectionFoot, createdAt, p.Author.Id, scheduledAt)
 	if err != nil {
 		return err
 	}
+	p.Id = postId
 	// Insert postTags
 	for _, tagId := range tagIds {
 		err = database.InsertPostTag(postId, tagId)
@@ -36,11 +43,27 @@ func SavePost(p *structure.Post) error {
 			return err
 		}
 	}
+	// Remember the uploaded images the post uses
+	err = database.UpdatePostMedia(postId, imaging.ReferencedImages(p.Markdown, p.Html, p.Image))
+	if err != nil {
+		return err
+	}
+	// Save the first revision
+	err = savePostRevision(postId, p)
+	if err != nil {
+		return err
+	}
+	// Add post to search index
+	err = indexPost(postId, p)
+	if err != nil {
+		return err
+	}
 	// Generate new global blog
 	err = GenerateBlog()
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	triggerPostWebhooks(p, nil)
 	return nil
 }
 
@@ -56,13 +79,20 @@ func UpdatePost(p *structure.Post) error {
 			if err != nil {
 				return err
 			}
+			triggerTagWebhooks(tagId, &tag)
 		}
 		if tagId != 0 {
 			tagIds = append(tagIds, tagId)
 		}
 	}
+	// Remember the state before the update for the webhooks
+	previous, err := database.RetrievePostById(p.Id)
+	if err != nil {
+		return err
+	}
 	// Update post
-	err := database.UpdatePost(p.Id, p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.MetaDescription, p.Image, *p.Date, p.Author.Id)
+	updatedAt, scheduledAt := evaluateSchedule(p)
+	err = database.UpdatePost(p.Id, p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.MetaDescription, p.Image, p.CodeinjectionHead, p.CodeinjectionFoot, updatedAt, p.Author.Id, scheduledAt)
 	if err != nil {
 		return err
 	}
@@ -78,16 +108,53 @@ func UpdatePost(p *structure.Post) error {
 			return err
 		}
 	}
+	// Remember the uploaded images the post uses
+	err = database.UpdatePostMedia(p.Id, imaging.ReferencedImages(p.Markdown, p.Html, p.Image))
+	if err != nil {
+		return err
+	}
+	// Save a revision of the changed post
+	err = savePostRevision(p.Id, p)
+	if err != nil {
+		return err
+	}
+	// Update search index
+	err = indexPost(p.Id, p)
+	if err != nil {
+		return err
+	}
 	// Generate new global blog
 	err = GenerateBlog()
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	triggerPostWebhooks(p, previous)
 	return nil
 }
 
 func DeletePost(postId int64) error {
-	err := database.DeletePostById(postId)
+	// The webhooks get the post as it was before the deletion
+	post, err := database.RetrievePostById(postId)
+	if err != nil {
+		return err
+	}
+	err = database.DeletePostById(postId)
+	if err != nil {
+		return err
+	}
+	err = database.DeletePostRevisionsForPostId(postId)
+	if err != nil {
+		return err
+	}
+	err = database.DeleteSearchIndexForPostId(postId)
+	if err != nil {
+		return err
+	}
+	err = database.DeletePostMediaForPostId(postId)
+	if err != nil {
+		return err
+	}
+	err = database.DeleteCommentsForPostId(postId)
 	if err != nil {
 		return err
 	}
@@ -96,5 +163,78 @@ func DeletePost(postId int64) error {
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	triggerPostDeletedWebhooks(post)
 	return nil
 }
+
+// Function to restore the title and content of a post from one of its revisions. The restore itself is saved as a new revision.
+func RestorePostRevision(postId int64, revisionId int64, userId int64) error {
+	revision, err := database.RetrievePostRevision(revisionId, postId)
+	if err != nil {
+		return err
+	}
+	post, err := database.RetrievePostById(postId)
+	if err != nil {
+		return err
+	}
+	post.Title = revision.Title
+	post.Markdown = revision.Markdown
+	post.Html = revision.Html
+	post.Author = &structure.User{Id: userId}
+	if !post.IsScheduled {
+		currentTime := date.GetCurrentTime()
+		post.Date = &currentTime
+	}
+	return UpdatePost(post)
+}
+
+// Function to publish all scheduled posts whose publication date has passed. Returns the posts that were published.
+func PublishScheduledPosts() ([]structure.Post, error) {
+	posts, err := database.RetrieveScheduledPostsDue(date.GetCurrentTime())
+	if err != nil {
+		return nil, err
+	}
+	published := make([]structure.Post, 0)
+	for index, _ := range posts {
+		err = database.UpdateScheduledPostPublished(posts[index].Id)
+		if err != nil {
+			return published, err
+		}
+		log.Println("Published scheduled post \"" + string(posts[index].Title) + "\" (scheduled for " + posts[index].Date.Format(time.RFC3339) + ").")
+		posts[index].IsScheduled = false
+		posts[index].IsPublished = true
+		published = append(published, posts[index])
+	}
+	if len(published) != 0 {
+		// Generate new global blog (the post count has changed). This runs in the scheduler, so an error is returned
+		// instead of stopping the server.
+		err = GenerateBlog()
+		for index, _ := range published {
+			triggerWebhooks(webhooks.EventPostPublished, map[string]interface{}{"post": postWebhookData(&published[index])})
+		}
+		if err != nil {
+			return published, err
+		}
+		triggerSiteWebhooks()
+	}
+	return published, nil
+}
+
+func savePostRevision(postId int64, p *structure.Post) error {
+	_, err := database.InsertPostRevision(postId, p.Title, p.Markdown, p.Html, date.GetCurrentTime(), p.Author.Id)
+	if err != nil {
+		return err
+	}
+	if configuration.Config.PostRevisionsLimit > 0 {
+		return database.DeleteOldPostRevisions(postId, int64(configuration.Config.PostRevisionsLimit))
+	}
+	return nil
+}
+
+// The date of a scheduled post is its future publication date, not the date of the change.
+func evaluateSchedule(p *structure.Post) (time.Time, *time.Time) {
+	if p.IsScheduled {
+		return date.GetCurrentTime(), p.Date
+	}
+	return *p.Date, nil
+}
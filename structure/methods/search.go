@@ -0,0 +1,52 @@
+package methods
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/kabukky/journey/conversion"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/structure"
+)
+
+// Function to make sure every post is in the search index. The index is rebuilt if the number of indexed posts is off (e.g. after upgrading or migrating from Ghost).
+func InitializeSearchIndex() error {
+	if !database.SearchIsAvailable() {
+		return nil
+	}
+	indexed, err := database.RetrieveNumberOfIndexedPosts()
+	if err != nil {
+		return err
+	}
+	all, err := database.RetrieveNumberOfAllPosts()
+	if err != nil {
+		return err
+	}
+	if indexed == all {
+		return nil
+	}
+	log.Println("Rebuilding search index...")
+	err = database.DeleteSearchIndex()
+	if err != nil {
+		return err
+	}
+	posts, err := database.RetrievePostsForApi(all, 0)
+	if err != nil {
+		return err
+	}
+	for index, _ := range posts {
+		err = indexPost(posts[index].Id, &posts[index])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexPost(postId int64, p *structure.Post) error {
+	tags := make([][]byte, len(p.Tags))
+	for index, _ := range p.Tags {
+		tags[index] = p.Tags[index].Name
+	}
+	return database.UpdateSearchIndex(postId, p.Title, conversion.StripTagsFromHtml(p.Html), bytes.Join(tags, []byte(" ")))
+}
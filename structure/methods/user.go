@@ -3,7 +3,9 @@ package methods
 import (
 	"github.com/kabukky/journey/database"
 	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/sitemap"
 	"github.com/kabukky/journey/structure"
+	"log"
 )
 
 func SaveUser(u *structure.User, hashedPassword string, createdBy int64) error {
@@ -23,5 +25,25 @@ func UpdateUser(u *structure.User, updatedById int64) error {
 	if err != nil {
 		return err
 	}
+	// The sitemap of the authors contains the slug and image of the user
+	sitemap.Invalidate()
+	return nil
+}
+
+func DeleteUser(userId int64, newAuthorId int64) error {
+	// Hand the posts of the deleted user over to the new author
+	err := database.UpdatePostsAuthor(userId, newAuthorId)
+	if err != nil {
+		return err
+	}
+	err = database.DeleteUserById(userId)
+	if err != nil {
+		return err
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
 	return nil
 }
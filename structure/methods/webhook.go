@@ -0,0 +1,138 @@
+package methods
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/kabukky/journey/configuration"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/structure"
+	"github.com/kabukky/journey/webhooks"
+)
+
+// Number of entries that are kept in the delivery log of every webhook
+const webhookDeliveriesLimit = 100
+
+var webhookQueue *webhooks.Queue
+
+// Function to start sending webhooks in the background. Events that happen before (e.g. while the blog is exported) are not sent.
+func StartWebhooks() {
+	queue := webhooks.NewQueue(1000, 4)
+	queue.Record = recordWebhookDelivery
+	webhookQueue = queue
+}
+
+// Sends the payload of an event to every webhook that is registered for it
+func triggerWebhooks(event string, data map[string]interface{}) {
+	if webhookQueue == nil {
+		return
+	}
+	hooks, err := database.RetrieveWebhooks(event)
+	if err != nil {
+		log.Println("Couldn't get webhooks:", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	data["event"] = event
+	data["created_at"] = date.GetCurrentTime()
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Println("Couldn't create webhook payload:", err)
+		return
+	}
+	for _, hook := range hooks {
+		err = webhookQueue.Enqueue(&webhooks.Delivery{WebhookId: hook.Id, Event: event, Url: hook.TargetUrl, Secret: hook.Secret, Payload: payload})
+		if err != nil {
+			log.Println("Couldn't send webhook to "+hook.TargetUrl+":", err)
+		}
+	}
+}
+
+// Writes an attempt to the delivery log of its webhook
+func recordWebhookDelivery(delivery *webhooks.Delivery, attempt *webhooks.Attempt) {
+	err := database.InsertWebhookDelivery(delivery.WebhookId, delivery.Event, delivery.Url, delivery.Payload, attempt.Number, attempt.Status, attempt.StatusCode, attempt.Error, attempt.Duration.Nanoseconds()/1e6, date.GetCurrentTime())
+	if err == nil {
+		err = database.DeleteOldWebhookDeliveries(delivery.WebhookId, webhookDeliveriesLimit)
+	}
+	if err != nil {
+		log.Println("Couldn't write webhook delivery log:", err)
+	}
+	if attempt.Status != webhooks.StatusDelivered {
+		log.Println("Webhook delivery to " + delivery.Url + " failed (attempt " + strconv.Itoa(attempt.Number) + ", " + attempt.Status + "): " + attempt.Error)
+	}
+}
+
+// Sends post.published or post.updated (and site.changed if the blog looks different afterwards)
+func triggerPostWebhooks(p *structure.Post, previous *structure.Post) {
+	if previous == nil && !p.IsPublished {
+		// A new draft or scheduled post: nothing is visible yet
+		return
+	}
+	data := map[string]interface{}{"post": postWebhookData(p)}
+	event := webhooks.EventPostUpdated
+	if p.IsPublished && (previous == nil || !previous.IsPublished) {
+		event = webhooks.EventPostPublished
+	}
+	if previous != nil {
+		data["previous"] = map[string]interface{}{"title": string(previous.Title), "slug": previous.Slug, "status": postStatus(previous)}
+	}
+	triggerWebhooks(event, data)
+	if p.IsPublished || previous.IsPublished {
+		triggerSiteWebhooks()
+	}
+}
+
+// Sends post.deleted (and site.changed if the post was visible)
+func triggerPostDeletedWebhooks(p *structure.Post) {
+	triggerWebhooks(webhooks.EventPostDeleted, map[string]interface{}{"post": postWebhookData(p)})
+	if p.IsPublished {
+		triggerSiteWebhooks()
+	}
+}
+
+func triggerTagWebhooks(tagId int64, tag *structure.Tag) {
+	triggerWebhooks(webhooks.EventTagAdded, map[string]interface{}{"tag": map[string]interface{}{"id": tagId, "name": string(tag.Name), "slug": tag.Slug}})
+}
+
+func triggerSiteWebhooks() {
+	triggerWebhooks(webhooks.EventSiteChanged, map[string]interface{}{"site": map[string]interface{}{"url": configuration.Config.Url}})
+}
+
+func postWebhookData(p *structure.Post) map[string]interface{} {
+	tags := make([]map[string]interface{}, 0, len(p.Tags))
+	for _, tag := range p.Tags {
+		tags = append(tags, map[string]interface{}{"name": string(tag.Name), "slug": tag.Slug})
+	}
+	data := map[string]interface{}{
+		"id":               p.Id,
+		"uuid":             string(p.Uuid),
+		"title":            string(p.Title),
+		"slug":             p.Slug,
+		"url":              configuration.Config.Url + "/" + p.Slug + "/",
+		"html":             string(p.Html),
+		"status":           postStatus(p),
+		"featured":         p.IsFeatured,
+		"page":             p.IsPage,
+		"meta_description": string(p.MetaDescription),
+		"feature_image":    string(p.Image),
+		"published_at":     p.Date,
+		"tags":             tags,
+	}
+	if p.Author != nil {
+		data["author_id"] = p.Author.Id
+	}
+	return data
+}
+
+func postStatus(p *structure.Post) string {
+	if p.IsPublished {
+		return "published"
+	} else if p.IsScheduled {
+		return "scheduled"
+	}
+	return "draft"
+}
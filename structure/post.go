@@ -5,18 +5,22 @@ import (
 )
 
 type Post struct {
-	Id          int64
-	Uuid        []byte
-	Title       []byte
-	Slug        string
-	Markdown    []byte
-	Html        []byte
-	IsFeatured  bool
-	IsPage      bool
-	IsPublished bool
-	Date        *time.Time
-	Tags        []Tag
-	Author      *User
-	MetaDescription []byte
-	Image       []byte
+	Id                int64
+	Uuid              []byte
+	Title             []byte
+	Slug              string
+	Markdown          []byte
+	Html              []byte
+	IsFeatured        bool
+	IsPage            bool
+	IsPublished       bool
+	IsScheduled       bool // If the post is scheduled, Date holds its future publication date
+	Date              *time.Time
+	UpdatedAt         *time.Time
+	Tags              []Tag
+	Author            *User
+	MetaDescription   []byte
+	Image             []byte
+	CodeinjectionHead []byte // inserted by {{ghost_head}} on the page of the post
+	CodeinjectionFoot []byte // inserted by {{ghost_foot}} on the page of the post
 }
@@ -12,8 +12,16 @@ type RequestData struct {
 	CurrentPostIndex       int
 	CurrentTagIndex        int
 	CurrentNavigationIndex int
-	CurrentHelperContext   int      // 0 = index, 1 = post, 2 = tag, 3 = author, 4 = navigation - used by block helpers
-	CurrentTemplate        int      // 0 = index, 1 = post, 2 = tag, 3 = author - never changes during execution. Used by funcs like body_classFunc etc to output the correct class
-	ContentForHelpers      []Helper // contentFor helpers that are attached to the currently rendering helper
-	CurrentPath            string   // path of the the url of this request
+	Comments               []Comment // approved comments of the current post in thread order (loaded by {{#foreach comments}})
+	CurrentCommentIndex    int
+	CurrentHelperContext   int               // 0 = index, 1 = post, 2 = tag, 3 = author, 4 = navigation, 5 = comment, 6 = get - used by block helpers
+	ParentHelperContexts   []int             // contexts outside of the current block, innermost last - used by ../ in paths
+	CurrentTemplate        int               // 0 = index, 1 = post, 2 = tag, 3 = author, 5 = search - never changes during execution. Used by funcs like body_classFunc etc to output the correct class
+	ContentForHelpers      []Helper          // contentFor helpers that are attached to the currently rendering helper
+	CurrentPath            string            // path of the the url of this request
+	CurrentSearchQuery     string            // query of the search template
+	CurrentSearchCount     int64             // number of posts found by the search template
+	PartialArguments       map[string][]byte // hash arguments of the partials that are executed (e.g. title in {{> "post/card" title="Hello"}})
+	CurrentPartials        []string          // names of the partials that are executed, outermost first - used to detect recursive includes
+	CurrentIteration       *Iteration        // innermost {{#foreach}} loop, nil outside of loops
 }
@@ -0,0 +1,16 @@
+package structure
+
+import (
+	"time"
+)
+
+// PostRevision: a snapshot of a post that is taken every time the post is saved
+type PostRevision struct {
+	Id        int64
+	PostId    int64
+	Title     []byte
+	Markdown  []byte
+	Html      []byte
+	CreatedAt *time.Time
+	CreatedBy int64
+}
@@ -0,0 +1,16 @@
+package structure
+
+import (
+	"time"
+)
+
+// Session: a login of a user. The session cookie contains a token, the database only stores its hash.
+type Session struct {
+	Id         int64
+	UserId     int64
+	UserAgent  []byte
+	Ip         []byte
+	CreatedAt  *time.Time
+	LastSeenAt *time.Time
+	ExpiresAt  *time.Time
+}
@@ -1,7 +1,8 @@
 package structure
 
 type Tag struct {
-	Id   int64
-	Name []byte
-	Slug string
+	Id        int64
+	Name      []byte
+	Slug      string
+	PostCount int64 // number of published posts, only set by {{#get "tags"}}
 }
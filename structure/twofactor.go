@@ -0,0 +1,14 @@
+package structure
+
+import (
+	"time"
+)
+
+// TwoFactor: the TOTP secret of a user. Two-factor authentication is only enabled once the user has confirmed the secret with a valid code.
+type TwoFactor struct {
+	UserId    int64
+	Secret    string
+	Confirmed bool
+	LastStep  int64 // Time step of the last accepted code (codes can't be used twice)
+	CreatedAt *time.Time
+}
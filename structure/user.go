@@ -1,14 +1,24 @@
 package structure
 
+// Roles a user can have (ids of the rows in the roles table)
+const (
+	RoleAdministrator = 1
+	RoleEditor        = 2
+	RoleAuthor        = 3
+	RoleOwner         = 4
+)
+
 type User struct {
-	Id       int64
-	Name     []byte
-	Slug     string
-	Email    []byte
-	Image    []byte
-	Cover    []byte
-	Bio      []byte
-	Website  []byte
-	Location []byte
-	Role     int //1 = Administrator, 2 = Editor, 3 = Author, 4 = Owner
+	Id        int64
+	Name      []byte
+	Slug      string
+	Email     []byte
+	Image     []byte
+	Cover     []byte
+	Bio       []byte
+	Website   []byte
+	Location  []byte
+	Role      int    //1 = Administrator, 2 = Editor, 3 = Author, 4 = Owner
+	Status    string // "active" or "inactive" (suspended)
+	PostCount int64  // number of published posts, only set by {{#get "authors"}}
 }
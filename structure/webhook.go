@@ -0,0 +1,30 @@
+package structure
+
+import (
+	"time"
+)
+
+// Webhook: a url that gets a signed json payload whenever Event happens on the blog
+type Webhook struct {
+	Id        int64
+	Event     string
+	TargetUrl string
+	Secret    string
+	CreatedAt *time.Time
+	CreatedBy int64
+}
+
+// WebhookDelivery: one attempt to send a payload to a webhook (the entries of the delivery log)
+type WebhookDelivery struct {
+	Id         int64
+	WebhookId  int64
+	Event      string
+	TargetUrl  string
+	Payload    []byte
+	Attempt    int
+	Status     string // delivered, retrying, or failed
+	StatusCode int    // 0 if the receiver didn't answer
+	Error      string
+	DurationMs int64
+	CreatedAt  *time.Time
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/kabukky/journey/structure/methods"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"sync"
 )
 
@@ -143,6 +144,37 @@ func ShowIndexTemplate(w http.ResponseWriter, r *http.Request, page int) error {
 	return err
 }
 
+func ShowSearchTemplate(w http.ResponseWriter, r *http.Request, query string, page int) error {
+	// Read lock templates and global blog
+	compiledTemplates.RLock()
+	defer compiledTemplates.RUnlock()
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	postIndex := int64(page - 1)
+	if postIndex < 0 {
+		postIndex = 0
+	}
+	count, err := database.RetrieveNumberOfPostsBySearch(query)
+	if err != nil {
+		return err
+	}
+	posts, err := database.RetrievePostsBySearch(query, methods.Blog.PostsPerPage, (methods.Blog.PostsPerPage * postIndex))
+	if err != nil {
+		return err
+	}
+	requestData := structure.RequestData{Posts: posts, Blog: methods.Blog, CurrentIndexPage: page, CurrentTemplate: 5, CurrentPath: r.URL.Path, CurrentSearchQuery: query, CurrentSearchCount: count} // CurrentTemplate = search
+	if template, ok := compiledTemplates.m["search"]; ok {
+		_, err = w.Write(executeHelper(template, &requestData, 0)) // context = index
+	} else {
+		_, err = w.Write(executeHelper(compiledTemplates.m["index"], &requestData, 0)) // context = index
+	}
+	if requestData.PluginVMs != nil {
+		// Put the lua state map back into the pool
+		plugins.LuaPool.Put(requestData.PluginVMs)
+	}
+	return err
+}
+
 func GetAllThemes() []string {
 	themes := make([]string, 0)
 	files, _ := filepath.Glob(filepath.Join(filenames.ThemesFilepath, "*"))
@@ -157,6 +189,14 @@ func GetAllThemes() []string {
 func executeHelper(helper *structure.Helper, values *structure.RequestData, context int) []byte {
 	// Set context and set it back to the old value once fuction returns
 	defer setCurrentHelperContext(values, values.CurrentHelperContext)
+	if context != values.CurrentHelperContext {
+		// Remember the outer context for ../ in paths
+		defer setParentHelperContexts(values, values.ParentHelperContexts)
+		values.ParentHelperContexts = append(values.ParentHelperContexts, values.CurrentHelperContext)
+		// The hash arguments of a partial only replace helpers in the context the partial was called in
+		defer setPartialArguments(values, values.PartialArguments)
+		values.PartialArguments = nil
+	}
 	values.CurrentHelperContext = context
 
 	block := helper.Block
@@ -170,7 +210,7 @@ func executeHelper(helper *structure.Helper, values *structure.RequestData, cont
 			extendHelper = compiledTemplates.m[string(child.Function(&child, values))]
 		} else {
 			var buffer bytes.Buffer
-			toAdd := child.Function(&child, values)
+			toAdd := evaluateHelper(&child, values)
 			buffer.Write(block[:child.Position+indexTracker])
 			buffer.Write(toAdd)
 			buffer.Write(block[child.Position+indexTracker:])
@@ -185,6 +225,58 @@ func executeHelper(helper *structure.Helper, values *structure.RequestData, cont
 	return block
 }
 
+// Executes a block helper once for each item of a list with the given length (e.g. {{#foreach posts}}). selectItem
+// makes the item at an index the current one before the block is executed for it. The arguments limit, from, and to
+// (counting from 1) select the items to show, columns sets @rowStart and @rowEnd (e.g. columns="3"). If no item is
+// shown, the else block is executed.
+func executeIteration(helper *structure.Helper, values *structure.RequestData, context int, length int, selectItem func(int)) []byte {
+	arguments := methods.ProcessHelperArguments(helper.Arguments)
+	from := 1
+	if number, err := strconv.Atoi(arguments["from"]); err == nil && number > 1 {
+		from = number
+	}
+	to := length
+	if number, err := strconv.Atoi(arguments["to"]); err == nil && number < to {
+		to = number
+	}
+	if number, err := strconv.Atoi(arguments["limit"]); err == nil && number > 0 && from+number-1 < to {
+		to = from + number - 1
+	}
+	if from > to {
+		if len(helper.Arguments) != 0 && helper.Arguments[len(helper.Arguments)-1].Name == "else" {
+			return executeHelper(&helper.Arguments[len(helper.Arguments)-1], values, values.CurrentHelperContext)
+		}
+		return []byte{}
+	}
+	columns, _ := strconv.Atoi(arguments["columns"])
+	// Set the iteration and set it back to the one of the outer loop once the function returns
+	defer setCurrentIteration(values, values.CurrentIteration)
+	// Every item is a new context (even in a loop over the posts of a post template), so the hash arguments of a partial don't apply
+	defer setPartialArguments(values, values.PartialArguments)
+	values.PartialArguments = nil
+	iteration := &structure.Iteration{Length: to - from + 1, Columns: columns}
+	var buffer bytes.Buffer
+	for position := from; position <= to; position++ {
+		selectItem(position - 1)
+		iteration.Index = position - from
+		values.CurrentIteration = iteration
+		buffer.Write(executeHelper(helper, values, context))
+	}
+	return buffer.Bytes()
+}
+
+func setParentHelperContexts(values *structure.RequestData, contexts []int) {
+	values.ParentHelperContexts = contexts
+}
+
+func setCurrentIteration(values *structure.RequestData, iteration *structure.Iteration) {
+	values.CurrentIteration = iteration
+}
+
 func setCurrentHelperContext(values *structure.RequestData, context int) {
 	values.CurrentHelperContext = context
 }
+
+func setPartialArguments(values *structure.RequestData, arguments map[string][]byte) {
+	values.PartialArguments = arguments
+}
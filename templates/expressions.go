@@ -0,0 +1,232 @@
+package templates
+
+import (
+	"github.com/kabukky/journey/structure"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Paths select the context of a helper with the parts before the last one, e.g. post.primary_tag.name, author.name,
+// this.title, or ../title (the context outside of the current block).
+func isPath(name string) bool {
+	if name == "" || strings.ContainsAny(name, "= \"'()") || isNumber(name) {
+		return false
+	}
+	return strings.Contains(name, ".") || strings.HasPrefix(name, "../")
+}
+
+func isNumber(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+func pathFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	path := helper.Name
+	// The path is evaluated on a copy of the request data so that the context of the caller doesn't change
+	data := *values
+	for strings.HasPrefix(path, "../") {
+		path = path[len("../"):]
+		data.PartialArguments = nil
+		if length := len(data.ParentHelperContexts); length != 0 {
+			data.CurrentHelperContext = data.ParentHelperContexts[length-1]
+			// Limit the capacity so that blocks inside of the path don't overwrite the contexts of the caller
+			data.ParentHelperContexts = data.ParentHelperContexts[: length-1 : length-1]
+		}
+	}
+	path = strings.TrimPrefix(path, "this.")
+	if strings.HasPrefix(path, "@site.") {
+		path = "@blog." + path[len("@site."):]
+	}
+	name := path
+	// Registered names win (e.g. @blog.title or author.name)
+	if helperFuctions[name] == nil {
+		parts := strings.Split(path, ".")
+		for _, part := range parts[:len(parts)-1] {
+			if !enterPathContext(&data, part) {
+				return []byte{}
+			}
+			// The hash arguments of a partial only replace helpers in the context the partial was called in
+			if part != "this" {
+				data.PartialArguments = nil
+			}
+		}
+		name = parts[len(parts)-1]
+	}
+	if helperFuctions[name] == nil {
+		log.Println("Warning: This helper is not implemented:", helper.Name)
+		return []byte{}
+	}
+	pathHelper := *helper
+	pathHelper.Name = name
+	pathHelper.Function = helperFuctions[name]
+	result := evaluateHelper(&pathHelper, &data)
+	// Keep the plugin states that were attached during the execution
+	values.PluginVMs = data.PluginVMs
+	return result
+}
+
+// Changes the context to the object of a part of a path. Returns false if there is no such object (e.g. the post has no tags).
+func enterPathContext(values *structure.RequestData, part string) bool {
+	if part == "this" {
+		return true
+	}
+	if values.CurrentPostIndex >= len(values.Posts) {
+		return false
+	}
+	post := &values.Posts[values.CurrentPostIndex]
+	switch part {
+	case "post":
+		values.CurrentHelperContext = 1 // post
+		return true
+	case "primary_tag":
+		if len(post.Tags) == 0 {
+			return false
+		}
+		values.CurrentTagIndex = 0
+		values.CurrentHelperContext = 2 // tag
+		return true
+	case "tag":
+		if values.CurrentHelperContext != 2 && values.CurrentTemplate == 2 && values.CurrentTag != nil {
+			// The tag of the tag template isn't attached to a post
+			values.Posts = []structure.Post{structure.Post{Tags: []structure.Tag{*values.CurrentTag}}}
+			values.CurrentPostIndex = 0
+			values.CurrentTagIndex = 0
+		} else if len(post.Tags) == 0 {
+			return false
+		}
+		values.CurrentHelperContext = 2 // tag
+		return true
+	case "author", "primary_author":
+		if post.Author == nil {
+			return false
+		}
+		values.CurrentHelperContext = 3 // author
+		return true
+	}
+	log.Println("Warning: Unknown object in path:", part)
+	return false
+}
+
+// Returns the output of a helper. Inside of partials, the hash arguments of the partial replace the helpers of the same name.
+func evaluateHelper(helper *structure.Helper, values *structure.RequestData) []byte {
+	if value, ok := values.PartialArguments[helper.Name]; ok && len(helper.Arguments) == 0 && len(helper.Children) == 0 {
+		return evaluateEscape(value, helper.Unescaped)
+	}
+	return helper.Function(helper, values)
+}
+
+// Returns the unescaped value of an argument: the text of strings and numbers, the output of helpers, paths, and
+// subexpressions otherwise. false, null, and undefined are empty.
+func evaluateArgument(argument *structure.Helper, values *structure.RequestData) []byte {
+	if argument.Quoted || isNumber(argument.Name) {
+		return []byte(argument.Name)
+	}
+	switch argument.Name {
+	case "true":
+		return []byte("true")
+	case "false", "null", "undefined":
+		return []byte{}
+	}
+	unescaped := *argument
+	unescaped.Unescaped = true
+	return evaluateHelper(&unescaped, values)
+}
+
+// Helpers that output numbers (see isTruthy)
+var numberHelpers = map[string]bool{"id": true, "post.id": true, "comment_count": true, "parent_id": true, "depth": true, "@index": true, "@number": true, "page": true, "pages": true, "pagination.total": true, "../pagination.total": true, "count.posts": true}
+
+// Returns whether the argument of a conditional helper (e.g. {{#if}}) is true. Like in Handlebars, empty values (e.g. "", false,
+// null, or a list without items) and the number 0 (e.g. {{#if 0}} or {{#if @index}} for the first item) are false. Text is
+// true if it isn't empty, even if it is "0" (e.g. {{#if "0"}} or {{#if title}} for a post titled 0).
+func isTruthy(argument *structure.Helper, values *structure.RequestData) bool {
+	value := evaluateArgument(argument, values)
+	if len(value) == 0 {
+		return false
+	}
+	if argument.Quoted || (!isNumber(argument.Name) && !numberHelpers[argument.Name]) {
+		return true
+	}
+	number, err := strconv.ParseFloat(string(value), 64)
+	return err != nil || number != 0
+}
+
+// Returns the key and the value of a hash argument (e.g. title="Hello", title=@blog.title, or title=(encode title)).
+// Unquoted values that aren't helpers are text, except for false, null, and undefined (e.g. limit=5 or featured=false).
+func evaluateHashArgument(argument *structure.Helper, values *structure.RequestData) (string, []byte, bool) {
+	if argument.Key == "" {
+		return "", nil, false
+	}
+	value := strings.TrimPrefix(argument.Name, argument.Key+"=")
+	if argument.Quoted {
+		return argument.Key, []byte(value), true
+	}
+	if len(argument.Arguments) != 0 {
+		return argument.Key, evaluateArgument(&argument.Arguments[0], values), true
+	}
+	switch value {
+	case "false", "null", "undefined":
+		return argument.Key, []byte{}, true
+	}
+	return argument.Key, []byte(value), true
+}
+
+// Returns the arguments of a helper that aren't hash arguments or the else block
+func positionalArguments(helper *structure.Helper) []structure.Helper {
+	arguments := make([]structure.Helper, 0, len(helper.Arguments))
+	for _, argument := range helper.Arguments {
+		if (argument.Name == "else" && argument.Children != nil) || argument.Key != "" {
+			continue
+		}
+		arguments = append(arguments, argument)
+	}
+	return arguments
+}
+
+// Executes the block of a conditional helper (e.g. {{#match}}) if the condition is true and the else block otherwise.
+// Used as a subexpression (e.g. {{#if (match title "Home")}}), the helper returns a non-empty value if the condition is true.
+func executeCondition(helper *structure.Helper, values *structure.RequestData, condition bool) []byte {
+	if helper.Children == nil {
+		if condition {
+			return []byte("true")
+		}
+		return []byte{}
+	}
+	if condition {
+		return executeHelper(helper, values, values.CurrentHelperContext)
+	} else if len(helper.Arguments) != 0 && helper.Arguments[len(helper.Arguments)-1].Name == "else" {
+		return executeHelper(&helper.Arguments[len(helper.Arguments)-1], values, values.CurrentHelperContext)
+	}
+	return []byte{}
+}
+
+// Compares two values with an operator (=, !=, <, >, <=, or >=). Numbers are compared by their value, everything else as text.
+func compareValues(left string, operator string, right string) bool {
+	comparison := strings.Compare(left, right)
+	if leftNumber, err := strconv.ParseFloat(left, 64); err == nil {
+		if rightNumber, err := strconv.ParseFloat(right, 64); err == nil {
+			comparison = 0
+			if leftNumber < rightNumber {
+				comparison = -1
+			} else if leftNumber > rightNumber {
+				comparison = 1
+			}
+		}
+	}
+	switch operator {
+	case "=", "==":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	case "<":
+		return comparison < 0
+	case ">":
+		return comparison > 0
+	case "<=":
+		return comparison <= 0
+	case ">=":
+		return comparison >= 0
+	}
+	log.Println("Warning: Unknown operator in match helper:", operator)
+	return false
+}
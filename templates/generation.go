@@ -16,67 +16,146 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 // For parsing of the theme files
 var openTag = []byte("{{")
 var closeTag = []byte("}}")
-var twoPartArgumentChecker = regexp.MustCompile("(\\S+?)\\s*?=\\s*?['\"](.*?)['\"]")
-var quoteTagChecker = regexp.MustCompile("(.*?)[\"'](.+?)[\"']$")
+var hashKeyChecker = regexp.MustCompile(`^([\w@.\-]+)\s*=\s*`)
 
 func getFunction(name string) func(*structure.Helper, *structure.RequestData) []byte {
 	if helperFuctions[name] != nil {
 		return helperFuctions[name]
+	} else if isPath(name) {
+		return pathFunc
 	} else {
 		return helperFuctions["null"]
 	}
 }
 
 func createHelper(helperName []byte, unescaped bool, startPos int, block []byte, children []structure.Helper, elseHelper *structure.Helper) *structure.Helper {
-	var helper *structure.Helper
-	// Check for =arguments
-	twoPartArgumentResult := twoPartArgumentChecker.FindAllSubmatch(helperName, -1)
-	twoPartArguments := make([][]byte, 0)
-	for _, arg := range twoPartArgumentResult {
-		if len(arg) == 3 {
-			twoPartArguments = append(twoPartArguments, bytes.Join(arg[1:], []byte("=")))
-			//remove =argument from helper name
-			helperName = bytes.Replace(helperName, arg[0], []byte(""), 1)
-		}
+	// Separate arguments (e.g. 'if @blog.title' or 'match title "Hello world"')
+	tokens := splitArguments(helperName)
+	if len(tokens) == 0 {
+		tokens = append(tokens, argumentToken{})
+	}
+	helper := makeHelper(tokens[0].value, unescaped, startPos, block, children)
+	for _, token := range tokens[1:] {
+		helper.Arguments = append(helper.Arguments, *createArgument(token, unescaped))
+	}
+	if elseHelper != nil {
+		helper.Arguments = append(helper.Arguments, *elseHelper)
 	}
-	// Separate arguments (e.g. 'if @blog.title')
-	tags := bytes.Fields(helperName)
-	for index, tag := range tags {
-		//remove "" around tag if present
-		quoteTagResult := quoteTagChecker.FindSubmatch(tag)
-		if len(quoteTagResult) != 0 {
-			// Get the string inside the quotes (3rd element in array)
-			tag = quoteTagResult[2]
+	return helper
+}
+
+// Argument of a helper as written in the theme file
+type argumentToken struct {
+	key           string // key of a hash argument (e.g. limit in limit="5"), empty for other arguments
+	value         string // without quotes or parentheses
+	quoted        bool
+	subexpression bool // e.g. (encode title)
+}
+
+// Splits a helper (e.g. 'match title "Hello world"' or 'foreach posts limit="3"') into its name and arguments.
+// Quoted strings can contain whitespace, subexpressions in parentheses can contain further arguments.
+func splitArguments(data []byte) []argumentToken {
+	tokens := make([]argumentToken, 0)
+	position := 0
+	for position < len(data) {
+		if isWhitespace(data[position]) {
+			position++
+			continue
 		}
-		//TODO: This may have to change if the first argument is surrounded by quotes
-		if index == 0 {
-			helper = makeHelper(string(tag), unescaped, startPos, block, children)
-		} else {
-			// Handle whitespaces in arguments
-			helper.Arguments = append(helper.Arguments, *makeHelper(string(tag), unescaped, 0, []byte{}, nil))
+		token := argumentToken{}
+		if key := hashKeyChecker.FindSubmatch(data[position:]); key != nil {
+			token.key = string(key[1])
+			position += len(key[0])
 		}
+		position = readArgumentValue(data, position, &token)
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// Reads a quoted string, a subexpression, or a word starting at position and returns the position after it
+func readArgumentValue(data []byte, position int, token *argumentToken) int {
+	if position >= len(data) {
+		return position
 	}
-	if len(twoPartArguments) != 0 {
-		for _, arg := range twoPartArguments {
-			// Check for quotes in the =argument (has beem omitted from the check above)
-			quoteTagResult := quoteTagChecker.FindSubmatch(arg)
-			if len(quoteTagResult) != 0 {
-				// Join poth parts, this time without the quotes
-				arg = bytes.Join([][]byte{quoteTagResult[1], quoteTagResult[2]}, []byte(""))
+	switch data[position] {
+	case '"', '\'':
+		token.quoted = true
+		end := bytes.IndexByte(data[position+1:], data[position])
+		if end == -1 {
+			token.value = string(data[position+1:])
+			return len(data)
+		}
+		token.value = string(data[position+1 : position+1+end])
+		return position + end + 2
+	case '(':
+		token.subexpression = true
+		depth := 0
+		var quote byte
+		for end := position; end < len(data); end++ {
+			if quote != 0 {
+				if data[end] == quote {
+					quote = 0
+				}
+			} else if data[end] == '"' || data[end] == '\'' {
+				quote = data[end]
+			} else if data[end] == '(' {
+				depth++
+			} else if data[end] == ')' {
+				depth--
+				if depth == 0 {
+					token.value = string(data[position+1 : end])
+					return end + 1
+				}
 			}
-			helper.Arguments = append(helper.Arguments, *makeHelper(string(arg), unescaped, 0, []byte{}, nil))
 		}
+		token.value = string(data[position+1:])
+		return len(data)
 	}
-	if elseHelper != nil {
-		helper.Arguments = append(helper.Arguments, *elseHelper)
+	end := position
+	for end < len(data) && !isWhitespace(data[end]) {
+		end++
 	}
-	return helper
+	token.value = string(data[position:end])
+	return end
+}
+
+func isWhitespace(character byte) bool {
+	return character == ' ' || character == '\t' || character == '\n' || character == '\r'
+}
+
+func createArgument(token argumentToken, unescaped bool) *structure.Helper {
+	if token.key == "" {
+		if token.subexpression {
+			// The output of subexpressions is used by the helper, not written to the page
+			return createHelper([]byte(token.value), true, 0, []byte{}, nil, nil)
+		}
+		argument := makeHelper(token.value, unescaped, 0, []byte{}, nil)
+		argument.Quoted = token.quoted
+		return argument
+	}
+	value := token.value
+	if token.subexpression {
+		value = "(" + value + ")"
+	}
+	argument := makeHelper(token.key+"="+value, unescaped, 0, []byte{}, nil)
+	argument.Key = token.key
+	argument.Quoted = token.quoted
+	// Unquoted values that are helpers, paths, or subexpressions (e.g. heading=@blog.title or title=(encode title)) get
+	// a helper as their argument that is evaluated by helpers that support it
+	if token.subexpression {
+		argument.Arguments = []structure.Helper{*createHelper([]byte(token.value), true, 0, []byte{}, nil, nil)}
+	} else if !token.quoted && (helperFuctions[token.value] != nil || isPath(token.value)) {
+		argument.Arguments = []structure.Helper{*makeHelper(token.value, true, 0, []byte{}, nil)}
+	}
+	return argument
 }
 
 func makeHelper(tag string, unescaped bool, startPos int, block []byte, children []structure.Helper) *structure.Helper {
@@ -106,6 +185,10 @@ func findHelper(data []byte, allHelpers []structure.Helper) ([]byte, []structure
 		if bytes.HasPrefix(helperName, []byte("! ")) || bytes.HasPrefix(helperName, []byte("!--")) {
 			return findHelper(data, allHelpers)
 		}
+		// Separate the name of a partial (e.g. {{>"post/card"}})
+		if bytes.HasPrefix(helperName, []byte(">")) && !bytes.HasPrefix(helperName, []byte("> ")) {
+			helperName = bytes.Join([][]byte{[]byte(">"), helperName[len([]byte(">")):]}, []byte(" "))
+		}
 		// Check if block
 		if bytes.HasPrefix(helperName, []byte("#")) {
 			helperName = helperName[len([]byte("#")):] //remove '#' from helperName
@@ -180,22 +263,21 @@ func compileTemplate(data []byte, name string) *structure.Helper {
 	return &baseHelper
 }
 
-func createTemplateFromFile(filename string) (*structure.Helper, error) {
+func createTemplateFromFile(filename string, name string) (*structure.Helper, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	fileNameWithoutExtension := helpers.GetFilenameWithoutExtension(filename)
 	// Check if a helper with the same name is already in the map
-	if compiledTemplates.m[fileNameWithoutExtension] != nil {
-		return nil, errors.New("Error: Conflicting .hbs name '" + fileNameWithoutExtension + "'. A theme file of the same name already exists.")
+	if compiledTemplates.m[name] != nil {
+		return nil, errors.New("Error: Conflicting .hbs name '" + name + "'. A theme file of the same name already exists.")
 	}
-	helper := compileTemplate(data, fileNameWithoutExtension)
+	helper := compileTemplate(data, name)
 	return helper, nil
 }
 
-func compileFile(fileName string) error {
-	helper, err := createTemplateFromFile(fileName)
+func compileFile(fileName string, name string) error {
+	helper, err := createTemplateFromFile(fileName, name)
 	if err != nil {
 		return err
 	}
@@ -203,14 +285,26 @@ func compileFile(fileName string) error {
 	return nil
 }
 
-func inspectTemplateFile(filePath string, info os.FileInfo, err error) error {
-	if !info.IsDir() && filepath.Ext(filePath) == ".hbs" {
-		err := compileFile(filePath)
+// Returns the function to compile the .hbs files of a theme. Files in the partials folder are named by their path
+// inside of it (e.g. "post/card" for partials/post/card.hbs), all other files by their file name (e.g. "index").
+func inspectTemplateFiles(themePath string) filepath.WalkFunc {
+	partialsPath := filepath.Join(themePath, "partials")
+	return func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if !info.IsDir() && filepath.Ext(filePath) == ".hbs" {
+			name := helpers.GetFilenameWithoutExtension(filePath)
+			if relativePath, err := filepath.Rel(partialsPath, filePath); err == nil && !strings.HasPrefix(relativePath, "..") {
+				name = filepath.ToSlash(strings.TrimSuffix(relativePath, ".hbs"))
+			}
+			err := compileFile(filePath, name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	return nil
 }
 
 func compileTheme(themePath string) error {
@@ -218,7 +312,9 @@ func compileTheme(themePath string) error {
 	if _, err := os.Stat(themePath); os.IsNotExist(err) {
 		return errors.New("Couldn't find theme files in " + themePath + ": " + err.Error())
 	}
-	err := filepath.Walk(themePath, inspectTemplateFile)
+	// Remove the templates of a theme that couldn't be compiled before
+	compiledTemplates.m = make(map[string]*structure.Helper)
+	err := filepath.Walk(themePath, inspectTemplateFiles(themePath))
 	if err != nil {
 		return err
 	}
@@ -229,16 +325,20 @@ func compileTheme(themePath string) error {
 	if _, ok := compiledTemplates.m["post"]; !ok {
 		return errors.New("Couldn't compile template 'post'. Is post.hbs missing?")
 	}
+	err = checkPartials()
+	if err != nil {
+		return err
+	}
 	// Check if pagination and navigation templates have been provided by the theme.
 	// If not, use the build in ones.
 	if _, ok := compiledTemplates.m["pagination"]; !ok {
-		err = compileFile(filepath.Join(filenames.HbsFilepath, "pagination.hbs"))
+		err = compileFile(filepath.Join(filenames.HbsFilepath, "pagination.hbs"), "pagination")
 		if err != nil {
 			log.Println("Warning: Couldn't compile pagination template.")
 		}
 	}
 	if _, ok := compiledTemplates.m["navigation"]; !ok {
-		err = compileFile(filepath.Join(filenames.HbsFilepath, "navigation.hbs"))
+		err = compileFile(filepath.Join(filenames.HbsFilepath, "navigation.hbs"), "navigation")
 		if err != nil {
 			log.Println("Warning: Couldn't compile navigation template.")
 		}
@@ -247,6 +347,59 @@ func compileTheme(themePath string) error {
 	return nil
 }
 
+// Returns an error if a template includes itself, directly or through other partials (e.g. {{> "post/card"}} in
+// partials/post/card.hbs). Partials are included by name, so every possible recursion is found before the theme is used.
+func checkPartials() error {
+	names := make([]string, 0, len(compiledTemplates.m))
+	for name := range compiledTemplates.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Templates that can't lead to a recursion
+	checked := make(map[string]bool)
+	for _, name := range names {
+		err := findRecursivePartial(compiledTemplates.m[name], []string{name}, checked)
+		if err != nil {
+			return err
+		}
+		checked[name] = true
+	}
+	return nil
+}
+
+func findRecursivePartial(helper *structure.Helper, partials []string, checked map[string]bool) error {
+	if helper.Name == ">" && len(helper.Arguments) != 0 {
+		name := helper.Arguments[0].Name
+		for _, partial := range partials {
+			if partial == name {
+				return errors.New("Recursive partial: " + strings.Join(append(partials, name), " > "))
+			}
+		}
+		if template, ok := compiledTemplates.m[name]; ok && !checked[name] {
+			// Limit the capacity so that the partials of the caller aren't overwritten
+			err := findRecursivePartial(template, append(partials[:len(partials):len(partials)], name), checked)
+			if err != nil {
+				return err
+			}
+			checked[name] = true
+		}
+	}
+	for index := range helper.Children {
+		err := findRecursivePartial(&helper.Children[index], partials, checked)
+		if err != nil {
+			return err
+		}
+	}
+	// Else blocks and subexpressions
+	for index := range helper.Arguments {
+		err := findRecursivePartial(&helper.Arguments[index], partials, checked)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func checkThemes() error {
 	// Get currently set theme from database
 	activeTheme, err := database.RetrieveActiveTheme()
@@ -258,6 +411,7 @@ func checkThemes() error {
 	if err == nil {
 		return nil
 	}
+	log.Println("Warning: Couldn't compile theme "+*activeTheme+":", err)
 	// If the currently set theme couldnt be compiled, try the default theme (promenade)
 	err = compileTheme(filepath.Join(filenames.ThemesFilepath, "promenade"))
 	if err == nil {
@@ -2,10 +2,14 @@ package templates
 
 import (
 	"bytes"
+	"github.com/kabukky/journey/configuration"
 	"github.com/kabukky/journey/conversion"
 	"github.com/kabukky/journey/database"
 	"github.com/kabukky/journey/date"
+	"github.com/kabukky/journey/imaging"
+	"github.com/kabukky/journey/nql"
 	"github.com/kabukky/journey/plugins"
+	"github.com/kabukky/journey/storage"
 	"github.com/kabukky/journey/structure"
 	"github.com/kabukky/journey/structure/methods"
 	"html"
@@ -124,6 +128,8 @@ func paginationDotTotalFunc(helper *structure.Helper, values *structure.RequestD
 			return []byte{}
 		}
 		return []byte(strconv.FormatInt(count, 10))
+	} else if values.CurrentTemplate == 5 { // search
+		return []byte(strconv.FormatInt(values.CurrentSearchCount, 10))
 	}
 	return []byte{}
 }
@@ -180,6 +186,8 @@ func nextFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 			log.Println("Couldn't get number of posts for author", err.Error())
 			return []byte{}
 		}
+	} else if values.CurrentTemplate == 5 { // search
+		count = values.CurrentSearchCount
 	}
 	maxPages := positiveCeilingInt64(float64(count) / float64(values.Blog.PostsPerPage))
 	if int64(values.CurrentIndexPage) < maxPages {
@@ -209,6 +217,8 @@ func pagesFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 			log.Println("Couldn't get number of posts for author", err.Error())
 			return []byte{}
 		}
+	} else if values.CurrentTemplate == 5 { // search
+		count = values.CurrentSearchCount
 	}
 	maxPages := positiveCeilingInt64(float64(count) / float64(values.Blog.PostsPerPage))
 	// Output at least 1 (even if there are no posts in the database)
@@ -232,6 +242,8 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 						buffer.WriteString("/tag/")
 						//TODO: Error handling if there is no Posts[values.CurrentPostIndex]
 						buffer.WriteString(values.CurrentTag.Slug)
+					} else if values.CurrentTemplate == 5 { // search
+						buffer.WriteString("/search")
 					}
 					buffer.WriteString("/")
 				} else {
@@ -243,6 +255,8 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 						buffer.WriteString("/tag/")
 						//TODO: Error handling if there is no Posts[values.CurrentPostIndex]
 						buffer.WriteString(values.CurrentTag.Slug)
+					} else if values.CurrentTemplate == 5 { // search
+						buffer.WriteString("/search")
 					}
 					page := values.CurrentIndexPage - 1
 					if page > 1 {
@@ -251,6 +265,7 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 					}
 					buffer.WriteString("/")
 				}
+				writeSearchQuery(&buffer, values)
 				return buffer.Bytes()
 			}
 		} else if helper.Arguments[0].Name == "next" || helper.Arguments[0].Name == "pagination.next" {
@@ -270,6 +285,8 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 					log.Println("Couldn't get number of posts for author", err.Error())
 					return []byte{}
 				}
+			} else if values.CurrentTemplate == 5 { // search
+				count = values.CurrentSearchCount
 			}
 			maxPages := positiveCeilingInt64(float64(count) / float64(values.Blog.PostsPerPage))
 			if int64(values.CurrentIndexPage) < maxPages {
@@ -282,6 +299,8 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 					buffer.WriteString("/tag/")
 					// TODO: Error handling if there is no Posts[values.CurrentPostIndex]
 					buffer.WriteString(values.CurrentTag.Slug)
+				} else if values.CurrentTemplate == 5 { // search
+					buffer.WriteString("/search")
 				}
 				page := values.CurrentIndexPage + 1
 				if page > 1 {
@@ -289,6 +308,7 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 					buffer.WriteString(strconv.Itoa(page))
 				}
 				buffer.WriteString("/")
+				writeSearchQuery(&buffer, values)
 				return buffer.Bytes()
 			}
 		}
@@ -296,6 +316,18 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 	return []byte{}
 }
 
+// The search template needs the query in all of its page urls
+func writeSearchQuery(buffer *bytes.Buffer, values *structure.RequestData) {
+	if values.CurrentTemplate == 5 { // search
+		buffer.WriteString("?q=")
+		buffer.WriteString(url.QueryEscape(values.CurrentSearchQuery))
+	}
+}
+
+func search_queryFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return evaluateEscape([]byte(values.CurrentSearchQuery), helper.Unescaped)
+}
+
 func extendFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(helper.Arguments) != 0 {
 		return []byte(helper.Arguments[0].Name)
@@ -347,6 +379,13 @@ func body_classFunc(helper *structure.Helper, values *structure.RequestData) []b
 			buffer.WriteString(" paged archive-template")
 		}
 		return buffer.Bytes()
+	} else if values.CurrentTemplate == 5 { // search
+		var buffer bytes.Buffer
+		buffer.WriteString("search-template")
+		if values.CurrentIndexPage > 1 {
+			buffer.WriteString(" paged archive-template")
+		}
+		return buffer.Bytes()
 	}
 	// TODO: Delete this. Probably not needed.
 	return []byte("post-template")
@@ -360,13 +399,71 @@ func ghost_headFunc(helper *structure.Helper, values *structure.RequestData) []b
 	buffer.Write(evaluateEscape(values.Blog.Url, helper.Unescaped))
 	buffer.WriteString(values.CurrentPath)
 	buffer.WriteString("\">")
+	// Output feed discovery links
+	writeFeedLinks(&buffer, helper, values, values.Blog.Title, "")
+	if values.CurrentTemplate == 2 && values.CurrentTag != nil { // tag
+		writeFeedLinks(&buffer, helper, values, values.CurrentTag.Name, "/tag/"+values.CurrentTag.Slug)
+	} else if values.CurrentTemplate == 3 && len(values.Posts) > 0 && values.Posts[0].Author != nil { // author
+		writeFeedLinks(&buffer, helper, values, values.Posts[0].Author.Name, "/author/"+values.Posts[0].Author.Slug)
+	}
 	// TODO: structured data
+	// Output code injection of the blog and the post (never escaped)
+	writeCodeInjection(&buffer, values.Blog.CodeinjectionHead)
+	if post := codeInjectionPost(values); post != nil {
+		writeCodeInjection(&buffer, post.CodeinjectionHead)
+	}
 	return buffer.Bytes()
 }
 
+var feedLinkTypes = []struct {
+	format      string
+	contentType string
+}{
+	{FeedRss, "application/rss+xml"},
+	{FeedAtom, "application/atom+xml"},
+	{FeedJson, "application/feed+json"},
+}
+
+func writeFeedLinks(buffer *bytes.Buffer, helper *structure.Helper, values *structure.RequestData, title []byte, pathPrefix string) {
+	for _, feedType := range feedLinkTypes {
+		buffer.WriteString("\n<link rel=\"alternate\" type=\"")
+		buffer.WriteString(feedType.contentType)
+		buffer.WriteString("\" title=\"")
+		// Always escaped since it is an attribute value
+		buffer.Write(evaluateEscape(title, false))
+		buffer.WriteString("\" href=\"")
+		buffer.Write(evaluateEscape(values.Blog.Url, helper.Unescaped))
+		buffer.WriteString(FeedPath(pathPrefix, feedType.format))
+		buffer.WriteString("\">")
+	}
+}
+
 func ghost_footFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	// TODO: customized code injection
-	return []byte{}
+	// Output code injection of the blog and the post (never escaped)
+	var buffer bytes.Buffer
+	writeCodeInjection(&buffer, values.Blog.CodeinjectionFoot)
+	if post := codeInjectionPost(values); post != nil {
+		writeCodeInjection(&buffer, post.CodeinjectionFoot)
+	}
+	return buffer.Bytes()
+}
+
+// Returns the post of the post template, whose code injection is added to the one of the blog
+func codeInjectionPost(values *structure.RequestData) *structure.Post {
+	if values.CurrentTemplate == 1 && len(values.Posts) != 0 { // post
+		return &values.Posts[0]
+	}
+	return nil
+}
+
+func writeCodeInjection(buffer *bytes.Buffer, code []byte) {
+	if len(code) == 0 {
+		return
+	}
+	if buffer.Len() != 0 {
+		buffer.WriteString("\n")
+	}
+	buffer.Write(code)
 }
 
 func meta_titleFunc(helper *structure.Helper, values *structure.RequestData) []byte {
@@ -386,6 +483,12 @@ func meta_titleFunc(helper *structure.Helper, values *structure.RequestData) []b
 		buffer.WriteString(" - ")
 		buffer.Write(values.Blog.Title)
 		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
+	} else if values.CurrentTemplate == 5 { // search
+		var buffer bytes.Buffer
+		buffer.WriteString(values.CurrentSearchQuery)
+		buffer.WriteString(" - ")
+		buffer.Write(values.Blog.Title)
+		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
 	}
 	// index
 	return evaluateEscape(values.Blog.Title, helper.Unescaped)
@@ -407,12 +510,42 @@ func bodyFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 }
 
 func insertFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if len(helper.Arguments) != 0 {
-		if templateHelper, ok := compiledTemplates.m[helper.Arguments[0].Name]; ok {
-			return executeHelper(templateHelper, values, values.CurrentHelperContext)
+	if len(helper.Arguments) == 0 {
+		return []byte{}
+	}
+	name := helper.Arguments[0].Name
+	templateHelper, ok := compiledTemplates.m[name]
+	if !ok {
+		log.Println("Warning: Couldn't find partial:", name)
+		return []byte{}
+	}
+	for _, partial := range values.CurrentPartials {
+		if partial == name {
+			log.Println("Warning: Recursive partial:", strings.Join(append(values.CurrentPartials, name), " > "))
+			return []byte{}
 		}
 	}
-	return []byte{}
+	arguments := values.PartialArguments
+	partials := values.CurrentPartials
+	if len(helper.Arguments) > 1 {
+		// Hash arguments (e.g. {{> "post/card" title="Hello" featured=true}}) are added to the ones of the outer partials
+		partialArguments := make(map[string][]byte, len(arguments)+len(helper.Arguments)-1)
+		for key, value := range arguments {
+			partialArguments[key] = value
+		}
+		for index := 1; index < len(helper.Arguments); index++ {
+			// this is skipped because the partial is executed in the context of the caller anyway
+			if key, value, ok := evaluateHashArgument(&helper.Arguments[index], values); ok && helper.Arguments[index].Name != key+"=this" {
+				partialArguments[key] = value
+			}
+		}
+		values.PartialArguments = partialArguments
+	}
+	values.CurrentPartials = append(partials, name)
+	result := executeHelper(templateHelper, values, values.CurrentHelperContext)
+	values.PartialArguments = arguments
+	values.CurrentPartials = partials
+	return result
 }
 
 func encodeFunc(helper *structure.Helper, values *structure.RequestData) []byte {
@@ -462,6 +595,9 @@ func emailFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 }
 
 func websiteFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 5 { // comment
+		return evaluateEscape(values.Comments[values.CurrentCommentIndex].AuthorUrl, helper.Unescaped)
+	}
 	// TODO: Error handling if there is no Posts[values.CurrentPostIndex]
 	return evaluateEscape(values.Posts[values.CurrentPostIndex].Author.Website, helper.Unescaped)
 }
@@ -601,6 +737,12 @@ func urlFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	} else if values.CurrentHelperContext == 4 { // navigation
 		buffer.WriteString(values.Blog.NavigationItems[values.CurrentNavigationIndex].Url)
 		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
+	} else if values.CurrentHelperContext == 5 { // comment
+		buffer.WriteString("/")
+		buffer.WriteString(values.Posts[values.CurrentPostIndex].Slug)
+		buffer.WriteString("/#comment-")
+		buffer.WriteString(strconv.FormatInt(values.Comments[values.CurrentCommentIndex].Id, 10))
+		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
 	}
 	return []byte{}
 }
@@ -610,8 +752,70 @@ func titleFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 }
 
 func contentFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 5 { // comment
+		return conversion.GenerateHtmlFromText(values.Comments[values.CurrentCommentIndex].Content)
+	}
 	// TODO: is content always unescaped? seems like it...
-	return values.Posts[values.CurrentPostIndex].Html
+	// Add a srcset with the resized variants to uploaded images (and link them to the CDN if one is configured)
+	return storage.RewriteUrls(imaging.AddSrcset(values.Posts[values.CurrentPostIndex].Html, configuration.Config.Images.Sizes, imageVariantExists))
+}
+
+// Returns the url of an image in one of the sizes of the "Images" section in config.json, e.g. {{img_url image size="m"}}
+// or {{img_url @blog.cover size="l" format="webp"}}. Falls back to the original image if there is no such variant.
+func img_urlFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if len(helper.Arguments) == 0 {
+		return []byte{}
+	}
+	var image []byte
+	unescaped := &structure.Helper{Unescaped: true}
+	switch helper.Arguments[0].Name {
+	case "image", "feature_image":
+		image = imageFunc(unescaped, values)
+	case "author.image":
+		image = authorDotImageFunc(unescaped, values)
+	case "cover", "author.cover":
+		image = coverFunc(unescaped, values)
+	case "@blog.logo":
+		image = values.Blog.Logo
+	case "@blog.cover":
+		image = values.Blog.Cover
+	default:
+		image = []byte(helper.Arguments[0].Name)
+	}
+	extension := ""
+	width := 0
+	arguments := methods.ProcessHelperArguments(helper.Arguments[1:])
+	for key, value := range arguments {
+		if key == "size" {
+			width, _ = imaging.SizeWidth(configuration.Config.Images.Sizes, value)
+		} else if key == "format" && value == "webp" {
+			extension = ".webp"
+		}
+	}
+	if width != 0 {
+		variantPath := imaging.VariantPath(string(image), width, extension)
+		if variantPath != "" && imageVariantExists(variantPath) {
+			image = []byte(variantPath)
+		}
+	}
+	return evaluateEscape(cdnImageUrl(image), helper.Unescaped)
+}
+
+// Returns the CDN url of an uploaded image if a CDN is configured
+func cdnImageUrl(image []byte) []byte {
+	for _, prefix := range []string{"/images/", "/content/images/"} {
+		if bytes.HasPrefix(image, []byte(prefix)) {
+			if cdnUrl := storage.CdnUrl(string(image[len(prefix):])); cdnUrl != "" {
+				return []byte(cdnUrl)
+			}
+			break
+		}
+	}
+	return image
+}
+
+func imageVariantExists(variantPath string) bool {
+	return storage.Exists(strings.TrimPrefix(strings.TrimPrefix(variantPath, "/content"), "/images/"))
 }
 
 func excerptFunc(helper *structure.Helper, values *structure.RequestData) []byte {
@@ -656,9 +860,13 @@ func excerptFunc(helper *structure.Helper, values *structure.RequestData) []byte
 func dateFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	showPublicationDate := false
 	timeFormat := "MMM Do, YYYY" // Default time format
+	publicationDate := values.Posts[values.CurrentPostIndex].Date
 	// If in scope of a post, change default to published date
 	if values.CurrentHelperContext == 1 { // post
 		showPublicationDate = true
+	} else if values.CurrentHelperContext == 5 { // comment
+		showPublicationDate = true
+		publicationDate = values.Comments[values.CurrentCommentIndex].CreatedAt
 	}
 	// Get the date
 	if len(helper.Arguments) != 0 {
@@ -669,7 +877,7 @@ func dateFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 			} else if key == "timeago" {
 				if value == "true" {
 					// Compute time ago
-					return evaluateEscape(date.GenerateTimeAgo(values.Posts[values.CurrentPostIndex].Date), helper.Unescaped)
+					return evaluateEscape(date.GenerateTimeAgo(publicationDate), helper.Unescaped)
 				}
 			} else if key == "format" {
 				timeFormat = value
@@ -677,81 +885,72 @@ func dateFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 		}
 	}
 	if showPublicationDate {
-		return evaluateEscape(date.FormatDate(timeFormat, values.Posts[values.CurrentPostIndex].Date), helper.Unescaped)
+		return evaluateEscape(date.FormatDate(timeFormat, publicationDate), helper.Unescaped)
 	}
 	currentDate := date.GetCurrentTime()
 	return evaluateEscape(date.FormatDate(timeFormat, &currentDate), helper.Unescaped)
 }
 
 func atFirstFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if values.CurrentHelperContext == 1 { // post
-		if values.CurrentPostIndex == 0 {
-			return []byte{1}
-		}
-		return []byte{}
-	}
-	if values.CurrentHelperContext == 2 { // tag
-		if values.CurrentTagIndex == 0 {
-			return []byte{1}
-		}
-		return []byte{}
+	if values.CurrentIteration != nil && values.CurrentIteration.First() {
+		return []byte{1}
 	}
 	return []byte{}
 }
 
 func atLastFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if values.CurrentHelperContext == 1 { // post
-		if values.CurrentPostIndex == (len(values.Posts) - 1) {
-			return []byte{1}
-		}
-		return []byte{}
-	}
-	if values.CurrentHelperContext == 2 { // tag
-		if values.CurrentTagIndex == (len(values.Posts[values.CurrentPostIndex].Tags) - 1) {
-			return []byte{1}
-		}
-		return []byte{}
+	if values.CurrentIteration != nil && values.CurrentIteration.Last() {
+		return []byte{1}
 	}
 	return []byte{}
 }
 
 func atEvenFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if values.CurrentHelperContext == 1 { // post
-		// First post (index 0) needs to be odd
-		if values.CurrentPostIndex%2 == 1 {
-			return []byte{1}
-		}
-		return []byte{}
-	}
-	if values.CurrentHelperContext == 2 { // tag
-		// First tag (index 0) needs to be odd
-		if values.CurrentTagIndex%2 == 1 {
-			return []byte{1}
-		}
-		return []byte{}
+	if values.CurrentIteration != nil && values.CurrentIteration.Even() {
+		return []byte{1}
 	}
 	return []byte{}
 }
 
 func atOddFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if values.CurrentHelperContext == 1 { // post
-		// First post (index 0) needs to be odd
-		if values.CurrentPostIndex%2 == 0 {
-			return []byte{1}
-		}
-		return []byte{}
+	if values.CurrentIteration != nil && values.CurrentIteration.Odd() {
+		return []byte{1}
 	}
-	if values.CurrentHelperContext == 2 { // tag
-		// First tag (index 0) needs to be odd
-		if values.CurrentTagIndex%2 == 0 {
-			return []byte{1}
-		}
-		return []byte{}
+	return []byte{}
+}
+
+func atIndexFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentIteration != nil {
+		return []byte(strconv.Itoa(values.CurrentIteration.Index))
+	}
+	return []byte{}
+}
+
+func atNumberFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentIteration != nil {
+		return []byte(strconv.Itoa(values.CurrentIteration.Number()))
+	}
+	return []byte{}
+}
+
+func atRowStartFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentIteration != nil && values.CurrentIteration.RowStart() {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+func atRowEndFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentIteration != nil && values.CurrentIteration.RowEnd() {
+		return []byte{1}
 	}
 	return []byte{}
 }
 
 func nameFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 5 { // comment
+		return evaluateEscape(values.Comments[values.CurrentCommentIndex].AuthorName, helper.Unescaped)
+	}
 	// If tag (commented out the code for generating a link. Ghost doesn't seem to do that either).
 	if values.CurrentHelperContext == 2 { // tag
 		//var buffer bytes.Buffer
@@ -777,6 +976,22 @@ func nameFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	return evaluateEscape(values.Posts[values.CurrentPostIndex].Author.Name, helper.Unescaped)
 }
 
+// Name of the first tag of the post. As a block helper, the block is executed in the context of the tag (e.g. {{#primary_tag}}{{url}}{{/primary_tag}}).
+func primary_tagFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentPostIndex >= len(values.Posts) || len(values.Posts[values.CurrentPostIndex].Tags) == 0 {
+		return []byte{}
+	}
+	// Check if helper is block helper
+	if len(helper.Block) != 0 {
+		tagIndex := values.CurrentTagIndex
+		values.CurrentTagIndex = 0
+		result := executeHelper(helper, values, 2) // context = tag
+		values.CurrentTagIndex = tagIndex
+		return result
+	}
+	return evaluateEscape(values.Posts[values.CurrentPostIndex].Tags[0].Name, helper.Unescaped)
+}
+
 func tagDotNameFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(values.CurrentTag.Name) != 0 {
 		return evaluateEscape(values.CurrentTag.Name, helper.Unescaped)
@@ -794,9 +1009,57 @@ func tagDotSlugFunc(helper *structure.Helper, values *structure.RequestData) []b
 }
 
 func idFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 5 { // comment
+		return []byte(strconv.FormatInt(values.Comments[values.CurrentCommentIndex].Id, 10))
+	}
 	return []byte(strconv.FormatInt(values.Posts[values.CurrentPostIndex].Id, 10))
 }
 
+// Returns the number of approved comments of the post. Like the plural helper, it can output a text instead,
+// e.g. {{comment_count empty="No comments" singular="% comment" plural="% comments"}}.
+func comment_countFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	count, err := database.RetrieveNumberOfCommentsForPost(values.Posts[values.CurrentPostIndex].Id, structure.CommentApproved)
+	if err != nil {
+		log.Println("Couldn't get number of comments", err.Error())
+		return []byte{}
+	}
+	countString := strconv.FormatInt(count, 10)
+	if len(helper.Arguments) != 0 {
+		arguments := methods.ProcessHelperArguments(helper.Arguments)
+		for key, value := range arguments {
+			if (count == 0 && key == "empty") || (count == 1 && key == "singular") || (count > 1 && key == "plural") {
+				return evaluateEscape([]byte(strings.Replace(value, "%", countString, -1)), helper.Unescaped)
+			}
+		}
+	}
+	return []byte(countString)
+}
+
+// Possible if argument: {{#if comments}}
+func commentsFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	count, err := database.RetrieveNumberOfCommentsForPost(values.Posts[values.CurrentPostIndex].Id, structure.CommentApproved)
+	if err == nil && count > 0 {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// Id of the comment a reply answers (empty for top-level comments)
+func parent_idFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 5 && values.Comments[values.CurrentCommentIndex].Depth > 0 { // comment
+		return []byte(strconv.FormatInt(values.Comments[values.CurrentCommentIndex].ParentId, 10))
+	}
+	return []byte{}
+}
+
+// Level of a reply in its thread (0 for top-level comments)
+func depthFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 5 { // comment
+		return []byte(strconv.Itoa(values.Comments[values.CurrentCommentIndex].Depth))
+	}
+	return []byte{}
+}
+
 func assetFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(helper.Arguments) != 0 {
 		var buffer bytes.Buffer
@@ -807,34 +1070,41 @@ func assetFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	return []byte{}
 }
 
+// Block helper to iterate posts, tags, authors, navigation items, or comments, e.g.
+// {{#foreach posts limit="3" from="2" columns="3"}}...{{else}}No posts.{{/foreach}}. See executeIteration for the arguments.
 func foreachFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(helper.Arguments) != 0 {
 		switch helper.Arguments[0].Name {
 		case "posts":
-			var buffer bytes.Buffer
-			for index, _ := range values.Posts {
-				//if values.Posts[index].Id != 0 { // If post is not empty (Commented out for now. This was only neccessary in previous versions, when the array length was always the postsPerPage length)
+			return executeIteration(helper, values, 1, len(values.Posts), func(index int) { // context = post
 				values.CurrentPostIndex = index
-				buffer.Write(executeHelper(helper, values, 1)) // context = post
-				//}
-			}
-			return buffer.Bytes()
+			})
 		case "tags":
-			var buffer bytes.Buffer
-			for index, _ := range values.Posts[values.CurrentPostIndex].Tags {
-				//if values.Posts[values.CurrentPostIndex].Tags[index].Id != 0 { // If tag is not empty (Commented out for now. Not neccessary.)
+			return executeIteration(helper, values, 2, len(values.Posts[values.CurrentPostIndex].Tags), func(index int) { // context = tag
 				values.CurrentTagIndex = index
-				buffer.Write(executeHelper(helper, values, 2)) // context = tag
-				//}
+			})
+		case "authors":
+			// A post has one author. The authors of {{#get "authors"}} are attached to one post each.
+			if values.CurrentHelperContext != 6 { // get
+				return executeIteration(helper, values, 3, 1, func(index int) {}) // context = author
 			}
-			return buffer.Bytes()
+			return executeIteration(helper, values, 3, len(values.Posts), func(index int) { // context = author
+				values.CurrentPostIndex = index
+			})
 		case "navigation":
-			var buffer bytes.Buffer
-			for index, _ := range values.Blog.NavigationItems {
+			return executeIteration(helper, values, 4, len(values.Blog.NavigationItems), func(index int) { // context = navigation
 				values.CurrentNavigationIndex = index
-				buffer.Write(executeHelper(helper, values, 4)) // context = navigation
+			})
+		case "comments":
+			comments, err := methods.RetrieveCommentThreads(values.Posts[values.CurrentPostIndex].Id)
+			if err != nil {
+				log.Println("Couldn't get comments for post", err.Error())
+				return []byte{}
 			}
-			return buffer.Bytes()
+			values.Comments = comments
+			return executeIteration(helper, values, 5, len(values.Comments), func(index int) { // context = comment
+				values.CurrentCommentIndex = index
+			})
 		default:
 			return []byte{}
 		}
@@ -842,16 +1112,100 @@ func foreachFunc(helper *structure.Helper, values *structure.RequestData) []byte
 	return []byte{}
 }
 
+// Block helper to query posts, pages, tags, or authors anywhere in a theme, e.g.
+// {{#get "posts" limit="5" filter="tag:news+featured:true" order="published_at desc"}}. The block is executed once with
+// the result (context = get) and iterates it with {{#foreach posts}} (or tags, or authors). The else block is executed if
+// nothing was found. The limit defaults to 15 ("all" for no limit), page selects further results.
+func getFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if len(helper.Arguments) == 0 {
+		return []byte{}
+	}
+	resource := helper.Arguments[0].Name
+	arguments := methods.ProcessHelperArguments(helper.Arguments)
+	limit := int64(15)
+	if value, ok := arguments["limit"]; ok {
+		if value == "all" {
+			limit = 0
+		} else if number, err := strconv.ParseInt(value, 10, 64); err == nil && number > 0 {
+			limit = number
+		}
+	}
+	page := int64(1)
+	if number, err := strconv.ParseInt(arguments["page"], 10, 64); err == nil && number > 0 {
+		page = number
+	}
+	filter, err := nql.Parse(arguments["filter"])
+	if err != nil {
+		log.Println("Couldn't parse filter of get helper:", err)
+		return []byte{}
+	}
+	// The result replaces the posts of the request only inside the block
+	data := *values
+	found := false
+	switch resource {
+	case "posts", "pages":
+		posts, err := database.RetrievePostsByFilter(filter, arguments["order"], resource == "pages", limit, (page-1)*limit)
+		if err != nil {
+			log.Println("Couldn't get posts for get helper:", err)
+			return []byte{}
+		}
+		data.Posts = posts
+		found = len(posts) != 0
+	case "tags":
+		tags, err := database.RetrieveTagsByFilter(filter, arguments["order"], limit, (page-1)*limit)
+		if err != nil {
+			log.Println("Couldn't get tags for get helper:", err)
+			return []byte{}
+		}
+		data.Posts = []structure.Post{structure.Post{Tags: tags}}
+		found = len(tags) != 0
+	case "authors":
+		users, err := database.RetrieveAuthorsByFilter(filter, arguments["order"], limit, (page-1)*limit)
+		if err != nil {
+			log.Println("Couldn't get authors for get helper:", err)
+			return []byte{}
+		}
+		data.Posts = make([]structure.Post, len(users))
+		for index, _ := range users {
+			data.Posts[index].Author = &users[index]
+		}
+		found = len(users) != 0
+	default:
+		log.Println("Unknown resource in get helper: " + resource)
+		return []byte{}
+	}
+	data.CurrentPostIndex = 0
+	data.CurrentTagIndex = 0
+	data.PartialArguments = nil
+	if found {
+		output := executeHelper(helper, &data, 6) // context = get
+		// Keep the plugin states that were attached during the execution of the block
+		values.PluginVMs = data.PluginVMs
+		return output
+	} else if helper.Arguments[len(helper.Arguments)-1].Name == "else" {
+		return executeHelper(&helper.Arguments[len(helper.Arguments)-1], values, values.CurrentHelperContext)
+	}
+	return []byte{}
+}
+
+// Number of published posts of a tag or an author that was loaded by {{#get}}
+func countDotPostsFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentHelperContext == 2 { // tag
+		return []byte(strconv.FormatInt(values.Posts[values.CurrentPostIndex].Tags[values.CurrentTagIndex].PostCount, 10))
+	} else if values.CurrentHelperContext == 3 { // author
+		return []byte(strconv.FormatInt(values.Posts[values.CurrentPostIndex].Author.PostCount, 10))
+	}
+	return []byte{}
+}
+
 func ifFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(helper.Arguments) != 0 {
-		if len(helper.Arguments[0].Function(&helper.Arguments[0], values)) != 0 {
+		if isTruthy(&helper.Arguments[0], values) {
 			// If the evaluation is true, execute the if helper
 			return executeHelper(helper, values, values.CurrentHelperContext)
 		} else {
 			// Else execute the else helper which is always at the last index of the if helper Arguments
 			if helper.Arguments[len(helper.Arguments)-1].Name == "else" {
-				if len(helper.Arguments[len(helper.Arguments)-1].Children) != 0 {
-				}
 				return executeHelper(&helper.Arguments[len(helper.Arguments)-1], values, values.CurrentHelperContext)
 			}
 		}
@@ -861,7 +1215,7 @@ func ifFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 
 func unlessFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(helper.Arguments) != 0 {
-		if len(helper.Arguments[0].Function(&helper.Arguments[0], values)) == 0 {
+		if !isTruthy(&helper.Arguments[0], values) {
 			// If the evaluation is false, execute the unless helper
 			return executeHelper(helper, values, values.CurrentHelperContext)
 		}
@@ -869,6 +1223,148 @@ func unlessFunc(helper *structure.Helper, values *structure.RequestData) []byte
 	return []byte{}
 }
 
+// Block helper to compare values, e.g. {{#match title "About"}}, {{#match @number ">=" 3}}, or {{#match featured}}.
+// Operators are =, !=, <, >, <=, and >=. Also works as a subexpression: {{#if (match primary_tag.slug "news")}}.
+func matchFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	arguments := positionalArguments(helper)
+	condition := false
+	switch len(arguments) {
+	case 1:
+		condition = isTruthy(&arguments[0], values)
+	case 2:
+		condition = compareValues(string(evaluateArgument(&arguments[0], values)), "=", string(evaluateArgument(&arguments[1], values)))
+	case 3:
+		condition = compareValues(string(evaluateArgument(&arguments[0], values)), string(evaluateArgument(&arguments[1], values)), string(evaluateArgument(&arguments[2], values)))
+	}
+	return executeCondition(helper, values, condition)
+}
+
+// Block helper to test the current post, e.g. {{#has tag="news, Getting started"}}, {{#has author="jane"}}, {{#has slug="about"}},
+// {{#has id="3"}}, or {{#has number="nth:3"}} (every third item of a loop, index counts from 0 instead). Lists match if one of their
+// values does, tag and author also take count:2, count:>2, or count:<2.
+func hasFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	if values.CurrentPostIndex >= len(values.Posts) {
+		return executeCondition(helper, values, false)
+	}
+	post := &values.Posts[values.CurrentPostIndex]
+	condition := false
+	for key, value := range methods.ProcessHelperArguments(helper.Arguments) {
+		list := splitList(value)
+		switch key {
+		case "tag":
+			names := make([]string, 0, len(post.Tags))
+			for _, tag := range post.Tags {
+				names = append(names, string(tag.Name), tag.Slug)
+			}
+			condition = condition || hasValue(list, names, len(post.Tags))
+		case "author":
+			if post.Author != nil {
+				condition = condition || hasValue(list, []string{string(post.Author.Name), post.Author.Slug}, 1)
+			}
+		case "slug":
+			condition = condition || hasValue(list, []string{post.Slug}, 1)
+		case "id":
+			condition = condition || hasValue(list, []string{strconv.FormatInt(post.Id, 10)}, 1)
+		case "number", "index":
+			if values.CurrentIteration != nil {
+				number := values.CurrentIteration.Number()
+				if key == "index" {
+					number = values.CurrentIteration.Index
+				}
+				condition = condition || hasNumber(list, number)
+			}
+		}
+	}
+	return executeCondition(helper, values, condition)
+}
+
+// Returns true if one of the list values is one of the names (ignoring case) or a count:2 value matches the count
+func hasValue(list []string, names []string, count int) bool {
+	for _, value := range list {
+		if strings.HasPrefix(value, "count:") {
+			value = value[len("count:"):]
+			operator := "="
+			if strings.HasPrefix(value, ">") || strings.HasPrefix(value, "<") {
+				operator = value[:1]
+				value = value[1:]
+			}
+			if compareValues(strconv.Itoa(count), operator, value) {
+				return true
+			}
+			continue
+		}
+		for _, name := range names {
+			if strings.EqualFold(name, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns true if one of the list values is the number or an nth:3 value divides it
+func hasNumber(list []string, number int) bool {
+	for _, value := range list {
+		if strings.HasPrefix(value, "nth:") {
+			if divisor, err := strconv.Atoi(value[len("nth:"):]); err == nil && divisor > 0 && number%divisor == 0 {
+				return true
+			}
+		} else if value == strconv.Itoa(number) {
+			return true
+		}
+	}
+	return false
+}
+
+// Block helper to test the template, e.g. {{#is "home, paged"}}. The contexts are home (the first page of the index),
+// index, paged (further pages of the index, a tag, or an author), post, page, tag, author, and search.
+func isFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	arguments := positionalArguments(helper)
+	condition := false
+	if len(arguments) != 0 {
+		for _, context := range splitList(string(evaluateArgument(&arguments[0], values))) {
+			if isContext(context, values) {
+				condition = true
+				break
+			}
+		}
+	}
+	return executeCondition(helper, values, condition)
+}
+
+func isContext(context string, values *structure.RequestData) bool {
+	switch context {
+	case "home":
+		return values.CurrentTemplate == 0 && values.CurrentIndexPage <= 1 // index
+	case "index":
+		return values.CurrentTemplate == 0 // index
+	case "paged":
+		return (values.CurrentTemplate == 0 || values.CurrentTemplate == 2 || values.CurrentTemplate == 3) && values.CurrentIndexPage > 1 // index, tag, or author
+	case "post":
+		return values.CurrentTemplate == 1 && len(values.Posts) != 0 && !values.Posts[0].IsPage // post
+	case "page":
+		return values.CurrentTemplate == 1 && len(values.Posts) != 0 && values.Posts[0].IsPage // post
+	case "tag":
+		return values.CurrentTemplate == 2 // tag
+	case "author":
+		return values.CurrentTemplate == 3 // author
+	case "search":
+		return values.CurrentTemplate == 5 // search
+	}
+	return false
+}
+
+// Splits a comma separated argument (e.g. "home, paged")
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
 func atBlogDotTitleFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	return evaluateEscape(values.Blog.Title, helper.Unescaped)
 }
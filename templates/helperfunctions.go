@@ -13,6 +13,7 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"if":               ifFunc,
 	"unless":           unlessFunc,
 	"foreach":          foreachFunc,
+	"get":              getFunc,
 	"!<":               extendFunc,
 	"body":             bodyFunc,
 	"asset":            assetFunc,
@@ -26,8 +27,13 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"plural":           pluralFunc,
 	"date":             dateFunc,
 	"image":            imageFunc,
+	"img_url":          img_urlFunc,
 	"contentFor":       contentForFunc,
 	"block":            blockFunc,
+	"search_query":     search_queryFunc,
+	"match":            matchFunc,
+	"has":              hasFunc,
+	"is":               isFunc,
 
 	// @blog functions
 	"@blog.title":       atBlogDotTitleFunc,
@@ -48,11 +54,13 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"post.id":    idFunc,
 
 	// Tag functions
-	"tag.name": tagDotNameFunc,
-	"tag.slug": tagDotSlugFunc,
+	"tag.name":    tagDotNameFunc,
+	"tag.slug":    tagDotSlugFunc,
+	"primary_tag": primary_tagFunc,
 
 	// Author functions
 	"author":          authorFunc,
+	"primary_author":  authorFunc,
 	"bio":             bioFunc,
 	"email":           emailFunc,
 	"website":         websiteFunc,
@@ -66,6 +74,12 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"author.cover":    coverFunc,
 	"author.location": locationFunc,
 
+	// Comment functions
+	"comment_count": comment_countFunc,
+	"comments":      commentsFunc,
+	"parent_id":     parent_idFunc,
+	"depth":         depthFunc,
+
 	// Navigation functions
 	"navigation": navigationFunc,
 	"label":      labelFunc,
@@ -73,12 +87,16 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"slug":       slugFunc,
 
 	// Multiple block functions
-	"@first": atFirstFunc,
-	"@last":  atLastFunc,
-	"@even":  atEvenFunc,
-	"@odd":   atOddFunc,
-	"name":   nameFunc,
-	"url":    urlFunc,
+	"@first":    atFirstFunc,
+	"@last":     atLastFunc,
+	"@even":     atEvenFunc,
+	"@odd":      atOddFunc,
+	"@index":    atIndexFunc,
+	"@number":   atNumberFunc,
+	"@rowStart": atRowStartFunc,
+	"@rowEnd":   atRowEndFunc,
+	"name":      nameFunc,
+	"url":       urlFunc,
 
 	// Pagination functions
 	"pagination": paginationFunc,
@@ -98,4 +116,5 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	// Possible plural arguments
 	"pagination.total":    paginationDotTotalFunc,
 	"../pagination.total": paginationDotTotalFunc,
+	"count.posts":         countDotPostsFunc,
 }
@@ -2,30 +2,58 @@ package templates
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/kabukky/feeds"
+	"github.com/kabukky/journey/configuration"
+	"github.com/kabukky/journey/conversion"
 	"github.com/kabukky/journey/database"
-	"github.com/kabukky/journey/date"
 	"github.com/kabukky/journey/structure"
 	"github.com/kabukky/journey/structure/methods"
-	"net/http"
 )
 
-func ShowIndexRss(writer http.ResponseWriter) error {
+// Feed formats
+const (
+	FeedRss  = "rss"
+	FeedAtom = "atom"
+	FeedJson = "json"
+)
+
+// 15 posts in feeds for now
+const postsPerFeed = 15
+
+// Number of words of a post in feeds that are set to excerpts (like the default of the excerpt helper)
+const feedExcerptWords = 50
+
+// Returns the path of a feed, e.g. /tag/news/atom/ (pathPrefix is "" for the index, "/tag/news" for a tag, or "/author/john" for an author)
+func FeedPath(pathPrefix string, format string) string {
+	switch format {
+	case FeedAtom:
+		return pathPrefix + "/atom/"
+	case FeedJson:
+		return pathPrefix + "/feed.json"
+	}
+	return pathPrefix + "/rss/"
+}
+
+func ShowIndexFeed(writer http.ResponseWriter, request *http.Request, format string) error {
 	// Read lock global blog
 	methods.Blog.RLock()
 	defer methods.Blog.RUnlock()
-	// 15 posts in rss for now
-	posts, err := database.RetrievePostsForIndex(15, 0)
+	posts, err := database.RetrievePostsForIndex(postsPerFeed, 0)
 	if err != nil {
 		return err
 	}
 	blogData := &structure.RequestData{Posts: posts, Blog: methods.Blog}
-	feed := createFeed(blogData)
-	err = feed.WriteRss(writer)
-	return err
+	return writeFeed(writer, request, blogData, string(methods.Blog.Title), "", format)
 }
 
-func ShowTagRss(writer http.ResponseWriter, slug string) error {
+func ShowTagFeed(writer http.ResponseWriter, request *http.Request, slug string, format string) error {
 	// Read lock global blog
 	methods.Blog.RLock()
 	defer methods.Blog.RUnlock()
@@ -33,18 +61,15 @@ func ShowTagRss(writer http.ResponseWriter, slug string) error {
 	if err != nil {
 		return err
 	}
-	// 15 posts in rss for now
-	posts, err := database.RetrievePostsByTag(tag.Id, 15, 0)
+	posts, err := database.RetrievePostsByTag(tag.Id, postsPerFeed, 0)
 	if err != nil {
 		return err
 	}
 	blogData := &structure.RequestData{Posts: posts, Blog: methods.Blog}
-	feed := createFeed(blogData)
-	err = feed.WriteRss(writer)
-	return err
+	return writeFeed(writer, request, blogData, string(tag.Name)+" - "+string(methods.Blog.Title), "/tag/"+tag.Slug, format)
 }
 
-func ShowAuthorRss(writer http.ResponseWriter, slug string) error {
+func ShowAuthorFeed(writer http.ResponseWriter, request *http.Request, slug string, format string) error {
 	// Read lock global blog
 	methods.Blog.RLock()
 	defer methods.Blog.RUnlock()
@@ -52,30 +77,111 @@ func ShowAuthorRss(writer http.ResponseWriter, slug string) error {
 	if err != nil {
 		return err
 	}
-	// 15 posts in rss for now
-	posts, err := database.RetrievePostsByUser(author.Id, 15, 0)
+	posts, err := database.RetrievePostsByUser(author.Id, postsPerFeed, 0)
 	if err != nil {
 		return err
 	}
 	blogData := &structure.RequestData{Posts: posts, Blog: methods.Blog}
-	feed := createFeed(blogData)
-	err = feed.WriteRss(writer)
+	return writeFeed(writer, request, blogData, string(author.Name)+" - "+string(methods.Blog.Title), "/author/"+author.Slug, format)
+}
+
+// Writes the feed in the requested format. Answers with 304 Not Modified if the client already has the current version (ETag or Last-Modified).
+func writeFeed(writer http.ResponseWriter, request *http.Request, values *structure.RequestData, title string, pathPrefix string, format string) error {
+	var buffer bytes.Buffer
+	var contentType string
+	var err error
+	lastModified := feedLastModified(values.Posts)
+	switch format {
+	case FeedAtom:
+		contentType = "application/atom+xml; charset=utf-8"
+		feed := createFeed(values, title, pathPrefix, format, lastModified)
+		// Atom ids must be IRIs
+		for _, item := range feed.Items {
+			item.Id = "urn:uuid:" + item.Id
+		}
+		err = feed.WriteAtom(&buffer)
+	case FeedJson:
+		contentType = "application/feed+json; charset=utf-8"
+		err = json.NewEncoder(&buffer).Encode(createJsonFeed(values, title, pathPrefix))
+	default:
+		contentType = "application/rss+xml; charset=utf-8"
+		err = createFeed(values, title, pathPrefix, format, lastModified).WriteRss(&buffer)
+	}
+	if err != nil {
+		return err
+	}
+	hash := sha1.Sum(buffer.Bytes())
+	etag := "\"" + hex.EncodeToString(hash[:10]) + "\""
+	writer.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		writer.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if feedIsNotModified(request, etag, lastModified) {
+		writer.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	writer.Header().Set("Content-Type", contentType)
+	_, err = writer.Write(buffer.Bytes())
 	return err
 }
 
-func createFeed(values *structure.RequestData) *feeds.Feed {
-	now := date.GetCurrentTime()
+// If-None-Match takes precedence over If-Modified-Since (RFC 7232)
+func feedIsNotModified(request *http.Request, etag string, lastModified time.Time) bool {
+	if match := request.Header.Get("If-None-Match"); match != "" {
+		for _, value := range strings.Split(match, ",") {
+			value = strings.TrimSpace(value)
+			if value == etag || value == "W/"+etag || value == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since := request.Header.Get("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		sinceTime, err := http.ParseTime(since)
+		// The header only has a precision of seconds
+		return err == nil && !lastModified.Truncate(time.Second).After(sinceTime)
+	}
+	return false
+}
+
+// The most recent publication or update date of the posts
+func feedLastModified(posts []structure.Post) time.Time {
+	var lastModified time.Time
+	for index, _ := range posts {
+		for _, date := range []*time.Time{posts[index].Date, posts[index].UpdatedAt} {
+			if date != nil && date.After(lastModified) {
+				lastModified = *date
+			}
+		}
+	}
+	return lastModified
+}
+
+// Returns the content of a post for feeds: the html or an excerpt (if "FeedContent" is set to "excerpt" in config.json)
+func feedContent(post *structure.Post) (string, bool) {
+	if configuration.Config.FeedContent != "excerpt" {
+		return string(post.Html), true
+	}
+	words := bytes.Fields(conversion.StripTagsFromHtml(post.Html))
+	if len(words) > feedExcerptWords {
+		return string(bytes.Join(words[:feedExcerptWords], []byte(" "))) + "…", false
+	}
+	return string(bytes.Join(words, []byte(" "))), false
+}
+
+func createFeed(values *structure.RequestData, title string, pathPrefix string, format string, lastModified time.Time) *feeds.Feed {
 	feed := &feeds.Feed{
-		Title:       string(values.Blog.Title),
+		Title:       title,
 		Description: string(values.Blog.Description),
-		Link:        &feeds.Link{Href: string(values.Blog.Url)},
-		Updated:     now,
+		Link:        &feeds.Link{Href: string(values.Blog.Url) + pathPrefix + "/"},
+		// The update time only changes with the content (so that the ETag stays the same)
+		Updated: lastModified,
 		Image: &feeds.Image{
 			Url:   string(values.Blog.Url) + string(values.Blog.Logo),
 			Title: string(values.Blog.Title),
 			Link:  string(values.Blog.Url),
 		},
-		Url: string(values.Blog.Url) + "/rss/",
+		Url: string(values.Blog.Url) + FeedPath(pathPrefix, format),
 	}
 	for i := 0; i < len(values.Posts); i++ {
 		if values.Posts[i].Id != 0 {
@@ -84,14 +190,18 @@ func createFeed(values *structure.RequestData) *feeds.Feed {
 			buffer.Write(values.Blog.Url)
 			buffer.WriteString("/")
 			buffer.WriteString(values.Posts[i].Slug)
+			content, _ := feedContent(&values.Posts[i])
 			item := &feeds.Item{
 				Title:       string(values.Posts[i].Title),
-				Description: string(values.Posts[i].Html),
+				Description: content,
 				Link:        &feeds.Link{Href: buffer.String()},
 				Id:          string(values.Posts[i].Uuid),
 				Author:      &feeds.Author{Name: string(values.Posts[i].Author.Name), Email: ""},
 				Created:     *values.Posts[i].Date,
 			}
+			if values.Posts[i].UpdatedAt != nil {
+				item.Updated = *values.Posts[i].UpdatedAt
+			}
 			// If the post has a cover image, add it to the item
 			image := string(values.Posts[i].Image)
 			if image != "" {
@@ -105,3 +215,82 @@ func createFeed(values *structure.RequestData) *feeds.Feed {
 
 	return feed
 }
+
+// JSON Feed 1.1 (https://jsonfeed.org/version/1.1)
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageUrl string           `json:"home_page_url"`
+	FeedUrl     string           `json:"feed_url"`
+	Description string           `json:"description,omitempty"`
+	Icon        string           `json:"icon,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedItem struct {
+	Id            string           `json:"id"`
+	Url           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHtml   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished *time.Time       `json:"date_published,omitempty"`
+	DateModified  *time.Time       `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name   string `json:"name"`
+	Url    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+func createJsonFeed(values *structure.RequestData, title string, pathPrefix string) *jsonFeed {
+	blogUrl := string(values.Blog.Url)
+	feed := &jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       title,
+		HomePageUrl: blogUrl + pathPrefix + "/",
+		FeedUrl:     blogUrl + FeedPath(pathPrefix, FeedJson),
+		Description: string(values.Blog.Description),
+		Items:       make([]jsonFeedItem, 0),
+	}
+	if len(values.Blog.Logo) != 0 {
+		feed.Icon = blogUrl + string(values.Blog.Logo)
+	}
+	for i := 0; i < len(values.Posts); i++ {
+		post := &values.Posts[i]
+		if post.Id == 0 {
+			continue
+		}
+		item := jsonFeedItem{
+			Id:            string(post.Uuid),
+			Url:           blogUrl + "/" + post.Slug + "/",
+			Title:         string(post.Title),
+			DatePublished: post.Date,
+			DateModified:  post.UpdatedAt,
+		}
+		if content, isHtml := feedContent(post); isHtml {
+			item.ContentHtml = content
+		} else {
+			item.ContentText = content
+		}
+		if len(post.Image) != 0 {
+			item.Image = blogUrl + string(post.Image)
+		}
+		if post.Author != nil {
+			author := jsonFeedAuthor{Name: string(post.Author.Name), Url: blogUrl + "/author/" + post.Author.Slug + "/"}
+			if len(post.Author.Image) != 0 {
+				author.Avatar = blogUrl + string(post.Author.Image)
+			}
+			item.Authors = []jsonFeedAuthor{author}
+		}
+		for _, tag := range post.Tags {
+			item.Tags = append(item.Tags, string(tag.Name))
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
@@ -0,0 +1,225 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kabukky/journey/configuration"
+	"github.com/kabukky/journey/database"
+	"github.com/kabukky/journey/structure"
+)
+
+// The templates are rendered with the posts of a temporary SQLite database
+func TestMain(m *testing.M) {
+	directory, err := ioutil.TempDir("", "journey-templates")
+	if err != nil {
+		panic(err)
+	}
+	configuration.Config.DatabaseDialect = database.DialectSqlite
+	configuration.Config.DatabaseConnection = filepath.Join(directory, "journey.db")
+	err = database.Initialize()
+	if err == nil {
+		err = insertTestPosts()
+	}
+	if err != nil {
+		os.RemoveAll(directory)
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(directory)
+	os.Exit(code)
+}
+
+func insertTestPosts() error {
+	createdAt := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	userId, err := database.InsertUser([]byte("Jane"), "jane", "", []byte("jane@example.com"), nil, nil, createdAt, 1)
+	if err != nil {
+		return err
+	}
+	tagId, err := database.InsertTag([]byte("News"), "news", createdAt, userId)
+	if err != nil {
+		return err
+	}
+	posts := []struct {
+		title     string
+		published bool
+		tagged    bool
+	}{
+		{"Alpha", true, true},
+		{"Beta", true, false},
+		{"Gamma", true, true},
+		{"Delta", false, true},
+	}
+	for index, post := range posts {
+		postId, err := database.InsertPost([]byte(post.title), post.title, nil, nil, false, false, post.published, nil, nil, nil, nil, createdAt.Add(time.Duration(index)*time.Hour), userId, nil)
+		if err != nil {
+			return err
+		}
+		if post.tagged {
+			err = database.InsertPostTag(postId, tagId)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Compiles the template and executes it in the index context
+func render(template string, values *structure.RequestData) string {
+	if values.Blog == nil {
+		values.Blog = &structure.Blog{}
+	}
+	return string(executeHelper(compileTemplate([]byte(template), "test"), values, 0))
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{#get "posts" filter="tag:news" order="title asc"}}{{#foreach posts}}{{title}} {{/foreach}}{{/get}}`, "Alpha Gamma "},
+		{`{{#get "posts" filter="tag:news" order="title desc" limit="1"}}{{#foreach posts}}{{title}}{{/foreach}}{{/get}}`, "Gamma"},
+		{`{{#get "posts" filter="tag:-news" limit="all"}}{{#foreach posts}}{{title}}{{/foreach}}{{/get}}`, "Beta"},
+		{`{{#get "posts" order="published_at desc" limit="2"}}{{#foreach posts}}{{title}} {{/foreach}}{{/get}}`, "Gamma Beta "},
+		{`{{#get "posts" filter="tag:sports"}}{{title}}{{else}}No posts{{/get}}`, "No posts"},
+		{`{{#get "tags" filter="slug:news"}}{{#foreach tags}}{{name}}: {{count.posts}}{{/foreach}}{{/get}}`, "News: 2"},
+	}
+	for _, test := range tests {
+		if got := render(test.template, &structure.RequestData{}); got != test.want {
+			t.Errorf("%s = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestRecursivePartial(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journey-theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	files := map[string]string{
+		"index.hbs":               `{{> "post/card"}}`,
+		"post.hbs":                `{{title}}`,
+		"partials/post/card.hbs":  `{{#if title}}{{> "post/title"}}{{/if}}`,
+		"partials/post/title.hbs": `{{#foreach tags}}{{else}}{{> "post/card"}}{{/foreach}}`,
+	}
+	for name, content := range files {
+		err = os.MkdirAll(filepath.Dir(filepath.Join(directory, name)), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = compileTheme(directory)
+	if err == nil || err.Error() != "Recursive partial: index > post/card > post/title > post/card" {
+		t.Errorf("compileTheme = %v, want the recursion of post/card", err)
+	}
+	// Without the recursion, the theme compiles
+	err = ioutil.WriteFile(filepath.Join(directory, "partials", "post", "title.hbs"), []byte(`<h1>{{title}}</h1>`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = compileTheme(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(executeHelper(compiledTemplates.m["index"], &structure.RequestData{Posts: []structure.Post{structure.Post{Title: []byte("Hello")}}, Blog: &structure.Blog{}}, 0))
+	if got != "<h1>Hello</h1>" {
+		t.Errorf("index = %q, want %q", got, "<h1>Hello</h1>")
+	}
+}
+
+func TestForeach(t *testing.T) {
+	posts := make([]structure.Post, 5)
+	for index := range posts {
+		posts[index].Title = []byte{byte('A' + index)}
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{#foreach posts}}{{@index}}{{title}}{{#if @first}}F{{/if}}{{#if @last}}L{{/if}} {{/foreach}}`, "0AF 1B 2C 3D 4EL "},
+		{`{{#foreach posts from="2" to="4"}}{{@number}}{{title}}{{#if @first}}F{{/if}}{{#if @last}}L{{/if}} {{/foreach}}`, "1BF 2C 3DL "},
+		{`{{#foreach posts limit="2"}}{{title}}{{/foreach}}`, "AB"},
+		{`{{#foreach posts columns="2"}}{{#if @rowStart}}[{{/if}}{{title}}{{#if @rowEnd}}]{{/if}}{{/foreach}}`, "[AB][CD][E]"},
+		{`{{#foreach posts from="6"}}{{title}}{{else}}Nothing{{/foreach}}`, "Nothing"},
+		{`{{#foreach posts}}{{#foreach tags}}{{name}}{{else}}{{title}}{{/foreach}}{{/foreach}}`, "ABCDE"},
+	}
+	for _, test := range tests {
+		if got := render(test.template, &structure.RequestData{Posts: posts}); got != test.want {
+			t.Errorf("%s = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestIf(t *testing.T) {
+	posts := []structure.Post{structure.Post{Title: []byte("Hello")}, structure.Post{Title: []byte("0")}}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{#if 0}}yes{{else}}no{{/if}}`, "no"},
+		{`{{#if 0.0}}yes{{else}}no{{/if}}`, "no"},
+		{`{{#if false}}yes{{else}}no{{/if}}`, "no"},
+		{`{{#if ""}}yes{{else}}no{{/if}}`, "no"},
+		{`{{#if "0"}}yes{{else}}no{{/if}}`, "yes"},
+		{`{{#if tags}}yes{{else}}no{{/if}}`, "no"},
+		{`{{#if 1}}yes{{else}}no{{/if}}`, "yes"},
+		{`{{#if "false"}}yes{{else}}no{{/if}}`, "yes"},
+		{`{{#if title}}yes{{else}}no{{/if}}`, "yes"},
+		{`{{#unless 0}}yes{{/unless}}`, "yes"},
+		{`{{#foreach posts}}{{#if title}}{{title}}{{else}}-{{/if}}{{/foreach}}`, "Hello0"},
+		{`{{#foreach posts}}{{#match title}}{{title}}{{/match}}{{/foreach}}`, "Hello0"},
+		{`{{#foreach posts}}{{#if @index}}, {{/if}}{{@number}}{{/foreach}}`, "1, 2"},
+		{`{{#if (match title "Hello")}}yes{{else}}no{{/if}}`, "yes"},
+	}
+	for _, test := range tests {
+		if got := render(test.template, &structure.RequestData{Posts: posts}); got != test.want {
+			t.Errorf("%s = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestArguments(t *testing.T) {
+	compiledTemplates.m["test/heading"] = compileTemplate([]byte(`<h1>{{heading}}</h1>{{subheading}}`), "test/heading")
+	defer delete(compiledTemplates.m, "test/heading")
+	posts := []structure.Post{structure.Post{Title: []byte("a=b")}}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{#get "posts" filter="title:'Beta'"}}{{#foreach posts}}{{title}}{{/foreach}}{{/get}}`, "Beta"},
+		{`{{#get "posts" filter='title:"Gamma"'}}{{#foreach posts}}{{title}}{{/foreach}}{{/get}}`, "Gamma"},
+		{`{{#match title "a=b"}}yes{{else}}no{{/match}}`, "yes"},
+		{`{{#match title "a"}}yes{{else}}no{{/match}}`, "no"},
+		{`{{> "test/heading" heading="Hello world"}}`, "<h1>Hello world</h1>"},
+		{`{{> "test/heading" heading=title subheading=false}}`, "<h1>a=b</h1>"},
+		{`{{> "test/heading" heading=(match title "a=b")}}`, "<h1>true</h1>"},
+	}
+	for _, test := range tests {
+		if got := render(test.template, &structure.RequestData{Posts: posts}); got != test.want {
+			t.Errorf("%s = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+// The hash arguments of a partial replace the helpers of the same name only in the context the partial was called in
+func TestPartialArguments(t *testing.T) {
+	compiledTemplates.m["test/card"] = compileTemplate([]byte(`{{title}}:{{#foreach posts}}{{title}}{{/foreach}}:{{#get "posts" filter="tag:news" order="title asc"}}{{#foreach posts}}{{title}}{{/foreach}}{{/get}}`), "test/card")
+	defer delete(compiledTemplates.m, "test/card")
+	posts := []structure.Post{structure.Post{Title: []byte("A")}, structure.Post{Title: []byte("B")}}
+	want := "X:AB:AlphaGamma"
+	if got := render(`{{> "test/card" title="X"}}`, &structure.RequestData{Posts: posts}); got != want {
+		t.Errorf("partial = %q, want %q", got, want)
+	}
+	// In the post context, a loop over the posts is a new context as well
+	if got := string(executeHelper(compileTemplate([]byte(`{{> "test/card" title="X"}}`), "test"), &structure.RequestData{Posts: posts, Blog: &structure.Blog{}}, 1)); got != want {
+		t.Errorf("partial in post context = %q, want %q", got, want)
+	}
+}
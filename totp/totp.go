@@ -0,0 +1,103 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as used by authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Authenticator apps only support the defaults reliably: SHA1, 6 digits, and a period of 30 seconds
+const digits = 6
+const period = 30
+
+// Codes of the previous and the next period are accepted as well (to allow for clock drift)
+const skew = 1
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Function to generate a new random secret (base32 encoded, 160 bits like recommended by RFC 4226).
+func GenerateSecret() (string, error) {
+	key := make([]byte, 20)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(key), nil
+}
+
+// Function to get the uri that is encoded in the QR code authenticator apps scan (otpauth://totp/...).
+func ProvisioningUri(issuer string, account string, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", strconv.Itoa(digits))
+	values.Set("period", strconv.Itoa(period))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// Function to get the code for a secret at a given time.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, Step(t), digits), nil
+}
+
+// Function to get the time step (counter) of a time.
+func Step(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// Function to check a code. Returns the time step the code belongs to, so that the caller can reject codes that have been used before.
+func Validate(secret string, input string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	input = strings.Replace(input, " ", "", -1)
+	if len(input) != digits {
+		return 0, false
+	}
+	current := Step(t)
+	for step := current - skew; step <= current+skew; step++ {
+		if hmac.Equal([]byte(code(key, step, digits)), []byte(input)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	return encoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// HOTP (RFC 4226) with dynamic truncation
+func code(key []byte, counter int64, length int) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	modulo := int64(1)
+	for i := 0; i < length; i++ {
+		modulo *= 10
+	}
+	output := strconv.FormatInt(value%modulo, 10)
+	for len(output) < length {
+		output = "0" + output
+	}
+	return output
+}
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238, appendix B (SHA1)
+var rfcTests = []struct {
+	time int64
+	out  string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, test := range rfcTests {
+		if out := code(key, Step(time.Unix(test.time, 0)), 8); out != test.out {
+			t.Errorf("code(%d) => %s, want %s", test.time, out, test.out)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	current, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step, ok := Validate(secret, current, now); !ok || step != Step(now) {
+		t.Errorf("Validate(current code) => %d, %v", step, ok)
+	}
+	previous, _ := Code(secret, now.Add(-period*time.Second))
+	if _, ok := Validate(secret, previous, now); !ok {
+		t.Errorf("Validate(previous code) => false, want true")
+	}
+	old, _ := Code(secret, now.Add(-3*period*time.Second))
+	if _, ok := Validate(secret, old, now); ok {
+		t.Errorf("Validate(old code) => true, want false")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Errorf("Validate(short code) => true, want false")
+	}
+}
+
+func TestProvisioningUri(t *testing.T) {
+	out := ProvisioningUri("My Blog", "john", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/My%20Blog:john?algorithm=SHA1&digits=6&issuer=My+Blog&period=30&secret=JBSWY3DPEHPK3PXP"
+	if out != want {
+		t.Errorf("ProvisioningUri() => %s, want %s", out, want)
+	}
+}
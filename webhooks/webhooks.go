@@ -0,0 +1,201 @@
+// Package webhooks sends signed json payloads about changes on the blog to the urls that integrations registered.
+// Deliveries run in the background and are retried with an increasing delay if the receiver doesn't answer with 2xx.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Events
+const (
+	EventPostPublished = "post.published"
+	EventPostUpdated   = "post.updated"
+	EventPostDeleted   = "post.deleted"
+	EventTagAdded      = "tag.added"
+	EventSiteChanged   = "site.changed"
+)
+
+var Events = []string{EventPostPublished, EventPostUpdated, EventPostDeleted, EventTagAdded, EventSiteChanged}
+
+// Headers of every delivery
+const (
+	EventHeader     = "X-Journey-Event"
+	SignatureHeader = "X-Journey-Signature"
+)
+
+// Statuses of an attempt
+const (
+	StatusDelivered = "delivered"
+	StatusRetrying  = "retrying" // failed, another attempt follows
+	StatusFailed    = "failed"   // failed, no attempts left
+)
+
+var ErrQueueFull = errors.New("The webhook queue is full.")
+
+// Delivery: the payload of one event for one webhook
+type Delivery struct {
+	WebhookId int64
+	Event     string
+	Url       string
+	Secret    string
+	Payload   []byte
+	Attempts  int // number of attempts so far
+}
+
+// Attempt: the result of sending a delivery once
+type Attempt struct {
+	Number     int
+	Status     string
+	StatusCode int // 0 if there was no response
+	Error      string
+	Duration   time.Duration
+}
+
+// Queue: sends deliveries with a fixed number of workers
+type Queue struct {
+	// Defaults to a client with a timeout of 10 seconds
+	Client *http.Client
+	// Number of attempts before a delivery is given up
+	MaxAttempts int
+	// Delay before the next attempt after the given number of failed attempts
+	Backoff func(attempts int) time.Duration
+	// Called after every attempt (e.g. to write the delivery log)
+	Record     func(delivery *Delivery, attempt *Attempt)
+	deliveries chan *Delivery
+	pending    sync.WaitGroup
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Function to create a queue that holds up to size deliveries and starts its workers
+func NewQueue(size int, workers int) *Queue {
+	q := &Queue{MaxAttempts: 5, Backoff: DefaultBackoff, deliveries: make(chan *Delivery, size)}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Waits 30 seconds after the first failed attempt and doubles the delay after every further one (up to an hour)
+func DefaultBackoff(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// Function to add a delivery to the queue. Doesn't block: if the queue is full, the delivery is dropped.
+func (q *Queue) Enqueue(delivery *Delivery) error {
+	q.pending.Add(1)
+	select {
+	case q.deliveries <- delivery:
+		return nil
+	default:
+		q.pending.Done()
+		return ErrQueueFull
+	}
+}
+
+// Waits until all deliveries (including their retries) are done
+func (q *Queue) Wait() {
+	q.pending.Wait()
+}
+
+func (q *Queue) work() {
+	for delivery := range q.deliveries {
+		q.deliver(delivery)
+	}
+}
+
+func (q *Queue) deliver(delivery *Delivery) {
+	delivery.Attempts++
+	attempt := &Attempt{Number: delivery.Attempts, Status: StatusDelivered}
+	start := time.Now()
+	statusCode, err := q.send(delivery)
+	attempt.Duration = time.Since(start)
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.Status = StatusFailed
+		if delivery.Attempts < q.MaxAttempts {
+			attempt.Status = StatusRetrying
+		}
+	}
+	if q.Record != nil {
+		q.Record(delivery, attempt)
+	}
+	if attempt.Status != StatusRetrying {
+		q.pending.Done()
+		return
+	}
+	time.AfterFunc(q.Backoff(delivery.Attempts), func() {
+		select {
+		case q.deliveries <- delivery:
+		default:
+			log.Println("Dropped retry of webhook delivery to " + delivery.Url + ": " + ErrQueueFull.Error())
+			q.pending.Done()
+		}
+	})
+}
+
+// Posts the payload. Every status but 2xx is an error.
+func (q *Queue) send(delivery *Delivery) (int, error) {
+	request, err := http.NewRequest("POST", delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Journey webhooks")
+	request.Header.Set(EventHeader, delivery.Event)
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, delivery.Payload, time.Now()))
+	client := q.Client
+	if client == nil {
+		client = defaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	// Read the body so that the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 1<<16))
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, errors.New("Receiver answered with " + response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// Returns the value of the signature header: the hex encoded HMAC-SHA256 of the payload followed by the unix
+// timestamp (e.g. "sha256=5e0b..., t=1500000000"). Receivers compute it with the secret of the webhook and should
+// reject old timestamps.
+func Sign(secret string, payload []byte, timestamp time.Time) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	mac.Write([]byte(unix))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil)) + ", t=" + unix
+}
+
+func IsEvent(event string) bool {
+	for _, name := range Events {
+		if name == event {
+			return true
+		}
+	}
+	return false
+}
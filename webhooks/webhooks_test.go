@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", []byte(`{"event":"post.published"}`), time.Unix(1500000000, 0))
+	expected := "sha256=78eb5e19a8fb911683de9c14f6ed78d33ff132382b7ee36b585f566859c081c5, t=1500000000"
+	if signature != expected {
+		t.Errorf("Sign() => %q, want %q", signature, expected)
+	}
+	if Sign("secret", []byte("a"), time.Unix(1, 0)) == Sign("other", []byte("a"), time.Unix(1, 0)) {
+		t.Error("Expected the signature to depend on the secret")
+	}
+	if Sign("secret", []byte("a"), time.Unix(1, 0)) == Sign("secret", []byte("a"), time.Unix(2, 0)) {
+		t.Error("Expected the signature to depend on the timestamp")
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	expected := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 8: time.Hour, 20: time.Hour}
+	for attempts, delay := range expected {
+		if backoff := DefaultBackoff(attempts); backoff != delay {
+			t.Errorf("DefaultBackoff(%d) => %v, want %v", attempts, backoff, delay)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	var lock sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(EventHeader) != EventPostPublished || string(body) != `{"id":1}` || !strings.HasPrefix(r.Header.Get(SignatureHeader), "sha256=") {
+			t.Errorf("Unexpected request: %v %s", r.Header, body)
+		}
+		// Fail the first two attempts
+		if requests <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	queue := NewQueue(10, 2)
+	queue.Backoff = func(attempts int) time.Duration { return time.Millisecond }
+	attempts := make([]Attempt, 0)
+	queue.Record = func(delivery *Delivery, attempt *Attempt) {
+		lock.Lock()
+		defer lock.Unlock()
+		attempts = append(attempts, *attempt)
+	}
+	if err := queue.Enqueue(&Delivery{WebhookId: 1, Event: EventPostPublished, Url: server.URL, Secret: "secret", Payload: []byte(`{"id":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	queue.Wait()
+	if len(attempts) != 3 || attempts[0].Status != StatusRetrying || attempts[0].StatusCode != 503 || attempts[2].Status != StatusDelivered || attempts[2].Number != 3 {
+		t.Errorf("Attempts => %+v", attempts)
+	}
+	// Gives up after MaxAttempts
+	attempts = attempts[:0]
+	queue.MaxAttempts = 2
+	queue.Enqueue(&Delivery{WebhookId: 1, Event: EventPostPublished, Url: "http://127.0.0.1:1/", Payload: []byte(`{"id":1}`)})
+	queue.Wait()
+	if len(attempts) != 2 || attempts[1].Status != StatusFailed || attempts[1].Error == "" {
+		t.Errorf("Attempts => %+v", attempts)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	queue := NewQueue(1, 0)
+	if err := queue.Enqueue(&Delivery{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Enqueue(&Delivery{}); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+}